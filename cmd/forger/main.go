@@ -11,8 +11,10 @@ import (
 )
 
 type Config struct {
-	Default string   `json:"default"`
-	Enabled []string `json:"enabled"`
+	Default string                     `json:"default"`
+	Enabled []string                   `json:"enabled"`
+	Plugins map[string]json.RawMessage `json:"plugins"`
+	Keymap  core.KeymapConfig          `json:"keymap"`
 }
 
 func loadConfig(path string) (Config, error) {
@@ -28,6 +30,14 @@ func loadConfig(path string) (Config, error) {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if os.Args[1] != "plugin" {
+			fmt.Fprintf(os.Stderr, "forger: unknown command %q\n\n%s", os.Args[1], pluginUsage)
+			os.Exit(2)
+		}
+		os.Exit(runPlugin(os.Args[2:]))
+	}
+
 	cfg, err := loadConfig("forger.json")
 	if err != nil {
 		core.LogError(fmt.Sprintf("failed to load config: %v", err))
@@ -35,7 +45,14 @@ func main() {
 	}
 
 	model := core.NewModel()
+	model.Context.PluginConfig = cfg.Plugins
+	keys, problems := core.NewKeymap(cfg.Keymap)
+	model.Context.Keys = keys
 	model.Plugins, model.LoadErrors = core.LoadPlugins(cfg.Enabled, model.Context)
+	model.KeyConflicts = append(problems, core.KeyConflicts(keys, model.Plugins)...)
+	for _, c := range model.KeyConflicts {
+		core.LogError("keymap: " + c)
+	}
 
 	if _, ok := model.Plugins[cfg.Default]; ok {
 		model.Active = cfg.Default
@@ -44,7 +61,9 @@ func main() {
 	}
 
 	prog := tea.NewProgram(model)
-	if _, err := prog.Run(); err != nil {
+	_, err = prog.Run()
+	core.ClosePlugins(model.Plugins)
+	if err != nil {
 		core.LogError(fmt.Sprintf("program error: %v", err))
 		os.Exit(1)
 	}
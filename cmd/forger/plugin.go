@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"forger/internal/plugins/external"
+)
+
+const pluginUsage = `Usage:
+  forger plugin install [--force] [--index FILE] SOURCE|NAME
+  forger plugin list
+  forger plugin remove NAME...
+
+SOURCE is a local directory or git URL (append #ref for a branch or tag)
+holding a plugin.json. NAME is looked up in the registry index given with
+--index or $FORGER_PLUGIN_INDEX. Plugins are installed into
+~/.config/forger/plugins (or $FORGER_PLUGIN_DIR) and recorded in plugins.lock.
+`
+
+// indexEnv names the default registry index file.
+const indexEnv = "FORGER_PLUGIN_INDEX"
+
+// runPlugin implements "forger plugin" and returns the exit code.
+func runPlugin(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, pluginUsage)
+		return 2
+	}
+	dir, err := external.Dir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "forger:", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "install":
+		err = pluginInstall(dir, args[1:])
+	case "list", "ls":
+		err = pluginList(dir)
+	case "remove", "rm", "uninstall":
+		err = pluginRemove(dir, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(pluginUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "forger: unknown plugin command %q\n\n%s", args[0], pluginUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "forger:", err)
+		return 1
+	}
+	return 0
+}
+
+func pluginInstall(dir string, args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace an installed plugin of the same name")
+	indexPath := fs.String("index", os.Getenv(indexEnv), "registry index file to look NAME up in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("install takes one SOURCE or NAME\n\n%s", pluginUsage)
+	}
+
+	source, name, want := fs.Arg(0), "", ""
+	if !external.IsGitSource(source) && !isDir(source) {
+		if *indexPath == "" {
+			return fmt.Errorf("%s is not a directory or git URL, and no registry index was given (--index)", source)
+		}
+		index, err := external.LoadIndex(*indexPath)
+		if err != nil {
+			return err
+		}
+		entry, ok := index.Plugins[source]
+		if !ok {
+			return fmt.Errorf("plugin %q is not in %s", source, *indexPath)
+		}
+		name, source, want = source, entry.Source, entry.Version
+	}
+
+	m, err := external.Install(dir, source, external.InstallOptions{Force: *force})
+	if err != nil {
+		return err
+	}
+	if name != "" && m.Name != name {
+		fmt.Fprintf(os.Stderr, "warning: index entry %s installed a plugin named %s\n", name, m.Name)
+	}
+	if want != "" && m.Version != want {
+		fmt.Fprintf(os.Stderr, "warning: index lists %s %s but %s was installed\n", m.Name, want, versionOf(m.Version))
+	}
+	fmt.Printf("Installed %s %s into %s\n", m.Name, versionOf(m.Version), m.Dir)
+	fmt.Printf("Add %q to \"enabled\" in forger.json to load it.\n", m.Name)
+	return nil
+}
+
+func pluginList(dir string) error {
+	lock, err := external.LoadLock(dir)
+	if err != nil {
+		return err
+	}
+	plugins, errs := external.Discover(dir)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	if len(plugins) == 0 && len(lock.Plugins) == 0 {
+		fmt.Printf("No plugins installed in %s\n", dir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tCAPABILITIES\tSTATUS\tSOURCE")
+	seen := make(map[string]bool)
+	for _, m := range plugins {
+		seen[m.Name] = true
+		status, source := "unmanaged", "-"
+		if entry, ok := lock.Plugins[m.Name]; ok {
+			source = entry.Source
+			if entry.Commit != "" {
+				source += "@" + entry.Commit[:min(len(entry.Commit), 12)]
+			}
+			status = "ok"
+			if sum, err := external.Checksum(m.Dir); err != nil || sum != entry.Checksum {
+				status = "modified"
+			}
+		}
+		caps := strings.Join(m.Capabilities, ",")
+		if caps == "" {
+			caps = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Name, versionOf(m.Version), caps, status, source)
+	}
+	for name, entry := range lock.Plugins {
+		if !seen[name] {
+			fmt.Fprintf(w, "%s\t%s\t-\tmissing\t%s\n", name, versionOf(entry.Version), entry.Source)
+		}
+	}
+	return w.Flush()
+}
+
+func pluginRemove(dir string, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("remove takes at least one NAME\n\n%s", pluginUsage)
+	}
+	for _, name := range names {
+		if err := external.Remove(dir, name); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", name)
+	}
+	return nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func versionOf(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
@@ -4,3 +4,6 @@ import "forger/internal/types"
 
 // Context holds shared mutable state for plugins.
 type Context = types.Context
+
+// Keymap holds the effective key bindings.
+type Keymap = types.Keymap
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"forger/internal/types"
+	"forger/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	finderHeight = 15 // results shown at once
+	finderWidth  = 60 // columns for a result's title
+	kindWidth    = 8
+)
+
+// finderEntry is something the finder lists: an item a plugin offered,
+// or one of its actions.
+type finderEntry struct {
+	item     types.SearchItem
+	action   bool // item.ID is the ID of an action of item.Plugin
+	focus    bool // an action that needs its plugin on screen
+	disabled bool // an action that can't run now
+}
+
+// finderResult is an entry that matches the query, with where it matched.
+type finderResult struct {
+	finderEntry
+	score     int
+	positions []int
+}
+
+// Finder is the fuzzy finder overlay, which also serves as the command
+// palette. It is given its entries when it opens and ranks them against
+// the query as it is typed.
+type Finder struct {
+	Open    bool
+	title   string
+	query   string
+	entries []finderEntry
+	results []finderResult
+	cursor  int
+	offset  int
+}
+
+// NewFinder returns a closed finder.
+func NewFinder() *Finder {
+	return &Finder{}
+}
+
+// Show opens the finder over entries with an empty query.
+func (f *Finder) Show(title string, entries []finderEntry) {
+	f.Open = true
+	f.title = title
+	f.query = ""
+	f.entries = entries
+	f.rank()
+}
+
+// Close hides the finder and drops its entries.
+func (f *Finder) Close() {
+	f.Open = false
+	f.entries, f.results = nil, nil
+}
+
+// rank matches every item against the query, best first, with actions
+// that can't run now last. Ties go to the shorter title, then to the order
+// the entries were given in, which is all that counts with no query.
+func (f *Finder) rank() {
+	f.results = f.results[:0]
+	for _, e := range f.entries {
+		if score, positions, ok := ui.FuzzyMatch(f.query, e.item.Title); ok {
+			f.results = append(f.results, finderResult{finderEntry: e, score: score, positions: positions})
+		}
+	}
+	sort.SliceStable(f.results, func(i, j int) bool {
+		a, b := f.results[i], f.results[j]
+		switch {
+		case a.disabled != b.disabled:
+			return b.disabled
+		case f.query == "":
+			return false
+		case a.score != b.score:
+			return a.score > b.score
+		}
+		return len(a.item.Title) < len(b.item.Title)
+	})
+	f.cursor, f.offset = 0, 0
+}
+
+// Update edits the query and moves the selection.
+func (f *Finder) Update(key tea.KeyMsg) {
+	switch key.String() {
+	case "up":
+		f.moveTo(f.cursor - 1)
+	case "down":
+		f.moveTo(f.cursor + 1)
+	case "pgup":
+		f.moveTo(f.cursor - finderHeight)
+	case "pgdown":
+		f.moveTo(f.cursor + finderHeight)
+	case "backspace":
+		if r := []rune(f.query); len(r) > 0 {
+			f.query = string(r[:len(r)-1])
+			f.rank()
+		}
+	case "ctrl+u":
+		f.query = ""
+		f.rank()
+	default:
+		if key.Type == tea.KeyRunes || key.Type == tea.KeySpace {
+			f.query += string(key.Runes)
+			f.rank()
+		}
+	}
+}
+
+func (f *Finder) moveTo(i int) {
+	f.cursor = max(0, min(i, len(f.results)-1))
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	}
+	if f.cursor >= f.offset+finderHeight {
+		f.offset = f.cursor - finderHeight + 1
+	}
+}
+
+// Selected returns the entry under the cursor.
+func (f *Finder) Selected() (finderEntry, bool) {
+	if f.cursor >= len(f.results) {
+		return finderEntry{}, false
+	}
+	return f.results[f.cursor].finderEntry, true
+}
+
+// View renders the query and the visible results.
+func (f *Finder) View() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s (%d of %d)\n\n", f.title, len(f.results), len(f.entries)))
+	sb.WriteString("> " + f.query + cursorStyle.Render(" ") + "\n\n")
+	if len(f.entries) == 0 {
+		sb.WriteString("Nothing here yet; plugins add to it as they load.\n")
+	} else if len(f.results) == 0 {
+		sb.WriteString("No matches.\n")
+	}
+	end := min(f.offset+finderHeight, len(f.results))
+	for i := f.offset; i < end; i++ {
+		r := f.results[i]
+		var line string
+		if r.disabled {
+			line = detailStyle.Render(fmt.Sprintf("%-*s %s  %s (unavailable)", kindWidth, r.item.Kind, highlight(r.item.Title, nil, finderWidth), r.item.Detail))
+		} else {
+			line = kindStyle.Render(fmt.Sprintf("%-*s", kindWidth, r.item.Kind)) + " " + highlight(r.item.Title, r.positions, finderWidth)
+			if r.item.Detail != "" {
+				line += "  " + detailStyle.Render(r.item.Detail)
+			}
+		}
+		if i == f.cursor {
+			line = "> " + line
+		} else {
+			line = "  " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n↑/↓ select • Enter open or run • Esc close")
+	return sb.String()
+}
+
+// highlight renders title with the matched runes emphasized, cut to width
+// runes.
+func highlight(title string, positions []int, width int) string {
+	runes := []rune(title)
+	cut := len(runes) > width
+	if cut {
+		runes = runes[:width-1]
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+	var sb strings.Builder
+	for i, r := range runes {
+		if matched[i] {
+			sb.WriteString(matchStyle.Render(string(r)))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	if cut {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+var (
+	matchStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+	kindStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	detailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"forger/internal/types"
+)
+
+// KeymapConfig is the keymap section of forger.json: a preset for the
+// global keys, changes to single global actions, and plugin actions
+// rebound by ID.
+type KeymapConfig struct {
+	Preset  string                       `json:"preset"`
+	Global  map[string]Keys              `json:"global"`
+	Plugins map[string]map[string]string `json:"plugins"`
+}
+
+// Keys is the keys of a global action, written in forger.json as one key
+// or a list of them.
+type Keys []string
+
+func (k *Keys) UnmarshalJSON(data []byte) error {
+	var key string
+	if err := json.Unmarshal(data, &key); err == nil {
+		*k = Keys{key}
+		return nil
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("keys must be a string or a list of strings")
+	}
+	*k = keys
+	return nil
+}
+
+// globalActions are Forger's own actions, in the order help lists them.
+// Close only applies to the chat overlay; otherwise its keys go to the
+// focused plugin.
+var globalActions = []types.Action{
+	{ID: "quit", Title: "Quit"},
+	{ID: "chat", Title: "Open or close the MarChat overlay"},
+	{ID: "close", Title: "Close the MarChat overlay (elsewhere the key goes to the plugin)"},
+	{ID: "next-plugin", Title: "Next plugin"},
+	{ID: "prev-plugin", Title: "Previous plugin"},
+	{ID: "notifications", Title: "Notification list"},
+	{ID: "find", Title: "Fuzzy finder"},
+	{ID: "palette", Title: "Command palette"},
+	{ID: "help", Title: "Key bindings"},
+}
+
+// presets are the global keys of each preset. The vim and emacs presets
+// keep single lowercase letters free for plugins.
+var presets = map[string]map[string][]string{
+	"default": {
+		"quit":          {"q", "ctrl+c"},
+		"chat":          {"c"},
+		"close":         {"esc"},
+		"next-plugin":   {"tab"},
+		"prev-plugin":   {"shift+tab"},
+		"notifications": {"ctrl+n"},
+		"find":          {"ctrl+p"},
+		"palette":       {"ctrl+k"},
+		"help":          {"?"},
+	},
+	"vim": {
+		"quit":          {"Q", "ctrl+c"},
+		"chat":          {"C"},
+		"close":         {"esc"},
+		"next-plugin":   {"tab", "L"},
+		"prev-plugin":   {"shift+tab", "H"},
+		"notifications": {"ctrl+n"},
+		"find":          {"ctrl+p"},
+		"palette":       {":"},
+		"help":          {"?"},
+	},
+	"emacs": {
+		"quit":          {"ctrl+q", "ctrl+c"},
+		"chat":          {"alt+c"},
+		"close":         {"esc", "ctrl+g"},
+		"next-plugin":   {"tab", "alt+n"},
+		"prev-plugin":   {"shift+tab", "alt+p"},
+		"notifications": {"ctrl+n"},
+		"find":          {"ctrl+p", "ctrl+s"},
+		"palette":       {"ctrl+k", "alt+x"},
+		"help":          {"?", "f1"},
+	},
+}
+
+// NewKeymap builds the effective keymap from cfg. Unknown presets and
+// global actions are reported and otherwise ignored.
+func NewKeymap(cfg KeymapConfig) (*Keymap, []string) {
+	var problems []string
+	preset, ok := presets[cfg.Preset]
+	if cfg.Preset == "" {
+		preset = presets["default"]
+	} else if !ok {
+		problems = append(problems, fmt.Sprintf("unknown keymap preset %q; using the default keys", cfg.Preset))
+		preset = presets["default"]
+	}
+
+	keys := &Keymap{Global: make(map[string][]string), Plugins: cfg.Plugins}
+	for id, k := range preset {
+		keys.Global[id] = k
+	}
+	for id, k := range cfg.Global {
+		if _, ok := keys.Global[id]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown global action %q in keymap", id))
+			continue
+		}
+		keys.Global[id] = k
+	}
+	sort.Strings(problems)
+	return keys, problems
+}
+
+// globalAction returns the global action bound to key, or "" if there is
+// none. Ctrl+C always quits, so there is a way out whatever the keymap.
+func globalAction(keys *Keymap, key string) string {
+	if key == "ctrl+c" {
+		return "quit"
+	}
+	if keys == nil {
+		return ""
+	}
+	for _, a := range globalActions {
+		for _, bound := range keys.Global[a.ID] {
+			if bound == key {
+				return a.ID
+			}
+		}
+	}
+	return ""
+}
+
+// KeyConflicts reports bindings that can't all work: a key bound to two
+// global actions, a plugin action whose key a global action takes first,
+// two actions of a plugin sharing a key in the same mode, and rebound
+// actions that don't exist. Keys shared by different plugins are fine,
+// since only the focused plugin gets keys.
+func KeyConflicts(keys *Keymap, plugins map[string]Plugin) []string {
+	var conflicts []string
+	owner := make(map[string]string) // key → global action
+	for _, a := range globalActions {
+		for _, key := range keys.Global[a.ID] {
+			if first, ok := owner[key]; ok && first != a.ID {
+				conflicts = append(conflicts, fmt.Sprintf("%s is bound to both %s and %s", types.KeyLabel(key), first, a.ID))
+				continue
+			}
+			owner[key] = a.ID
+		}
+	}
+
+	for _, name := range SortedPluginNames(plugins) {
+		var actions []types.Action
+		if a, ok := plugins[name].(Actor); ok {
+			actions = a.Actions()
+		}
+		known := make(map[string]bool, len(actions))
+		for _, a := range actions {
+			known[a.ID] = true
+		}
+		for _, id := range sortedKeys(keys.Plugins[name]) {
+			if !known[id] {
+				conflicts = append(conflicts, fmt.Sprintf("%s has no action %q to rebind", name, id))
+			}
+		}
+
+		taken := make(map[string]string) // mode and key → action
+		for _, a := range keys.Bind(name, actions) {
+			if a.Key == "" {
+				continue
+			}
+			if id := globalAction(keys, a.Key); id != "" && id != "close" {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s %s is shadowed by global %s", types.KeyLabel(a.Key), name, a.ID, id))
+			}
+			slot := a.Mode + "\n" + a.Key
+			if first, ok := taken[slot]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s %s and %s share the key", types.KeyLabel(a.Key), name, first, a.ID))
+				continue
+			}
+			taken[slot] = a.ID
+		}
+	}
+	return conflicts
+}
+
+// helpText lists the effective bindings: the global keys, any conflicts,
+// and every plugin's actions, the focused plugin's first.
+func (m Model) helpText() string {
+	keys := m.Context.Keys
+	var sb strings.Builder
+	sb.WriteString("Global\n")
+	for _, a := range globalActions {
+		var labels []string
+		for _, key := range keys.Global[a.ID] {
+			labels = append(labels, types.KeyLabel(key))
+		}
+		if len(labels) == 0 {
+			labels = append(labels, types.KeyLabel(""))
+		}
+		sb.WriteString(fmt.Sprintf("  %-22s %s\n", strings.Join(labels, ", "), a.Title))
+	}
+
+	if len(m.KeyConflicts) > 0 {
+		sb.WriteString("\nConflicts\n")
+		for _, c := range m.KeyConflicts {
+			sb.WriteString("  ⚠ " + c + "\n")
+		}
+	}
+
+	for _, name := range m.focusedFirst() {
+		a, ok := m.Plugins[name].(Actor)
+		if !ok {
+			continue
+		}
+		sb.WriteString("\n" + name + "\n")
+		for _, action := range keys.Bind(name, a.Actions()) {
+			line := fmt.Sprintf("  %-22s %s", types.KeyLabel(action.Key), action.Title)
+			if action.Mode != "" {
+				line += " (in " + action.Mode + ")"
+			}
+			if id := globalAction(keys, action.Key); id != "" && id != "close" {
+				line += " (shadowed by " + id + ")"
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakePlugin is a plugin that only declares actions.
+type fakePlugin struct {
+	name    string
+	actions []types.Action
+}
+
+func (p fakePlugin) Init() tea.Cmd                          { return nil }
+func (p fakePlugin) Update(tea.Msg) (types.Plugin, tea.Cmd) { return p, nil }
+func (p fakePlugin) View() string                           { return "" }
+func (p fakePlugin) Name() string                           { return p.name }
+func (p fakePlugin) Actions() []types.Action                { return p.actions }
+
+func TestNewKeymap(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      KeymapConfig
+		global   map[string][]string // expected keys of some global actions
+		problems []string
+	}{
+		{
+			name:   "default preset",
+			global: map[string][]string{"quit": {"q", "ctrl+c"}, "palette": {"ctrl+k"}},
+		},
+		{
+			name:   "named preset",
+			cfg:    KeymapConfig{Preset: "vim"},
+			global: map[string][]string{"quit": {"Q", "ctrl+c"}, "palette": {":"}},
+		},
+		{
+			name:     "unknown preset",
+			cfg:      KeymapConfig{Preset: "nano"},
+			global:   map[string][]string{"quit": {"q", "ctrl+c"}},
+			problems: []string{`unknown keymap preset "nano"; using the default keys`},
+		},
+		{
+			name:   "global override",
+			cfg:    KeymapConfig{Preset: "emacs", Global: map[string]Keys{"help": {"f2"}, "quit": {}}},
+			global: map[string][]string{"help": {"f2"}, "quit": {}, "find": {"ctrl+p", "ctrl+s"}},
+		},
+		{
+			name:     "unknown global actions",
+			cfg:      KeymapConfig{Global: map[string]Keys{"zoom": {"z"}, "exit": {"x"}, "help": {"h"}}},
+			global:   map[string][]string{"help": {"h"}},
+			problems: []string{`unknown global action "exit" in keymap`, `unknown global action "zoom" in keymap`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, problems := NewKeymap(tt.cfg)
+			if !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", problems, tt.problems)
+			}
+			for id, want := range tt.global {
+				if got := keys.Global[id]; len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+					t.Errorf("Global[%q] = %q, want %q", id, got, want)
+				}
+			}
+			if _, ok := keys.Global["zoom"]; ok {
+				t.Error("unknown global action was added")
+			}
+		})
+	}
+}
+
+func TestKeymapConfigKeys(t *testing.T) {
+	var cfg KeymapConfig
+	if err := json.Unmarshal([]byte(`{"global":{"help":"f1","quit":["q","ctrl+q"]}}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Keys{"help": {"f1"}, "quit": {"q", "ctrl+q"}}
+	if !reflect.DeepEqual(cfg.Global, want) {
+		t.Errorf("Global = %q, want %q", cfg.Global, want)
+	}
+	if err := json.Unmarshal([]byte(`{"global":{"help":1}}`), &cfg); err == nil {
+		t.Error("a number was accepted as keys")
+	}
+}
+
+func TestKeyConflicts(t *testing.T) {
+	list := fakePlugin{name: "list", actions: []types.Action{
+		{ID: "open", Title: "Open", Key: "o"},
+		{ID: "share", Title: "Share", Key: "p", Mode: "report"},
+		{ID: "back", Title: "Back", Key: "esc"},
+	}}
+	tests := []struct {
+		name      string
+		cfg       KeymapConfig
+		plugins   []fakePlugin
+		conflicts []string
+	}{
+		{
+			name:    "no conflicts",
+			plugins: []fakePlugin{list},
+		},
+		{
+			name: "global key bound twice",
+			cfg:  KeymapConfig{Global: map[string]Keys{"help": {"?", "ctrl+k"}}},
+			conflicts: []string{
+				"Ctrl+K is bound to both palette and help",
+			},
+		},
+		{
+			name: "plugin action shadowed by a global",
+			plugins: []fakePlugin{{name: "tests", actions: []types.Action{
+				{ID: "quick", Title: "Quick run", Key: "q"},
+				{ID: "help", Title: "Help", Key: "?", Mode: "report"},
+			}}},
+			conflicts: []string{
+				"Q: tests quick is shadowed by global quit",
+				"?: tests help is shadowed by global help",
+			},
+		},
+		{
+			name:    "close doesn't shadow plugin keys",
+			cfg:     KeymapConfig{Global: map[string]Keys{"close": {"esc", "o"}}},
+			plugins: []fakePlugin{list},
+		},
+		{
+			name: "keys shared within a mode",
+			plugins: []fakePlugin{{name: "logs", actions: []types.Action{
+				{ID: "open", Title: "Open", Key: "o"},
+				{ID: "filter", Title: "Filter", Key: "o"},
+				{ID: "share", Title: "Share", Key: "o", Mode: "report"},
+			}}},
+			conflicts: []string{
+				"O: logs open and filter share the key",
+			},
+		},
+		{
+			name: "rebinding makes a conflict",
+			cfg:  KeymapConfig{Plugins: map[string]map[string]string{"list": {"share": "o", "back": "o"}}},
+			plugins: []fakePlugin{list, {name: "other", actions: []types.Action{
+				{ID: "open", Title: "Open", Key: "o"},
+			}}},
+			conflicts: []string{
+				"O: list open and back share the key",
+			},
+		},
+		{
+			name: "rebinding unknown actions",
+			cfg: KeymapConfig{Plugins: map[string]map[string]string{
+				"list":  {"zap": "z", "open": "x"},
+				"plain": {"open": "o"},
+			}},
+			plugins: []fakePlugin{list, {name: "plain"}},
+			conflicts: []string{
+				`list has no action "zap" to rebind`,
+				`plain has no action "open" to rebind`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, problems := NewKeymap(tt.cfg)
+			if len(problems) > 0 {
+				t.Fatalf("NewKeymap: %q", problems)
+			}
+			plugins := make(map[string]Plugin)
+			for _, p := range tt.plugins {
+				plugins[p.name] = p
+			}
+			if got := KeyConflicts(keys, plugins); !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("conflicts = %q, want %q", got, tt.conflicts)
+			}
+		})
+	}
+}
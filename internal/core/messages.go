@@ -1,8 +1,25 @@
 package core
 
+import "forger/internal/types"
+
 // SnapshotMsg is sent when a snapshot is selected.
 type SnapshotMsg struct {
 	ID string
 }
 
+// ShareMsg asks the chat plugin to post an artifact summary.
+type ShareMsg = types.ShareMsg
+
+// OpenRefMsg asks core to switch to the plugin a reference points into.
+type OpenRefMsg = types.OpenRefMsg
+
+// ColorizeMsg asks core to show an artifact in the ascii-colorizer plugin.
+type ColorizeMsg = types.ColorizeMsg
+
+// SearchSelectMsg delivers an item picked in the finder to its plugin.
+type SearchSelectMsg = types.SearchSelectMsg
+
+// ActionMsg asks a plugin to run one of its actions.
+type ActionMsg = types.ActionMsg
+
 // Add additional cross-plugin message types here.
@@ -1,11 +1,13 @@
 package core
 
 import (
-	"forger/internal/plugins/codesleuth"
-	"forger/internal/plugins/ignoregrets"
-	"forger/internal/plugins/marchat"
+	"fmt"
+	"sort"
 	"strings"
 
+	"forger/internal/types"
+	"forger/internal/ui"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -18,14 +20,24 @@ type Model struct {
 	Context    *Context
 	LoadErrors []string
 	Styles     lipgloss.Style
+	Notices    *Notifications
+	Finder     *Finder
+	// KeyConflicts are problems with the keymap, announced at startup
+	// and listed in help.
+	KeyConflicts []string
+	Help         *ui.Pager // the key bindings, while shown
 }
 
-// NewModel constructs a Model with default styling and an empty Context.
+// NewModel constructs a Model with default styling, the default keys and
+// an empty Context.
 func NewModel() Model {
+	keys, _ := NewKeymap(KeymapConfig{})
 	return Model{
-		Context:    &Context{GlobalState: make(map[string]interface{})},
+		Context:    &Context{GlobalState: make(map[string]interface{}), Keys: keys},
 		Styles:     lipgloss.NewStyle().Padding(1).Border(lipgloss.NormalBorder()),
 		LoadErrors: nil,
+		Notices:    NewNotifications(),
+		Finder:     NewFinder(),
 	}
 }
 
@@ -35,72 +47,312 @@ func (m Model) Init() tea.Cmd {
 	for _, plugin := range m.Plugins {
 		cmds = append(cmds, plugin.Init())
 	}
+	for _, c := range m.KeyConflicts {
+		cmds = append(cmds, types.Notify("keymap", types.SeverityWarning, "Key conflict: "+c))
+	}
 
 	// Return a command that runs all the init commands
 	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Anything that isn't a key press (availability checks, command results,
+	// chat events, window size) goes to every plugin, since a plugin's
+	// background commands keep delivering results while it isn't focused.
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		switch msg := msg.(type) {
+		case NotifyMsg:
+			return m, m.Notices.Add(msg, m.viewing())
+		case toastExpiredMsg:
+			m.Notices.expire(msg.ID)
+			return m, nil
+		case OpenRefMsg:
+			// Following a reference brings its plugin to the front; the
+			// plugin itself navigates when the message reaches it.
+			m.activate(msg.Ref.Plugin)
+		case SearchSelectMsg:
+			m.activate(msg.Item.Plugin)
+		case ColorizeMsg:
+			// Artifacts sent for display bring the colorizer to the front.
+			if _, ok := m.Plugins[types.ColorizerPlugin]; !ok {
+				return m, m.Notices.Add(NotifyMsg{Source: msg.From, Severity: types.SeverityWarning,
+					Text: types.ColorizerPlugin + " is not enabled in forger.json"}, m.viewing())
+			}
+			m.activate(types.ColorizerPlugin)
+		}
+		return m, m.broadcast(msg)
+	}
+
+	if m.Notices.Open {
+		return m, m.updateNotices(msg.(tea.KeyMsg))
+	}
+	if m.Finder.Open {
+		return m, m.updateFinder(msg.(tea.KeyMsg))
+	}
+	if m.Help != nil {
+		return m, m.updateHelp(msg.(tea.KeyMsg))
+	}
+
+	// A plugin that is capturing input gets every key except ctrl+c.
+	key := msg.(tea.KeyMsg)
+	if key.String() != "ctrl+c" && capturing(m.focused()) {
+		return m, m.updateFocused(msg)
+	}
+	global := globalAction(m.Context.Keys, key.String())
+
 	// Overlay routing
 	if m.Overlay != nil {
-		updated, cmd := m.Overlay.Update(msg)
-		m.Overlay = updated
-		if key, ok := msg.(tea.KeyMsg); ok && (key.String() == "c" || key.String() == "esc") {
+		cmd := m.updateFocused(msg)
+		// The key may have just started text entry; only close if not.
+		if (global == "chat" || global == "close") && !capturing(m.Overlay) {
 			m.Overlay = nil
 		}
 		return m, cmd
 	}
 
-	// Check if this is an availability message that should be sent to all plugins
-	switch msg.(type) {
-	case marchat.ServerCheckMsg, codesleuth.AvailabilityMsg, ignoregrets.AvailabilityMsg:
-		// Route availability messages to all plugins
-		var cmds []tea.Cmd
-		for name, plugin := range m.Plugins {
-			updated, cmd := plugin.Update(msg)
-			m.Plugins[name] = updated
-			if cmd != nil {
-				cmds = append(cmds, cmd)
+	switch global {
+	case "quit":
+		return m, tea.Quit
+	case "chat":
+		if chat, ok := m.Plugins["marchat"]; ok {
+			m.Overlay = chat
+			m.Notices.Seen("marchat")
+		}
+		return m, nil
+	case "next-plugin":
+		// Use tab to switch between plugins instead of up/down
+		m.activate(NextPluginKey(m.Plugins, m.Active))
+		return m, nil
+	case "prev-plugin":
+		// Use shift+tab to go backwards
+		m.activate(PrevPluginKey(m.Plugins, m.Active))
+		return m, nil
+	case "notifications":
+		m.Notices.Toggle()
+		return m, nil
+	case "find":
+		m.Finder.Show("Find", append(m.searchEntries(), m.actionEntries()...))
+		return m, nil
+	case "palette":
+		m.Finder.Show("Commands", m.actionEntries())
+		return m, nil
+	case "help":
+		m.Help = ui.NewPager("Key bindings", m.helpText())
+		return m, nil
+	}
+
+	// Update active plugin - let it handle all keys including up/down
+	return m, m.updateFocused(msg)
+}
+
+// activate makes the named plugin the active one, closing any overlay.
+func (m *Model) activate(name string) {
+	if _, ok := m.Plugins[name]; !ok {
+		return
+	}
+	m.Active = name
+	m.Overlay = nil
+	m.Notices.Seen(name)
+}
+
+// viewing names the plugin on screen, which doesn't need badging.
+func (m Model) viewing() string {
+	if m.Notices.Open || m.Finder.Open || m.Help != nil {
+		return ""
+	}
+	if m.Overlay != nil {
+		return m.Overlay.Name()
+	}
+	return m.Active
+}
+
+// updateNotices handles keys while the notification list is open. Enter
+// follows the selected notification's reference, or shows its plugin.
+func (m *Model) updateNotices(key tea.KeyMsg) tea.Cmd {
+	switch global := globalAction(m.Context.Keys, key.String()); {
+	case key.String() == "esc" || global == "notifications":
+		m.Notices.Open = false
+	case key.String() == "ctrl+c":
+		return tea.Quit
+	case key.String() == "enter":
+		note, ok := m.Notices.Selected()
+		if !ok {
+			return nil
+		}
+		m.Notices.Open = false
+		if note.Ref != nil {
+			ref := *note.Ref
+			return func() tea.Msg { return OpenRefMsg{Ref: ref} }
+		}
+		m.activate(note.Source)
+	default:
+		m.Notices.Update(key)
+	}
+	return nil
+}
+
+// updateFinder handles keys while the finder or command palette is open.
+// Enter on an action runs it in its plugin, switching to the plugin only
+// if the action opens something there. Any other item is opened: its
+// reference is followed if it has one, otherwise it goes back to its
+// plugin.
+func (m *Model) updateFinder(key tea.KeyMsg) tea.Cmd {
+	switch global := globalAction(m.Context.Keys, key.String()); {
+	case key.String() == "esc" || (global == "find" || global == "palette") && !isRune(key):
+		m.Finder.Close()
+	case key.String() == "ctrl+c":
+		return tea.Quit
+	case key.String() == "enter":
+		entry, ok := m.Finder.Selected()
+		if !ok || entry.disabled {
+			return nil
+		}
+		m.Finder.Close()
+		item := entry.item
+		switch {
+		case entry.action:
+			if entry.focus {
+				m.activate(item.Plugin)
 			}
+			return func() tea.Msg { return ActionMsg{Plugin: item.Plugin, ID: item.ID} }
+		case item.Ref != nil:
+			ref := *item.Ref
+			return func() tea.Msg { return OpenRefMsg{Ref: ref} }
 		}
-		if len(cmds) > 0 {
-			return m, tea.Batch(cmds...)
+		return func() tea.Msg { return SearchSelectMsg{Item: item} }
+	default:
+		m.Finder.Update(key)
+	}
+	return nil
+}
+
+// searchEntries collects the items of every searchable plugin, in sidebar
+// order.
+func (m Model) searchEntries() []finderEntry {
+	var entries []finderEntry
+	for _, name := range SortedPluginNames(m.Plugins) {
+		if s, ok := m.Plugins[name].(Searchable); ok {
+			for _, item := range s.SearchItems() {
+				entries = append(entries, finderEntry{item: item})
+			}
 		}
-		return m, nil
 	}
+	return entries
+}
+
+// focusedFirst returns the plugin names in sidebar order, with the
+// focused plugin moved to the front.
+func (m Model) focusedFirst() []string {
+	first := m.Active
+	if m.Overlay != nil {
+		first = m.Overlay.Name()
+	}
+	names := SortedPluginNames(m.Plugins)
+	sort.SliceStable(names, func(i, j int) bool { return names[i] == first && names[j] != first })
+	return names
+}
 
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-		case "c":
-			if chat, ok := m.Plugins["marchat"]; ok {
-				m.Overlay = chat
+// actionEntries lists every plugin's actions, the focused plugin's first,
+// with the keys the keymap gives them.
+func (m Model) actionEntries() []finderEntry {
+	var entries []finderEntry
+	for _, name := range m.focusedFirst() {
+		a, ok := m.Plugins[name].(Actor)
+		if !ok {
+			continue
+		}
+		for _, action := range m.Context.Keys.Bind(name, a.Actions()) {
+			item := types.SearchItem{Plugin: name, Kind: "action", Title: name + ": " + action.Title, ID: action.ID}
+			if action.Key != "" {
+				item.Detail = types.KeyLabel(action.Key)
 			}
-			return m, nil
-		case "esc":
-			m.Overlay = nil
-			return m, nil
-		case "tab":
-			// Use tab to switch between plugins instead of up/down
-			m.Active = NextPluginKey(m.Plugins, m.Active)
-			return m, nil
-		case "shift+tab":
-			// Use shift+tab to go backwards
-			m.Active = PrevPluginKey(m.Plugins, m.Active)
-			return m, nil
+			entries = append(entries, finderEntry{item: item, action: true, focus: action.Focus, disabled: !action.Available()})
 		}
 	}
+	return entries
+}
 
-	// Update active plugin - let it handle all keys including up/down
-	if p, ok := m.Plugins[m.Active]; ok {
-		updated, cmd := p.Update(msg)
-		m.Plugins[m.Active] = updated
-		return m, cmd
+// updateHelp handles keys while the key bindings are shown.
+func (m *Model) updateHelp(key tea.KeyMsg) tea.Cmd {
+	switch global := globalAction(m.Context.Keys, key.String()); {
+	case key.String() == "esc" || global == "help" || global == "close":
+		m.Help = nil
+	case global == "quit":
+		return tea.Quit
+	default:
+		m.Help.Update(key)
+	}
+	return nil
+}
+
+// focused returns the plugin that receives keys: the overlay if one is
+// open, otherwise the active plugin.
+func (m Model) focused() Plugin {
+	if m.Overlay != nil {
+		return m.Overlay
+	}
+	return m.Plugins[m.Active]
+}
+
+// updateFocused sends msg to the focused plugin and stores the result.
+// A key bound to one of the plugin's actions arrives as that action.
+func (m *Model) updateFocused(msg tea.Msg) tea.Cmd {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if p := m.focused(); p != nil {
+			msg = actionFor(p, m.Context.Keys, key)
+		}
+	}
+	if m.Overlay != nil {
+		updated, cmd := m.Overlay.Update(msg)
+		m.Overlay = updated
+		return cmd
+	}
+	p, ok := m.Plugins[m.Active]
+	if !ok {
+		return nil
 	}
+	updated, cmd := p.Update(msg)
+	m.Plugins[m.Active] = updated
+	return cmd
+}
+
+// actionFor returns the ActionMsg for the available action of p bound to
+// key, or key itself if there is none or p is capturing input.
+func actionFor(p Plugin, keys *Keymap, key tea.KeyMsg) tea.Msg {
+	a, ok := p.(Actor)
+	if !ok || capturing(p) {
+		return key
+	}
+	for _, action := range keys.Bind(p.Name(), a.Actions()) {
+		if action.Key == key.String() && action.Available() {
+			return ActionMsg{Plugin: p.Name(), ID: action.ID}
+		}
+	}
+	return key
+}
 
-	return m, nil
+func capturing(p Plugin) bool {
+	c, ok := p.(InputCapturer)
+	return ok && c.CapturingInput()
+}
+
+// isRune reports whether key types text, such as a global key like ? that
+// should go into the finder's query rather than close it.
+func isRune(key tea.KeyMsg) bool {
+	return key.Type == tea.KeyRunes || key.Type == tea.KeySpace
+}
+
+// broadcast delivers msg to every plugin and batches their commands.
+func (m Model) broadcast(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+	for name, plugin := range m.Plugins {
+		updated, cmd := plugin.Update(msg)
+		m.Plugins[name] = updated
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) View() string {
@@ -121,13 +373,22 @@ func (m Model) View() string {
 		if name == m.Active {
 			prefix = "> "
 		}
-		sb.WriteString(prefix + name + "\n")
+		sb.WriteString(prefix + name + m.Notices.Badge(name) + "\n")
 	}
 	sb.WriteString("\n")
+	if n := len(m.Notices.Items); n > 0 {
+		sb.WriteString(fmt.Sprintf("  🔔 %d  %s \n", n, strings.Join(m.Context.Keys.Global["notifications"], "/")))
+	}
 
 	// Main content
 	mainContent := ""
-	if m.Overlay != nil {
+	if m.Notices.Open {
+		mainContent = m.Notices.View()
+	} else if m.Finder.Open {
+		mainContent = m.Finder.View()
+	} else if m.Help != nil {
+		mainContent = m.Help.View() + "\n\n↑/↓ scroll • Esc close"
+	} else if m.Overlay != nil {
 		mainContent = m.Overlay.View()
 	} else if p, ok := m.Plugins[m.Active]; ok {
 		mainContent = p.View()
@@ -136,5 +397,9 @@ func (m Model) View() string {
 	}
 	styledContent := m.Styles.Render(mainContent)
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, sb.String(), styledContent)
+	view := lipgloss.JoinHorizontal(lipgloss.Top, sb.String(), styledContent)
+	if toasts := m.Notices.Toasts(); toasts != "" && !m.Notices.Open {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, toasts)
+	}
+	return view
 }
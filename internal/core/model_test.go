@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyPlugin records the keys it receives.
+type keyPlugin struct {
+	name string
+	keys *[]string
+}
+
+func (p keyPlugin) Init() tea.Cmd { return nil }
+func (p keyPlugin) View() string  { return "" }
+func (p keyPlugin) Name() string  { return p.name }
+func (p keyPlugin) Update(msg tea.Msg) (Plugin, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		*p.keys = append(*p.keys, p.name+":"+key.String())
+	}
+	return p, nil
+}
+
+// TestEsc checks that Esc closes the chat overlay, and otherwise goes to
+// the active plugin rather than being taken by Forger.
+func TestEsc(t *testing.T) {
+	var keys []string
+	m := NewModel()
+	m.Plugins = map[string]Plugin{
+		"codesleuth": keyPlugin{"codesleuth", &keys},
+		"marchat":    keyPlugin{"marchat", &keys},
+	}
+	m.Active = "codesleuth"
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+
+	updated, _ := m.Update(esc)
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	m = updated.(Model)
+	if m.Overlay == nil {
+		t.Fatal("C didn't open the chat overlay")
+	}
+	updated, _ = m.Update(esc)
+	m = updated.(Model)
+	if m.Overlay != nil {
+		t.Error("Esc didn't close the chat overlay")
+	}
+
+	want := []string{"codesleuth:esc", "marchat:esc"}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("plugins got %v, want %v", keys, want)
+	}
+}
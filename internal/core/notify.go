@@ -0,0 +1,233 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// NotifyMsg is emitted by plugins to raise a notification.
+type NotifyMsg = types.NotifyMsg
+
+const (
+	toastDuration    = 5 * time.Second
+	maxToasts        = 3
+	maxNotifications = 200
+	listHeight       = 15
+)
+
+// Notification is a NotifyMsg as recorded by the notification center.
+type Notification struct {
+	NotifyMsg
+	ID   int
+	Time time.Time
+}
+
+// Notifications collects notifications from every plugin. It shows recent
+// ones as toasts, keeps a bounded list for the overlay, and counts unseen
+// ones per plugin for the sidebar badges.
+type Notifications struct {
+	Items  []Notification // oldest first
+	Open   bool           // the list overlay is shown
+	unseen map[string][]types.Severity
+	toasts []int // IDs of notifications still shown as toasts
+	nextID int
+	cursor int // index into Items while the list is open
+}
+
+// NewNotifications returns an empty notification center.
+func NewNotifications() *Notifications {
+	return &Notifications{unseen: make(map[string][]types.Severity)}
+}
+
+// toastExpiredMsg removes a toast once its time is up.
+type toastExpiredMsg struct {
+	ID int
+}
+
+// Add records msg and returns a command that expires its toast. Unless
+// viewing is the plugin the notification came from, it also badges it.
+func (n *Notifications) Add(msg NotifyMsg, viewing string) tea.Cmd {
+	n.nextID++
+	note := Notification{NotifyMsg: msg, ID: n.nextID, Time: time.Now()}
+	n.Items = append(n.Items, note)
+	if len(n.Items) > maxNotifications {
+		n.Items = n.Items[len(n.Items)-maxNotifications:]
+	}
+	if msg.Source != viewing {
+		n.unseen[msg.Source] = append(n.unseen[msg.Source], msg.Severity)
+	}
+	n.toasts = append(n.toasts, note.ID)
+	if len(n.toasts) > maxToasts {
+		n.toasts = n.toasts[len(n.toasts)-maxToasts:]
+	}
+	id := note.ID
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg { return toastExpiredMsg{ID: id} })
+}
+
+func (n *Notifications) expire(id int) {
+	for i, t := range n.toasts {
+		if t == id {
+			n.toasts = append(n.toasts[:i], n.toasts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Seen clears the badge for a plugin.
+func (n *Notifications) Seen(source string) {
+	delete(n.unseen, source)
+}
+
+// Badge renders the sidebar badge for a plugin, colored by the most severe
+// unseen notification, or "" when there is nothing new.
+func (n *Notifications) Badge(source string) string {
+	unseen := n.unseen[source]
+	if len(unseen) == 0 {
+		return ""
+	}
+	worst := types.SeverityInfo
+	for _, s := range unseen {
+		if s > worst {
+			worst = s
+		}
+	}
+	return severityStyle(worst).Render(fmt.Sprintf(" (%d)", len(unseen)))
+}
+
+func (n *Notifications) find(id int) (Notification, bool) {
+	for i := len(n.Items) - 1; i >= 0; i-- {
+		if n.Items[i].ID == id {
+			return n.Items[i], true
+		}
+	}
+	return Notification{}, false
+}
+
+// Toasts renders the notifications currently shown as toasts, newest last.
+func (n *Notifications) Toasts() string {
+	var lines []string
+	for _, id := range n.toasts {
+		if note, ok := n.find(id); ok {
+			lines = append(lines, toastStyle.BorderForeground(severityColor(note.Severity)).Render(formatNotification(note)))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// Toggle opens or closes the list; opening it marks everything seen.
+func (n *Notifications) Toggle() {
+	n.Open = !n.Open
+	if n.Open {
+		n.unseen = make(map[string][]types.Severity)
+		n.toasts = nil
+		n.cursor = len(n.Items) - 1
+	}
+}
+
+// Update handles navigation keys while the list is open.
+func (n *Notifications) Update(key tea.KeyMsg) {
+	switch key.String() {
+	case "up", "k":
+		if n.cursor > 0 {
+			n.cursor--
+		}
+	case "down", "j":
+		if n.cursor < len(n.Items)-1 {
+			n.cursor++
+		}
+	case "home", "g":
+		n.cursor = 0
+	case "end", "G":
+		n.cursor = len(n.Items) - 1
+	case "x":
+		n.Items = nil
+		n.cursor = 0
+	}
+}
+
+// Selected returns the notification under the cursor.
+func (n *Notifications) Selected() (Notification, bool) {
+	if n.cursor < 0 || n.cursor >= len(n.Items) {
+		return Notification{}, false
+	}
+	return n.Items[n.cursor], true
+}
+
+// View renders the list, newest at the bottom, around the cursor.
+func (n *Notifications) View() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Notifications (%d)\n\n", len(n.Items)))
+	if len(n.Items) == 0 {
+		sb.WriteString("Nothing yet.\n")
+	}
+	start := n.cursor - listHeight/2
+	if start > len(n.Items)-listHeight {
+		start = len(n.Items) - listHeight
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + listHeight
+	if end > len(n.Items) {
+		end = len(n.Items)
+	}
+	for i := start; i < end; i++ {
+		line := formatNotification(n.Items[i])
+		if i == n.cursor {
+			line = cursorStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n↑/↓ select • Enter open • x clear • Esc close")
+	return sb.String()
+}
+
+func formatNotification(note Notification) string {
+	text := strings.ReplaceAll(note.Text, "\n", " ")
+	if r := []rune(text); len(r) > 70 {
+		text = string(r[:67]) + "..."
+	}
+	return fmt.Sprintf("%s %s %s: %s", note.Time.Format("15:04:05"),
+		severityStyle(note.Severity).Render(severityIcon(note.Severity)), note.Source, text)
+}
+
+var (
+	toastStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	cursorStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+func severityColor(s types.Severity) lipgloss.Color {
+	switch s {
+	case types.SeveritySuccess:
+		return lipgloss.Color("10")
+	case types.SeverityWarning:
+		return lipgloss.Color("11")
+	case types.SeverityError:
+		return lipgloss.Color("9")
+	}
+	return lipgloss.Color("12")
+}
+
+func severityStyle(s types.Severity) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(severityColor(s))
+}
+
+func severityIcon(s types.Severity) string {
+	switch s {
+	case types.SeveritySuccess:
+		return "✔"
+	case types.SeverityWarning:
+		return "▲"
+	case types.SeverityError:
+		return "✖"
+	}
+	return "●"
+}
@@ -4,3 +4,15 @@ import "forger/internal/types"
 
 // Plugin is the interface every plugin must implement.
 type Plugin = types.Plugin
+
+// Closer is implemented by plugins that need cleanup on exit.
+type Closer = types.Closer
+
+// InputCapturer is implemented by plugins that take over the keyboard.
+type InputCapturer = types.InputCapturer
+
+// Searchable is implemented by plugins that offer items to the finder.
+type Searchable = types.Searchable
+
+// Actor is implemented by plugins that declare actions.
+type Actor = types.Actor
@@ -2,10 +2,16 @@ package core
 
 import (
 	"fmt"
-	"sort"
 	"forger/internal/plugins/codesleuth"
+	"forger/internal/plugins/colorizer"
+	"forger/internal/plugins/command"
+	"forger/internal/plugins/env"
+	"forger/internal/plugins/external"
+	"forger/internal/plugins/gotest"
 	"forger/internal/plugins/ignoregrets"
 	"forger/internal/plugins/marchat"
+	"forger/internal/plugins/parsec"
+	"sort"
 )
 
 // PluginFactory creates a Plugin given shared Context.
@@ -16,17 +22,36 @@ var availablePlugins = map[string]PluginFactory{
 	"ignoregrets": ignoregrets.New,
 	"codesleuth":  codesleuth.New,
 	"marchat":     marchat.New,
-	// add ascii-colorizer, parsec, etc.
+	// ascii-colorizer is named by types.ColorizerPlugin so other plugins
+	// can send it artifacts.
+	"ascii-colorizer": colorizer.New,
+	"parsec":          parsec.New,
+	"gotest":          gotest.New,
+	"env":             env.New,
 }
 
-// LoadPlugins instantiates each enabled plugin or records errors.
+// LoadPlugins instantiates each enabled plugin or records errors. Names
+// not built in are command plugins if their config section defines a
+// command, and are otherwise looked up among the external plugins.
 func LoadPlugins(enabled []string, ctx *Context) (map[string]Plugin, []string) {
 	loaded := make(map[string]Plugin)
 	var errors []string
+	var externals map[string]external.Manifest
 
 	for _, name := range enabled {
 		if factory, ok := availablePlugins[name]; ok {
 			loaded[name] = factory(ctx)
+			continue
+		}
+		if command.IsSpec(ctx.PluginConfig[name]) {
+			loaded[name] = command.New(ctx, name)
+			continue
+		}
+		if externals == nil {
+			externals, errors = externalPlugins(errors)
+		}
+		if m, ok := externals[name]; ok {
+			loaded[name] = external.New(ctx, m)
 		} else {
 			msg := fmt.Sprintf("plugin '%s' not found in registry", name)
 			LogError(msg)
@@ -36,6 +61,37 @@ func LoadPlugins(enabled []string, ctx *Context) (map[string]Plugin, []string) {
 	return loaded, errors
 }
 
+// externalPlugins discovers the plugins in the external plugins directory,
+// appending any problems found to errors.
+func externalPlugins(errors []string) (map[string]external.Manifest, []string) {
+	found := make(map[string]external.Manifest)
+	dir, err := external.Dir()
+	if err != nil {
+		return found, append(errors, fmt.Sprintf("external plugins: %v", err))
+	}
+	manifests, errs := external.Discover(dir)
+	for _, err := range errs {
+		msg := fmt.Sprintf("external plugin: %v", err)
+		LogError(msg)
+		errors = append(errors, msg)
+	}
+	for _, m := range manifests {
+		found[m.Name] = m
+	}
+	return found, errors
+}
+
+// ClosePlugins releases resources held by plugins that implement Closer.
+func ClosePlugins(plugins map[string]Plugin) {
+	for name, plugin := range plugins {
+		if c, ok := plugin.(Closer); ok {
+			if err := c.Close(); err != nil {
+				LogError(fmt.Sprintf("failed to close plugin '%s': %v", name, err))
+			}
+		}
+	}
+}
+
 // SortedPluginNames returns plugin names sorted alphabetically.
 func SortedPluginNames(plugins map[string]Plugin) []string {
 	names := make([]string, 0, len(plugins))
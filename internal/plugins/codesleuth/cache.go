@@ -0,0 +1,192 @@
+package codesleuth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheDir is where analysis results are stored, relative to the workspace.
+const cacheDir = ".forger/cache/codesleuth"
+
+// cacheEntry is one cached codesleuth run for a single file.
+type cacheEntry struct {
+	File        string    `json:"file"`
+	Hash        string    `json:"hash"`
+	ToolVersion string    `json:"tool_version"`
+	Mode        string    `json:"mode"`
+	Output      string    `json:"output"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// cacheMaxAge is how long an entry is kept after it was last used.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// Cache stores analysis output keyed by file, content hash, analysis mode
+// and tool version, so unchanged files never hit codesleuth twice.
+type Cache struct {
+	Dir string
+}
+
+// cacheKey identifies one analysis. File is relative to the analyzed root,
+// so a snapshot or commit checked out in a temporary directory shares
+// entries with the working tree.
+type cacheKey struct {
+	File        string
+	Hash        string
+	Mode        string
+	ToolVersion string
+}
+
+// CacheStats summarizes the cache contents.
+type CacheStats struct {
+	Entries int
+	Bytes   int64
+	ByMode  map[string]int
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(k cacheKey) string {
+	key := contentHash([]byte(k.ToolVersion + "\x00" + k.Mode + "\x00" + k.File + "\x00" + k.Hash))
+	return filepath.Join(c.Dir, key[:32]+".json")
+}
+
+// Get returns the cached output for k, if present, and marks the entry
+// as used so Prune keeps it.
+func (c *Cache) Get(k cacheKey) (string, bool) {
+	path := c.path(k)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return "", false
+	}
+	// Guard against key collisions and stale formats.
+	if entry.File != k.File || entry.Hash != k.Hash || entry.Mode != k.Mode || entry.ToolVersion != k.ToolVersion {
+		return "", false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry.Output, true
+}
+
+// Put writes the output for k to the cache.
+func (c *Cache) Put(k cacheKey, output string) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	entry := cacheEntry{File: k.File, Hash: k.Hash, ToolVersion: k.ToolVersion, Mode: k.Mode, Output: output, CreatedAt: time.Now()}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := c.path(k)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Prune removes entries written by another codesleuth build, which can
+// never be used again, and entries unused for longer than maxAge. It
+// returns how many entries were removed.
+func (c *Cache) Prune(toolVersion string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(c.Dir, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stale := time.Since(info.ModTime()) > maxAge
+		if !stale {
+			var entry cacheEntry
+			data, err := os.ReadFile(path)
+			stale = err == nil && (json.Unmarshal(data, &entry) != nil || entry.ToolVersion != toolVersion)
+		}
+		if stale && os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Stats walks the cache directory. A missing directory is an empty cache.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{ByMode: make(map[string]int)}
+	entries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if data, err := os.ReadFile(filepath.Join(c.Dir, e.Name())); err == nil {
+			var entry cacheEntry
+			if json.Unmarshal(data, &entry) == nil {
+				stats.ByMode[entry.Mode]++
+			}
+		}
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every cached entry.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+func (s CacheStats) String() string {
+	if s.Entries == 0 {
+		return "Cache is empty"
+	}
+	var modes []string
+	for mode, n := range s.ByMode {
+		modes = append(modes, fmt.Sprintf("%s=%d", mode, n))
+	}
+	sort.Strings(modes)
+	return fmt.Sprintf("%d entries, %.1f KiB (%s)\nOldest %s, newest %s",
+		s.Entries, float64(s.Bytes)/1024, strings.Join(modes, " "),
+		s.Oldest.Format("2006-01-02 15:04"), s.Newest.Format("2006-01-02 15:04"))
+}
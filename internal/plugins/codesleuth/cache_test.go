@@ -0,0 +1,111 @@
+package codesleuth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheGet(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	key := cacheKey{File: "src/pay.cbl", Hash: "abc", Mode: "analyze", ToolVersion: "1.0"}
+	if err := c.Put(key, "3 paragraphs"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		key  cacheKey
+		hit  bool
+	}{
+		{"same key", key, true},
+		{"other file", cacheKey{File: "src/tax.cbl", Hash: "abc", Mode: "analyze", ToolVersion: "1.0"}, false},
+		{"changed content", cacheKey{File: "src/pay.cbl", Hash: "abd", Mode: "analyze", ToolVersion: "1.0"}, false},
+		{"other mode", cacheKey{File: "src/pay.cbl", Hash: "abc", Mode: "mermaid", ToolVersion: "1.0"}, false},
+		{"other tool version", cacheKey{File: "src/pay.cbl", Hash: "abc", Mode: "analyze", ToolVersion: "1.1"}, false},
+	}
+	for _, tt := range tests {
+		output, hit := c.Get(tt.key)
+		if hit != tt.hit {
+			t.Errorf("%s: hit = %t, want %t", tt.name, hit, tt.hit)
+		}
+		if hit && output != "3 paragraphs" {
+			t.Errorf("%s: output = %q", tt.name, output)
+		}
+	}
+}
+
+// TestAnalyzeFileSharesEntriesAcrossRoots checks that a file analyzed in
+// the working tree is served from the cache when the same content turns
+// up in a snapshot's temporary directory, and that changed content is
+// analyzed again.
+func TestAnalyzeFileSharesEntriesAcrossRoots(t *testing.T) {
+	t.Setenv("GOPATH", t.TempDir()) // no codesleuth: a cache miss fails
+	a := analyzer{cache: &Cache{Dir: t.TempDir()}, version: "1.0"}
+	write := func(root, content string) {
+		t.Helper()
+		path := filepath.Join(root, "src", "pay.cbl")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	worktree, snapshot := t.TempDir(), t.TempDir()
+	write(worktree, "PROCEDURE DIVISION.")
+	write(snapshot, "PROCEDURE DIVISION.")
+	key := cacheKey{File: "src/pay.cbl", Hash: contentHash([]byte("PROCEDURE DIVISION.")), Mode: "analyze", ToolVersion: "1.0"}
+	if err := a.cache.Put(key, "ok"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, root := range []string{worktree, snapshot} {
+		output, hit, err := a.analyzeFile(root, filepath.Join("src", "pay.cbl"), "analyze")
+		if err != nil || !hit || output != "ok" {
+			t.Errorf("%s: got %q, hit %t, err %v; want a cache hit", root, output, hit, err)
+		}
+	}
+	write(snapshot, "PROCEDURE DIVISION. STOP RUN.")
+	if _, hit, err := a.analyzeFile(snapshot, filepath.Join("src", "pay.cbl"), "analyze"); hit || err == nil {
+		t.Errorf("changed file: hit %t, err %v; want a miss that runs codesleuth", hit, err)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	keys := map[string]cacheKey{
+		"current":  {File: "a.cbl", Hash: "1", Mode: "analyze", ToolVersion: "2.0"},
+		"old tool": {File: "a.cbl", Hash: "1", Mode: "analyze", ToolVersion: "1.0"},
+		"unused":   {File: "b.cbl", Hash: "2", Mode: "analyze", ToolVersion: "2.0"},
+		"reused":   {File: "c.cbl", Hash: "3", Mode: "analyze", ToolVersion: "2.0"},
+	}
+	for _, k := range keys {
+		if err := c.Put(k, "output"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	long := time.Now().Add(-2 * cacheMaxAge)
+	for _, name := range []string{"unused", "reused"} {
+		if err := os.Chtimes(c.path(keys[name]), long, long); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Get(keys["reused"]) // a hit marks the entry as used
+
+	removed, err := c.Prune("2.0", cacheMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d entries, want 2", removed)
+	}
+	for name, want := range map[string]bool{"current": true, "old tool": false, "unused": false, "reused": true} {
+		if _, hit := c.Get(keys[name]); hit != want {
+			t.Errorf("%s: kept = %t, want %t", name, hit, want)
+		}
+	}
+	if removed, err := (&Cache{Dir: filepath.Join(t.TempDir(), "missing")}).Prune("2.0", cacheMaxAge); removed != 0 || err != nil {
+		t.Errorf("missing dir: removed %d, err %v", removed, err)
+	}
+}
@@ -3,10 +3,14 @@ package codesleuth
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"forger/internal/runner"
 	"forger/internal/types"
+	"forger/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -16,137 +20,638 @@ type Plugin struct {
 	available     bool
 	selectedIndex int
 	result        string // Add result field for command feedback
+	files         []string
+	symbols       []Symbol       // declarations in files, for the fuzzy finder
+	source        *ui.SourceView // non-nil while a file is open
+	pager         *ui.Pager      // non-nil while a report is shown
+	height        int
+	cache         *Cache
+	version       string // codesleuth build, part of every cache key
+	points        []Point
+	pointIndex    int
+	picking       bool   // choosing a point in time
+	base          *Point // marked as the base for comparisons
+	exporting     bool   // editing the export path
+	exportPath    string
+
+	goMu     sync.Mutex // guards the memoized Go analysis below
+	goMod    *goModule
+	goModKey string
 }
 
 func New(ctx *types.Context) types.Plugin {
 	return &Plugin{
 		ctx:           ctx,
 		selectedIndex: 0,
+		height:        20,
+		cache:         &Cache{Dir: cacheDir},
 	}
 }
 
 func (p *Plugin) Init() tea.Cmd {
-	return p.checkAvailability()
+	return tea.Batch(p.checkAvailability(), p.listFiles)
+}
+
+func codesleuthPath() string {
+	return runner.GopathTool("codesleuth")
 }
 
 func (p *Plugin) checkAvailability() tea.Cmd {
 	return func() tea.Msg {
-		codesleuthPath := "C:\\Users\\codyl\\go\\bin\\codesleuth.exe"
+		path := codesleuthPath()
 
-		if _, err := os.Stat(codesleuthPath); os.IsNotExist(err) {
-			return AvailabilityMsg{Available: false, Error: fmt.Sprintf("codesleuth not found at: %s", codesleuthPath)}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return AvailabilityMsg{Available: false, Error: fmt.Sprintf("codesleuth not found at: %s", path)}
 		}
 
-		cmd := exec.Command(codesleuthPath, "--help")
-		if err := cmd.Run(); err != nil {
-			return AvailabilityMsg{Available: false, Error: fmt.Sprintf("codesleuth failed to run: %v", err)}
+		if res := runner.Run("", path, "--help"); res.Err != nil {
+			return AvailabilityMsg{Available: false, Error: fmt.Sprintf("codesleuth failed to run: %v", res.Err)}
 		}
-		return AvailabilityMsg{Available: true}
+		return AvailabilityMsg{Available: true, Version: toolVersion(path)}
+	}
+}
+
+// toolVersion identifies the installed codesleuth build for cache keys.
+// Builds without a version flag are identified by size and mtime.
+func toolVersion(path string) string {
+	if res := runner.Run("", path, "--version"); res.Err == nil && strings.TrimSpace(res.Output) != "" {
+		return strings.TrimSpace(res.Output)
 	}
+	if info, err := os.Stat(path); err == nil {
+		return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().Unix())
+	}
+	return "unknown"
 }
 
 func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
 	switch msg := msg.(type) {
 	case AvailabilityMsg:
 		p.available = msg.Available
+		p.version = msg.Version
+		if msg.Available {
+			// Entries from other codesleuth builds can't be used again.
+			cache, version := p.cache, msg.Version
+			return p, func() tea.Msg {
+				cache.Prune(version, cacheMaxAge)
+				return nil
+			}
+		}
 		return p, nil
 	case CommandResultMsg:
-		// Display command results
-		if msg.Success {
-			p.result = "✅ " + msg.Output
-		} else {
+		// The result pane shows the output; a notice is only raised for
+		// errors and for commands slow enough that the user looked away.
+		if !msg.Success {
 			p.result = "❌ " + msg.Output
+			return p, types.Notify(p.Name(), types.SeverityError, types.FirstLine(msg.Output))
+		}
+		p.result = "✅ " + msg.Output
+		if msg.Elapsed >= slowCommand {
+			return p, types.Notify(p.Name(), types.SeveritySuccess, types.FirstLine(msg.Output))
+		}
+		return p, nil
+	case FilesMsg:
+		p.files, p.symbols = msg.Files, msg.Symbols
+		if p.selectedIndex >= len(p.files) {
+			p.selectedIndex = 0
+		}
+		return p, nil
+	case SourceMsg:
+		if msg.Err != nil {
+			p.result = "❌ " + msg.Err.Error()
+			return p, nil
+		}
+		p.source = ui.NewSourceView(msg.Path, msg.Content, markers(msg.Findings))
+		p.source.Height = p.height
+		if msg.Line > 0 {
+			p.source.GotoLine(msg.Line)
+		} else {
+			p.source.NextMarker()
+		}
+		return p, nil
+	case types.OpenRefMsg:
+		if msg.Ref.Plugin != p.Name() {
+			return p, nil
+		}
+		// References arrive over chat; only follow ones inside the workspace.
+		path := filepath.FromSlash(msg.Ref.Target)
+		if !filepath.IsLocal(path) {
+			p.result = "❌ Refusing to open " + msg.Ref.Target + ": outside the workspace"
+			return p, nil
+		}
+		p.pager, p.picking, p.exporting = nil, false, false
+		line, a := msg.Ref.Line, p.analyzer()
+		return p, func() tea.Msg {
+			m := p.openFile(a, path)
+			if src, ok := m.(SourceMsg); ok {
+				src.Line = line
+				return src
+			}
+			return m
+		}
+	case PointsMsg:
+		p.points = msg.Points
+		p.pointIndex = 0
+		p.picking = true
+		return p, nil
+	case PointAnalysisMsg:
+		if msg.Err != nil {
+			p.result = "❌ " + msg.Err.Error()
+			return p, nil
+		}
+		title := fmt.Sprintf("Findings at %s (%d)", msg.Point, len(msg.Findings))
+		p.showPager(title, formatFindings(msg.Findings))
+		return p, types.Notify(p.Name(), types.SeveritySuccess, "Analysis finished: "+title)
+	case FindingDiffMsg:
+		if msg.Err != nil {
+			p.result = "❌ " + msg.Err.Error()
+			return p, nil
+		}
+		report := fmt.Sprintf("Introduced (%d):\n%s\nResolved (%d):\n%s",
+			len(msg.Introduced), formatFindings(msg.Introduced), len(msg.Resolved), formatFindings(msg.Resolved))
+		p.showPager(fmt.Sprintf("Findings %s → %s", msg.Base, msg.Target), report)
+		severity := types.SeveritySuccess
+		if len(msg.Introduced) > 0 {
+			severity = types.SeverityWarning
+		}
+		return p, types.Notify(p.Name(), severity, fmt.Sprintf("Comparison finished: %d introduced, %d resolved", len(msg.Introduced), len(msg.Resolved)))
+	case tea.WindowSizeMsg:
+		// Leave room for the sidebar border, header and status lines.
+		if h := msg.Height - 8; h > 5 {
+			p.height = h
+			if p.source != nil {
+				p.source.Height = h
+			}
+			if p.pager != nil {
+				p.pager.Height = h
+			}
 		}
 		return p, nil
 	case tea.KeyMsg:
+		if p.source != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				p.source = nil
+			default:
+				p.source.Update(msg)
+			}
+			return p, nil
+		}
+		if p.pager != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				p.pager = nil
+			default:
+				p.pager.Update(msg)
+			}
+			return p, nil
+		}
+		if p.picking {
+			return p, p.updatePicker(msg)
+		}
+		if p.exporting {
+			return p, p.updateExport(msg)
+		}
 		switch msg.String() {
-		case "a":
-			return p, p.analyzeCurrentDirectory
-		case "i":
-			return p, p.showIRDiagram
-		case "r":
-			return p, p.findReferences
-		case "g":
-			return p, p.showCallGraph
+		case "up":
+			if p.selectedIndex > 0 {
+				p.selectedIndex--
+			}
+		case "down":
+			if p.selectedIndex < len(p.files)-1 {
+				p.selectedIndex++
+			}
+		case "enter":
+			if p.selectedIndex < len(p.files) {
+				path, a := p.files[p.selectedIndex], p.analyzer()
+				return p, func() tea.Msg { return p.openFile(a, path) }
+			}
 		case "ctrl+c":
 			return p, tea.Quit
 		}
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
 	}
 	return p, nil
 }
 
-func (p *Plugin) analyzeCurrentDirectory() tea.Msg {
-	codesleuthPath := os.Getenv("GOPATH") + "\\bin\\codesleuth.exe"
-	cmd := exec.Command(codesleuthPath, "analyze", ".")
-	output, err := cmd.CombinedOutput() // Use CombinedOutput to get both stdout and stderr
-	if err != nil {
-		return CommandResultMsg{
-			Success: false,
-			Output:  fmt.Sprintf("CodeSleuth only supports COBOL files. Current directory contains Go files.\nError: %v\nOutput: %s", err, string(output)),
+// Actions lists the analyses and reports. Sharing and colorizing apply to
+// the open source view or report, so they are in their own mode; the rest
+// apply to the file list.
+func (p *Plugin) Actions() []types.Action {
+	return []types.Action{
+		{ID: "analyze", Title: "Analyze current directory (COBOL or Go)", Key: "a", Enabled: p.browsing},
+		{ID: "ir", Title: "Show IR diagram", Key: "i", Enabled: p.browsing},
+		{ID: "references", Title: "Find references", Key: "r", Enabled: p.browsing},
+		{ID: "call-graph", Title: "Show call graph", Key: "g", Enabled: p.browsing},
+		{ID: "result", Title: "Open full result", Key: "o", Focus: true, Enabled: func() bool { return p.browsing() && p.result != "" }},
+		{ID: "export", Title: "Export report", Key: "e", Focus: true, Enabled: p.browsing},
+		{ID: "points", Title: "Analyze/compare a snapshot or commit", Key: "t", Focus: true, Enabled: p.browsing},
+		{ID: "cache-stats", Title: "Show cache stats", Key: "v", Enabled: p.browsing},
+		{ID: "clear-cache", Title: "Clear analysis cache", Key: "x", Enabled: p.browsing},
+		{ID: "share", Title: "Share source line or report to chat", Key: "p", Mode: "report", Enabled: func() bool { return p.source != nil || p.pager != nil }},
+		{ID: "colorize", Title: "View report in colorizer", Key: "v", Mode: "report", Enabled: func() bool { return p.pager != nil }},
+	}
+}
+
+// browsing reports whether the file list is shown.
+func (p *Plugin) browsing() bool {
+	return p.source == nil && p.pager == nil && !p.picking && !p.exporting
+}
+
+func (p *Plugin) runAction(id string) tea.Cmd {
+	switch id {
+	case "analyze":
+		return p.analyzeCurrentDirectory()
+	case "ir":
+		return p.showIRDiagram()
+	case "references":
+		return p.findReferences()
+	case "call-graph":
+		return p.showCallGraph()
+	case "result":
+		if p.result != "" {
+			p.showPager("Last result", p.result)
+		}
+	case "export":
+		p.exporting = true
+		if p.exportPath == "" {
+			p.exportPath = "codesleuth-report.md"
+		}
+	case "points":
+		// GlobalState belongs to the Update loop, so read it here rather
+		// than in the command.
+		snapshot, _ := p.ctx.GlobalState[types.StateSnapshot].(string)
+		return listPoints(snapshot)
+	case "cache-stats":
+		return p.showCacheStats()
+	case "clear-cache":
+		return p.clearCache
+	case "share":
+		if p.source != nil {
+			return p.shareSourceLine()
+		}
+		if p.pager != nil {
+			text := fmt.Sprintf("🔎 CodeSleuth: %s\n%s", p.pager.Title, types.Excerpt(strings.Join(p.pager.Lines, "\n"), 10))
+			return p.share(text)
+		}
+	case "colorize":
+		if p.pager != nil {
+			// Call graphs and IR diagrams read better colored.
+			colorize := types.ColorizeMsg{From: p.Name(), Title: p.pager.Title, Text: strings.Join(p.pager.Lines, "\n")}
+			return func() tea.Msg { return colorize }
 		}
 	}
+	return nil
+}
 
-	// Since CodeSleuth doesn't support JSON output, we'll just show the raw output
-	return CommandResultMsg{
-		Success: true,
-		Output:  fmt.Sprintf("CodeSleuth analysis output:\n%s", string(output)),
+// updatePicker handles keys while choosing a point in time.
+func (p *Plugin) updatePicker(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "up":
+		if p.pointIndex > 0 {
+			p.pointIndex--
+		}
+	case "down":
+		if p.pointIndex < len(p.points)-1 {
+			p.pointIndex++
+		}
+	case "enter":
+		if p.pointIndex < len(p.points) {
+			pt := p.points[p.pointIndex]
+			p.picking = false
+			p.result = "Analyzing " + pt.String() + "..."
+			a := p.analyzer()
+			return func() tea.Msg { return p.analyzePoint(a, pt) }
+		}
+	case "b":
+		if p.pointIndex < len(p.points) {
+			pt := p.points[p.pointIndex]
+			p.base = &pt
+		}
+	case "d":
+		if p.pointIndex < len(p.points) {
+			// Without a marked base, compare the selection with the working tree.
+			base, target := p.points[p.pointIndex], Point{Kind: PointWorktree}
+			if p.base != nil {
+				base, target = *p.base, p.points[p.pointIndex]
+			}
+			p.picking = false
+			p.result = fmt.Sprintf("Comparing %s → %s...", base, target)
+			a := p.analyzer()
+			return func() tea.Msg { return p.comparePoints(a, base, target) }
+		}
+	case "esc", "backspace":
+		p.picking = false
 	}
+	return nil
 }
 
-func (p *Plugin) showIRDiagram() tea.Msg {
-	codesleuthPath := os.Getenv("GOPATH") + "\\bin\\codesleuth.exe"
-	cmd := exec.Command(codesleuthPath, "analyze", ".", "--mermaid")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return CommandResultMsg{
-			Success: false,
-			Output:  fmt.Sprintf("Error generating IR diagram: %v\n%s", err, string(output)),
+// shareSourceLine posts the finding, or failing that the source line,
+// under the cursor to chat with a reference back to it.
+func (p *Plugin) shareSourceLine() tea.Cmd {
+	line := p.source.CursorLine()
+	path := filepath.ToSlash(p.source.Path)
+	var sb strings.Builder
+	if ms := p.source.CursorMarkers(); len(ms) > 0 {
+		for _, m := range ms {
+			sb.WriteString(fmt.Sprintf("🔎 %s:%d %s\n", path, line, m.Note))
 		}
+	} else {
+		sb.WriteString(fmt.Sprintf("🔎 %s:%d\n", path, line))
+	}
+	if line-1 < len(p.source.Lines) {
+		sb.WriteString("  " + strings.TrimSpace(p.source.Lines[line-1]) + "\n")
 	}
+	sb.WriteString(types.Ref{Plugin: p.Name(), Target: path, Line: line}.String())
+	return p.share(sb.String())
+}
+
+func (p *Plugin) share(text string) tea.Cmd {
+	p.result = "✅ Shared to chat"
+	return func() tea.Msg { return types.ShareMsg{From: p.Name(), Text: text} }
+}
+
+// CapturingInput reports whether the export path is being edited, so
+// typing q or c there doesn't trigger Forger's global shortcuts.
+func (p *Plugin) CapturingInput() bool {
+	return p.exporting
+}
 
-	return CommandResultMsg{
-		Success: true,
-		Output:  fmt.Sprintf("IR Diagram:\n%s", string(output)),
+// updateExport edits the export path. Ctrl+F cycles the file extension
+// through the supported formats.
+func (p *Plugin) updateExport(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		path := p.exportPath
+		p.exporting = false
+		p.result = "Exporting report to " + path + "..."
+		a := p.analyzer()
+		return timed(func() CommandResultMsg { return p.exportReport(a, path) })
+	case "esc":
+		p.exporting = false
+	case "backspace":
+		if r := []rune(p.exportPath); len(r) > 0 {
+			p.exportPath = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		p.exportPath = ""
+	case "ctrl+f":
+		ext := filepath.Ext(p.exportPath)
+		next := exportExtensions[0]
+		for i, e := range exportExtensions {
+			if e == strings.ToLower(ext) {
+				next = exportExtensions[(i+1)%len(exportExtensions)]
+			}
+		}
+		p.exportPath = strings.TrimSuffix(p.exportPath, ext) + next
+	default:
+		if msg.Type == tea.KeyRunes {
+			p.exportPath += string(msg.Runes)
+		}
 	}
+	return nil
+}
+
+func (p *Plugin) showPager(title, text string) {
+	p.pager = ui.NewPager(title, text)
+	p.pager.Height = p.height
+	p.result = ""
+}
+
+func (p *Plugin) listFiles() tea.Msg {
+	files := sourceFiles(".", func(path string) bool { return ui.LanguageFor(path) != nil })
+	return FilesMsg{Files: files, Symbols: findSymbols(files)}
+}
+
+func isCOBOL(path string) bool {
+	return ui.LanguageFor(path) == ui.LanguageFor(".cbl")
+}
+
+// sourceFiles walks root for files accepted by match, skipping hidden and
+// vendored directories, and those the go tool ignores: testdata and names
+// starting with an underscore.
+func sourceFiles(root string, match func(string) bool) []string {
+	var files []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "node_modules" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if match(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
 }
 
-func (p *Plugin) findReferences() tea.Msg {
-	codesleuthPath := os.Getenv("GOPATH") + "\\bin\\codesleuth.exe"
-	cmd := exec.Command(codesleuthPath, "analyze", ".", "--references")
-	output, err := cmd.CombinedOutput()
+// openFile reads path and collects findings for it from the built-in COBOL
+// scanner and codesleuth, or from the built-in Go analyzer.
+func (p *Plugin) openFile(a analyzer, path string) tea.Msg {
+	data, err := os.ReadFile(path)
 	if err != nil {
+		return SourceMsg{Path: path, Err: fmt.Errorf("failed to read %s: %v", path, err)}
+	}
+	content := string(data)
+
+	var findings []Finding
+	switch {
+	case isCOBOL(path):
+		findings = scanCOBOL(path, content)
+		if a.available {
+			output, _, _ := a.analyzeFile(".", path, "analyze")
+			findings = mergeFindings(findings, parseFindings(path, output))
+		}
+	case isGo(path):
+		if mod, err := p.workspaceGoModule(); err == nil {
+			for _, f := range mod.Findings() {
+				if samePath(path, f.File) {
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+	return SourceMsg{Path: path, Content: content, Findings: findings}
+}
+
+// analysisFlags maps each analysis mode to the extra codesleuth flags it needs.
+var analysisFlags = map[string][]string{
+	"analyze":    nil,
+	"mermaid":    {"--mermaid"},
+	"references": {"--references"},
+	"call-graph": {"--call-graph"},
+}
+
+func (p *Plugin) analyzeCurrentDirectory() tea.Cmd {
+	return p.runAnalysis("analyze", "CodeSleuth analysis output")
+}
+
+func (p *Plugin) showIRDiagram() tea.Cmd {
+	return p.runAnalysis("mermaid", "IR Diagram")
+}
+
+func (p *Plugin) findReferences() tea.Cmd {
+	return p.runAnalysis("references", "References")
+}
+
+func (p *Plugin) showCallGraph() tea.Cmd {
+	return p.runAnalysis("call-graph", "Call Graph")
+}
+
+// runAnalysis runs mode over every COBOL file in the workspace, serving
+// unchanged files from the cache. Go modules go to the built-in backend;
+// otherwise codesleuth analyzes the directory so it can report why.
+func (p *Plugin) runAnalysis(mode, title string) tea.Cmd {
+	a := p.analyzer()
+	return timed(func() CommandResultMsg {
+		files := sourceFiles(".", isCOBOL)
+		if len(files) == 0 && len(sourceFiles(".", isGo)) > 0 {
+			return p.runGoAnalysis(mode, title)
+		}
+		if len(files) == 0 {
+			args := append([]string{"analyze", "."}, analysisFlags[mode]...)
+			res := runner.Run("", codesleuthPath(), args...)
+			if res.Err != nil {
+				msg := fmt.Sprintf("Error running %s: %v\n%s", title, res.Err, res.Output)
+				if mode == "analyze" {
+					msg = fmt.Sprintf("CodeSleuth only supports COBOL files. No COBOL files found in the current directory.\nError: %v\nOutput: %s", res.Err, res.Output)
+				}
+				return CommandResultMsg{Success: false, Output: msg}
+			}
+			return CommandResultMsg{Success: true, Output: fmt.Sprintf("%s:\n%s", title, res.Output)}
+		}
+
+		output, cached, err := a.analyzeAll(mode, files)
+		if err != nil {
+			return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error running %s: %v", title, err)}
+		}
 		return CommandResultMsg{
-			Success: false,
-			Output:  fmt.Sprintf("Error finding references: %v\n%s", err, string(output)),
+			Success: true,
+			Output:  fmt.Sprintf("%s (%d files, %d from cache):\n%s", title, len(files), cached, output),
 		}
+	})
+}
+
+// timed runs run as a command and records how long it took.
+func timed(run func() CommandResultMsg) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		msg := run()
+		msg.Elapsed = time.Since(start)
+		return msg
 	}
+}
 
-	return CommandResultMsg{
-		Success: true,
-		Output:  fmt.Sprintf("References:\n%s", string(output)),
+// analyzer runs codesleuth for one command. Update builds it when the
+// command starts, so the command never reads plugin fields that Update
+// may be changing.
+type analyzer struct {
+	cache     *Cache
+	version   string
+	available bool // codesleuth is installed
+}
+
+func (p *Plugin) analyzer() analyzer {
+	return analyzer{cache: p.cache, version: p.version, available: p.available}
+}
+
+// analyzeAll runs mode over files in the working tree and returns the
+// combined output along with how many files were served from the cache.
+func (a analyzer) analyzeAll(mode string, files []string) (string, int, error) {
+	var sb strings.Builder
+	cached := 0
+	for _, file := range files {
+		output, hit, err := a.analyzeFile(".", file, mode)
+		if err != nil {
+			return "", cached, fmt.Errorf("%s: %v\n%s", file, err, output)
+		}
+		if hit {
+			cached++
+		}
+		sb.WriteString(fmt.Sprintf("== %s ==\n%s\n", file, output))
 	}
+	return sb.String(), cached, nil
 }
 
-func (p *Plugin) showCallGraph() tea.Msg {
-	codesleuthPath := os.Getenv("GOPATH") + "\\bin\\codesleuth.exe"
-	cmd := exec.Command(codesleuthPath, "analyze", ".", "--call-graph")
-	output, err := cmd.CombinedOutput()
+// analyzeFile returns codesleuth's output for file, relative to root, and
+// whether it was served from the cache. codesleuth runs in root, so its
+// output names the file the same way whichever point root holds.
+func (a analyzer) analyzeFile(root, file, mode string) (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(root, file))
 	if err != nil {
-		return CommandResultMsg{
-			Success: false,
-			Output:  fmt.Sprintf("Error generating call graph: %v\n%s", err, string(output)),
+		return "", false, err
+	}
+	key := cacheKey{File: filepath.ToSlash(file), Hash: contentHash(data), Mode: mode, ToolVersion: a.version}
+	if output, ok := a.cache.Get(key); ok {
+		return output, true, nil
+	}
+
+	args := append([]string{"analyze", file}, analysisFlags[mode]...)
+	res := runner.Run(root, codesleuthPath(), args...)
+	if res.Err != nil {
+		return res.Output, false, res.Err
+	}
+	if err := a.cache.Put(key, res.Output); err != nil {
+		// A cache write failure shouldn't hide a successful analysis.
+		return fmt.Sprintf("%s\n(failed to write cache: %v)", res.Output, err), false, nil
+	}
+	return res.Output, false, nil
+}
+
+func (p *Plugin) showCacheStats() tea.Cmd {
+	cache, version := p.cache, p.version
+	return func() tea.Msg {
+		stats, err := cache.Stats()
+		if err != nil {
+			return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error reading cache: %v", err)}
 		}
+		return CommandResultMsg{Success: true, Output: fmt.Sprintf("Cache %s (tool %s):\n%s", cache.Dir, version, stats)}
 	}
+}
 
-	return CommandResultMsg{
-		Success: true,
-		Output:  fmt.Sprintf("Call Graph:\n%s", string(output)),
+func (p *Plugin) clearCache() tea.Msg {
+	if err := p.cache.Clear(); err != nil {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error clearing cache: %v", err)}
 	}
+	return CommandResultMsg{Success: true, Output: "Analysis cache cleared"}
+}
+
+// truncatedHint ends a clipped result, naming the key bound to open it.
+func (p *Plugin) truncatedHint() string {
+	for _, a := range p.ctx.Keys.Bind(p.Name(), p.Actions()) {
+		if a.ID == "result" && a.Key != "" {
+			return fmt.Sprintf("... (truncated, %s to open)", types.KeyLabel(a.Key))
+		}
+	}
+	return "... (truncated)"
 }
 
 func (p *Plugin) View() string {
 	var sb strings.Builder
 
+	if p.source != nil {
+		sb.WriteString("CodeSleuth ─ ")
+		sb.WriteString(p.source.View())
+		sb.WriteString("↑/↓ scroll • n/N next/prev finding • " + types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())) + " • Esc back")
+		return sb.String()
+	}
+	if p.pager != nil {
+		sb.WriteString(p.pager.View())
+		sb.WriteString("\n↑/↓ PgUp/PgDn scroll • " + types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())) + " • Esc back")
+		return sb.String()
+	}
+	if p.picking {
+		return p.pickerView()
+	}
+
 	sb.WriteString("┌─ CodeSleuth ───────────────────────────────────────────────┐\n")
 	sb.WriteString("│                                                             │\n")
 
@@ -161,44 +666,54 @@ func (p *Plugin) View() string {
 		sb.WriteString("│     cd summarizer && cargo build --release              │\n")
 		sb.WriteString("│  3. Analyze files: codesleuth analyze <path>           │\n")
 		sb.WriteString("│                                                             │\n")
+		sb.WriteString("│  Go modules are analyzed by the built-in Go backend.    │\n")
 	} else {
 		sb.WriteString("│  ✅ CodeSleuth Available                                  │\n")
+	}
+	sb.WriteString("│                                                             │\n")
+
+	if p.exporting {
+		sb.WriteString(fmt.Sprintf("│  Export to: [%-43s] │\n", p.exportPath))
+		sb.WriteString("│  Enter: write • Ctrl+F: cycle .md/.html/.json • Esc      │\n")
 		sb.WriteString("│                                                             │\n")
+	}
 
-		// Show command results if any
-		if p.result != "" {
-			sb.WriteString("│  Result:                                                │\n")
-			sb.WriteString("│  ┌─────────────────────────────────────────────────────┐ │\n")
-			lines := strings.Split(p.result, "\n")
-			for i, line := range lines {
-				if i >= 3 { // Limit to 3 lines
-					sb.WriteString("│  │ ... (truncated)                                    │ │\n")
-					break
-				}
-				if len(line) > 55 {
-					line = line[:52] + "..."
-				}
-				sb.WriteString(fmt.Sprintf("│  │ %-55s │ │\n", line))
+	// Show command results if any
+	if p.result != "" {
+		sb.WriteString("│  Result:                                                │\n")
+		sb.WriteString("│  ┌─────────────────────────────────────────────────────┐ │\n")
+		lines := strings.Split(p.result, "\n")
+		for i, line := range lines {
+			if i >= 3 { // Limit to 3 lines
+				sb.WriteString(fmt.Sprintf("│  │ %-55s │ │\n", p.truncatedHint()))
+				break
 			}
-			sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
-			sb.WriteString("│                                                             │\n")
+			if len(line) > 55 {
+				line = line[:52] + "..."
+			}
+			sb.WriteString(fmt.Sprintf("│  │ %-55s │ │\n", line))
 		}
+		sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
+		sb.WriteString("│                                                             │\n")
+	}
 
-		sb.WriteString("│  Analysis Files:                                          │\n")
-		sb.WriteString("│  ┌─────────────────────────────────────────────────────┐ │\n")
+	sb.WriteString("│  Analysis Files:                                          │\n")
+	sb.WriteString("│  ┌─────────────────────────────────────────────────────┐ │\n")
 
-		sb.WriteString("│  │ No COBOL files analyzed                              │ │\n")
+	if len(p.files) == 0 {
+		sb.WriteString("│  │ No COBOL or Go files found                           │ │\n")
 		sb.WriteString("│  │ Press 'A' to analyze current directory            │ │\n")
-		sb.WriteString("│  │ (Note: CodeSleuth only supports COBOL files)      │ │\n")
+	} else {
+		p.writeFileList(&sb)
+	}
 
-		sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
-		sb.WriteString("│                                                             │\n")
-		sb.WriteString("│  Commands:                                                │\n")
-		sb.WriteString("│  • A: Analyze current directory (COBOL files only)      │\n")
-		sb.WriteString("│  • I: Show IR diagram                                    │\n")
-		sb.WriteString("│  • R: Find references                                    │\n")
-		sb.WriteString("│  • G: Show call graph                                    │\n")
+	sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  Commands:                                                │\n")
+	for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+		sb.WriteString(fmt.Sprintf("│  • %-52s │\n", line))
 	}
+	sb.WriteString("│  • ↑/↓: Select file  • Enter: Open source view          │\n")
 
 	sb.WriteString("│                                                             │\n")
 	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
@@ -206,6 +721,56 @@ func (p *Plugin) View() string {
 	return sb.String()
 }
 
+func (p *Plugin) pickerView() string {
+	var sb strings.Builder
+	sb.WriteString("┌─ CodeSleuth: Points in Time ───────────────────────────────┐\n")
+	sb.WriteString("│                                                             │\n")
+	for i, pt := range p.points {
+		prefix := "  "
+		if i == p.pointIndex {
+			prefix = "> "
+		}
+		if p.base != nil && *p.base == pt {
+			prefix += "[base] "
+		}
+		line := prefix + pt.String()
+		if len(line) > 55 {
+			line = line[:52] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("│  %-57s │\n", line))
+	}
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  • Enter: Analyze selected point                          │\n")
+	sb.WriteString("│  • B: Mark as comparison base                             │\n")
+	sb.WriteString("│  • D: Compare base (or selection) with selection/worktree │\n")
+	sb.WriteString("│  • Esc: Back                                              │\n")
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
+
+// writeFileList renders up to eight files around the selection.
+func (p *Plugin) writeFileList(sb *strings.Builder) {
+	const rows = 8
+	start := p.selectedIndex - rows/2
+	if start > len(p.files)-rows {
+		start = len(p.files) - rows
+	}
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(p.files) && i < start+rows; i++ {
+		prefix := "  "
+		if i == p.selectedIndex {
+			prefix = "> "
+		}
+		line := prefix + p.files[i]
+		if len(line) > 51 {
+			line = prefix + "..." + line[len(line)-46:]
+		}
+		sb.WriteString(fmt.Sprintf("│  │ %-51s │ │\n", line))
+	}
+}
+
 func (p *Plugin) Name() string {
 	return "codesleuth"
 }
@@ -213,9 +778,31 @@ func (p *Plugin) Name() string {
 type AvailabilityMsg struct {
 	Available bool
 	Error     string
+	Version   string
 }
 
+// slowCommand is how long a command must run before its success is
+// worth a notification rather than just the result pane.
+const slowCommand = 5 * time.Second
+
 type CommandResultMsg struct {
 	Success bool
 	Output  string
+	Elapsed time.Duration // how long the command ran, for analyses and exports
+}
+
+// FilesMsg carries the source files found in the working directory and
+// the symbols declared in them.
+type FilesMsg struct {
+	Files   []string
+	Symbols []Symbol
+}
+
+// SourceMsg carries a file's content and findings for the source view.
+type SourceMsg struct {
+	Path     string
+	Content  string
+	Findings []Finding
+	Line     int // line to show; 0 jumps to the first finding
+	Err      error
 }
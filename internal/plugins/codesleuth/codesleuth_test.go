@@ -0,0 +1,45 @@
+package codesleuth
+
+import (
+	"testing"
+	"time"
+
+	"forger/internal/types"
+)
+
+func TestCommandResultNotifies(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    CommandResultMsg
+		notify bool
+	}{
+		{"quick success", CommandResultMsg{Success: true, Output: "Analysis:\nok", Elapsed: time.Second}, false},
+		{"slow success", CommandResultMsg{Success: true, Output: "Analysis:\nok", Elapsed: slowCommand}, true},
+		{"error", CommandResultMsg{Success: false, Output: "Error running Analysis"}, true},
+	}
+	for _, tt := range tests {
+		p := &Plugin{}
+		_, cmd := p.Update(tt.msg)
+		if (cmd != nil) != tt.notify {
+			t.Errorf("%s: notified = %t, want %t", tt.name, cmd != nil, tt.notify)
+		}
+		if p.result == "" {
+			t.Errorf("%s: result not shown", tt.name)
+		}
+	}
+}
+
+func TestTruncatedHint(t *testing.T) {
+	p := &Plugin{ctx: &types.Context{}}
+	if got, want := p.truncatedHint(), "... (truncated, O to open)"; got != want {
+		t.Errorf("default hint = %q, want %q", got, want)
+	}
+	p.ctx.Keys = &types.Keymap{Plugins: map[string]map[string]string{p.Name(): {"result": "ctrl+o"}}}
+	if got, want := p.truncatedHint(), "... (truncated, Ctrl+O to open)"; got != want {
+		t.Errorf("rebound hint = %q, want %q", got, want)
+	}
+	p.ctx.Keys.Plugins[p.Name()]["result"] = ""
+	if got, want := p.truncatedHint(), "... (truncated)"; got != want {
+		t.Errorf("unbound hint = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package codesleuth
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Report is a complete analysis of the workspace, ready for export.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Root        string    `json:"root"`
+	Backend     string    `json:"backend"`
+	Summary     string    `json:"summary"`
+	Findings    []Finding `json:"findings"`
+	IR          string    `json:"ir,omitempty"`
+	Diagrams    []Diagram `json:"diagrams,omitempty"`
+	References  string    `json:"references,omitempty"`
+	CallGraph   string    `json:"call_graph,omitempty"`
+}
+
+// Diagram is the Mermaid source of one diagram: the import graph for Go,
+// or one per file for COBOL.
+type Diagram struct {
+	Title  string `json:"title"`
+	Source string `json:"source"`
+}
+
+// exportFormats maps file extensions to report writers.
+var exportFormats = map[string]func(io.Writer, *Report) error{
+	".md":   writeMarkdown,
+	".html": writeHTML,
+	".json": writeJSON,
+}
+
+// exportExtensions is the order the export prompt cycles through formats.
+var exportExtensions = []string{".md", ".html", ".json"}
+
+// buildReport analyzes the workspace with whichever backend applies.
+func (p *Plugin) buildReport(a analyzer) (*Report, error) {
+	r := &Report{GeneratedAt: time.Now()}
+	r.Root, _ = os.Getwd()
+
+	cobol := sourceFiles(".", isCOBOL)
+	switch {
+	case len(cobol) > 0:
+		r.Backend = "codesleuth"
+		findings, err := p.collectFindings(a, ".")
+		if err != nil {
+			return nil, err
+		}
+		r.Findings = findings
+		if !a.available {
+			r.Summary = "codesleuth is not available; findings come from the built-in COBOL scanner only."
+			return r, nil
+		}
+		var mermaid string
+		for mode, dst := range map[string]*string{
+			"analyze":    &r.Summary,
+			"mermaid":    &mermaid,
+			"references": &r.References,
+			"call-graph": &r.CallGraph,
+		} {
+			out, _, err := a.analyzeAll(mode, cobol)
+			if err != nil {
+				return nil, err
+			}
+			*dst = out
+		}
+		r.Diagrams = splitDiagrams(stripFences(mermaid))
+	case len(sourceFiles(".", isGo)) > 0:
+		mod, err := p.workspaceGoModule()
+		if err != nil {
+			return nil, err
+		}
+		r.Backend = "go"
+		r.Summary = mod.Summary()
+		r.Findings = mod.Findings()
+		r.IR = mod.FuncSummary()
+		r.Diagrams = []Diagram{{Title: "Import graph", Source: mod.ImportGraph()}}
+		r.References = mod.References()
+		r.CallGraph = FormatCallGraph(mod.CallGraph())
+	default:
+		return nil, fmt.Errorf("no COBOL or Go files to report on")
+	}
+	return r, nil
+}
+
+// splitDiagrams separates analyzeAll output, where each file's output
+// follows an "== file ==" header, into one diagram per file.
+func splitDiagrams(s string) []Diagram {
+	var diagrams []Diagram
+	var body []string
+	flush := func() {
+		if len(diagrams) > 0 {
+			diagrams[len(diagrams)-1].Source = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, "== ") && strings.HasSuffix(line, " ==") && len(line) > 6 {
+			flush()
+			diagrams = append(diagrams, Diagram{Title: line[3 : len(line)-3]})
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	kept := diagrams[:0]
+	for _, d := range diagrams {
+		if d.Source != "" {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// stripFences removes Markdown code fence lines from diagram output.
+func stripFences(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportReport writes a fresh report to path in the format implied by its
+// extension, creating parent directories as needed.
+func (p *Plugin) exportReport(a analyzer, path string) CommandResultMsg {
+	write, ok := exportFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Unknown export format %q (use .md, .html or .json)", filepath.Ext(path))}
+	}
+	report, err := p.buildReport(a)
+	if err != nil {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error building report: %v", err)}
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error creating %s: %v", dir, err)}
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error writing report: %v", err)}
+	}
+	defer f.Close()
+	if err := write(f, report); err != nil {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error writing report: %v", err)}
+	}
+	return CommandResultMsg{Success: true, Output: fmt.Sprintf("Report written to %s (%d findings)", path, len(report.Findings))}
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func writeMarkdown(w io.Writer, r *Report) error {
+	var sb strings.Builder
+	sb.WriteString("# CodeSleuth Report\n\n")
+	sb.WriteString(fmt.Sprintf("- Generated: %s\n- Root: `%s`\n- Backend: %s\n\n", r.GeneratedAt.Format(time.RFC1123), r.Root, r.Backend))
+
+	sb.WriteString("## Summary\n\n```text\n" + strings.TrimSpace(r.Summary) + "\n```\n\n")
+
+	sb.WriteString(fmt.Sprintf("## Findings (%d)\n\n", len(r.Findings)))
+	if len(r.Findings) == 0 {
+		sb.WriteString("No findings.\n\n")
+	} else {
+		sb.WriteString("| File | Line | Kind | Message |\n|------|-----:|------|---------|\n")
+		for _, f := range r.Findings {
+			sb.WriteString(fmt.Sprintf("| `%s` | %d | %s | %s |\n", f.File, f.Line, f.Kind, strings.ReplaceAll(f.Message, "|", "\\|")))
+		}
+		sb.WriteString("\n")
+	}
+
+	if strings.TrimSpace(r.IR) != "" {
+		sb.WriteString(fmt.Sprintf("## IR Summary\n\n```text\n%s\n```\n\n", strings.TrimSpace(r.IR)))
+	}
+	if len(r.Diagrams) > 0 {
+		sb.WriteString("## Diagrams\n\n")
+		for _, d := range r.Diagrams {
+			sb.WriteString(fmt.Sprintf("### %s\n\n```mermaid\n%s\n```\n\n", d.Title, d.Source))
+		}
+	}
+	sections := []struct{ title, body string }{
+		{"References", r.References},
+		{"Call Graph", r.CallGraph},
+	}
+	for _, s := range sections {
+		if strings.TrimSpace(s.body) == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n```text\n%s\n```\n\n", s.title, strings.TrimSpace(s.body)))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// htmlReport is one self-contained file with inline styles and no
+// scripts, so it reads the same offline. Diagrams are shown as Mermaid
+// source, one block per diagram.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>CodeSleuth Report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 72rem; color: #222; }
+pre { background: #f5f5f5; padding: 1rem; overflow-x: auto; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: .3rem .6rem; text-align: left; }
+td.line { text-align: right; }
+.dead-code { color: #666; } .unused-data { color: #a60; } .goto { color: #c00; }
+</style>
+</head>
+<body>
+<h1>CodeSleuth Report</h1>
+<p>Generated {{.GeneratedAt.Format "Mon, 02 Jan 2006 15:04:05 MST"}} for <code>{{.Root}}</code> ({{.Backend}} backend)</p>
+<h2>Summary</h2>
+<pre>{{.Summary}}</pre>
+<h2>Findings ({{len .Findings}})</h2>
+{{if .Findings}}<table>
+<tr><th>File</th><th>Line</th><th>Kind</th><th>Message</th></tr>
+{{range .Findings}}<tr class="{{.Kind}}"><td><code>{{.File}}</code></td><td class="line">{{.Line}}</td><td>{{.Kind}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{else}}<p>No findings.</p>{{end}}
+{{if .IR}}<h2>IR Summary</h2>
+<pre>{{.IR}}</pre>{{end}}
+{{if .Diagrams}}<h2>Diagrams</h2>
+<p>Mermaid source; paste a block into any Mermaid renderer to draw it.</p>
+{{range .Diagrams}}<h3>{{.Title}}</h3>
+<pre class="mermaid">{{.Source}}</pre>
+{{end}}{{end}}
+{{if .References}}<h2>References</h2>
+<pre>{{.References}}</pre>{{end}}
+{{if .CallGraph}}<h2>Call Graph</h2>
+<pre>{{.CallGraph}}</pre>{{end}}
+</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, r *Report) error {
+	return htmlReport.Execute(w, r)
+}
@@ -0,0 +1,132 @@
+package codesleuth
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleReport() *Report {
+	return &Report{
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Root:        "/work",
+		Backend:     "codesleuth",
+		Summary:     "2 files",
+		Findings: []Finding{
+			{File: "pay.cbl", Line: 12, Kind: "goto", Message: "GO TO <EXIT> | jumps"},
+		},
+		IR:        "PAY-PARA loc=3",
+		Diagrams:  []Diagram{{Title: "pay.cbl", Source: "graph TD\n  A --> B"}},
+		CallGraph: "MAIN\n  → PAY-PARA",
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeHTML(&buf, sampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, absent := range []string{"<script", "http://", "https://", "<EXIT>"} {
+		if strings.Contains(out, absent) {
+			t.Errorf("HTML report contains %q", absent)
+		}
+	}
+	for _, want := range []string{
+		"<h2>Findings (1)</h2>",
+		"GO TO &lt;EXIT&gt; | jumps",
+		"<h3>pay.cbl</h3>\n<pre class=\"mermaid\">graph TD\n  A --&gt; B</pre>",
+		"<h2>Call Graph</h2>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report lacks %q", want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, sampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"| `pay.cbl` | 12 | goto | GO TO <EXIT> \\| jumps |",
+		"## IR Summary\n\n```text\nPAY-PARA loc=3\n```",
+		"### pay.cbl\n\n```mermaid\ngraph TD\n  A --> B\n```",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown report lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "## References") {
+		t.Error("empty References section was written")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	want := sampleReport()
+	if err := writeJSON(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, *want)
+	}
+}
+
+// TestExportGoReport exports a Go module, which needs no codesleuth, and
+// checks that the IR section holds the function summary without the
+// diagram, which has a section of its own.
+func TestExportGoReport(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n\nfunc unused() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	p := &Plugin{cache: &Cache{Dir: t.TempDir()}}
+	msg := p.exportReport(p.analyzer(), filepath.Join("out", "report.json"))
+	if !msg.Success {
+		t.Fatal(msg.Output)
+	}
+	data, err := os.ReadFile(filepath.Join("out", "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Backend != "go" || !strings.Contains(r.IR, "main.unused") || strings.Contains(r.IR, "mermaid") {
+		t.Errorf("backend %q, IR %q", r.Backend, r.IR)
+	}
+	if len(r.Diagrams) != 1 || !strings.HasPrefix(r.Diagrams[0].Source, "graph TD") {
+		t.Errorf("diagrams = %+v", r.Diagrams)
+	}
+	if len(r.Findings) != 1 || r.Findings[0].Kind != "dead-code" {
+		t.Errorf("findings = %+v", r.Findings)
+	}
+
+	if msg := p.exportReport(p.analyzer(), "report.pdf"); msg.Success {
+		t.Error("exported to an unknown format")
+	}
+}
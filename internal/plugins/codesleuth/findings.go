@@ -0,0 +1,243 @@
+package codesleuth
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"forger/internal/ui"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FindingKind classifies an anomaly reported for a source line.
+type FindingKind string
+
+const (
+	KindDeadCode   FindingKind = "dead-code"
+	KindUnusedData FindingKind = "unused-data"
+	KindGoto       FindingKind = "goto"
+	KindOther      FindingKind = "note"
+)
+
+// Finding is a single anomaly attached to a file and line.
+type Finding struct {
+	File    string      `json:"file"`
+	Line    int         `json:"line"`
+	Kind    FindingKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+// marker converts a Finding into a gutter marker for the source view.
+func (f Finding) marker() ui.Marker {
+	m := ui.Marker{Line: f.Line, Note: fmt.Sprintf("[%s] %s", f.Kind, f.Message)}
+	switch f.Kind {
+	case KindDeadCode:
+		m.Symbol, m.Color = "D", lipgloss.Color("8")
+	case KindUnusedData:
+		m.Symbol, m.Color = "U", lipgloss.Color("11")
+	case KindGoto:
+		m.Symbol, m.Color = "G", lipgloss.Color("9")
+	default:
+		m.Symbol, m.Color = "●", lipgloss.Color("14")
+	}
+	return m
+}
+
+func markers(findings []Finding) []ui.Marker {
+	out := make([]ui.Marker, 0, len(findings))
+	for _, f := range findings {
+		out = append(out, f.marker())
+	}
+	return out
+}
+
+// codesleuthLine matches "path:line: message" lines in codesleuth output.
+var codesleuthLine = regexp.MustCompile(`^\s*(\S+?):(\d+):\s*(.+)$`)
+
+// parseFindings extracts findings for file from codesleuth's text output.
+// Lines that don't name file are ignored.
+func parseFindings(file, output string) []Finding {
+	var findings []Finding
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		m := codesleuthLine.FindStringSubmatch(scanner.Text())
+		if m == nil || !samePath(file, m[1]) {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		findings = append(findings, Finding{File: file, Line: line, Kind: classify(m[3]), Message: m[3]})
+	}
+	return findings
+}
+
+// samePath reports whether a and b name the same file, allowing either to
+// be a relative suffix of the other.
+func samePath(a, b string) bool {
+	a = strings.ReplaceAll(a, "\\", "/")
+	b = strings.ReplaceAll(b, "\\", "/")
+	a, b = strings.TrimPrefix(a, "./"), strings.TrimPrefix(b, "./")
+	return a == b || strings.HasSuffix(a, "/"+b) || strings.HasSuffix(b, "/"+a)
+}
+
+// mergeFindings appends extra to base, skipping entries already reported
+// for the same line and kind.
+func mergeFindings(base, extra []Finding) []Finding {
+	seen := make(map[string]bool)
+	for _, f := range base {
+		seen[fmt.Sprintf("%d/%s", f.Line, f.Kind)] = true
+	}
+	for _, f := range extra {
+		key := fmt.Sprintf("%d/%s", f.Line, f.Kind)
+		if !seen[key] {
+			seen[key] = true
+			base = append(base, f)
+		}
+	}
+	sort.SliceStable(base, func(i, j int) bool { return base[i].Line < base[j].Line })
+	return base
+}
+
+func classify(message string) FindingKind {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "dead") || strings.Contains(lower, "unreachable"):
+		return KindDeadCode
+	case strings.Contains(lower, "unused"):
+		return KindUnusedData
+	case strings.Contains(lower, "goto") || strings.Contains(lower, "go to"):
+		return KindGoto
+	}
+	return KindOther
+}
+
+var (
+	cobolDataItem  = regexp.MustCompile(`^\s*(\d{1,2})\s+([A-Z0-9][A-Z0-9-]*)\b(.*)$`)
+	cobolParagraph = regexp.MustCompile(`^\s*([A-Z0-9][A-Z0-9-]*)(\s+SECTION)?\s*\.\s*$`)
+	cobolGoto      = regexp.MustCompile(`\bGO\s+TO\b`)
+	cobolPerform   = regexp.MustCompile(`\b(?:PERFORM|GO\s+TO|THRU|THROUGH)\s+([A-Z0-9][A-Z0-9-]*)`)
+	cobolTerminal  = regexp.MustCompile(`\b(STOP\s+RUN|GOBACK|EXIT\s+PROGRAM|GO\s+TO)\b`)
+	cobolWord      = regexp.MustCompile(`[A-Z0-9][A-Z0-9-]*`)
+)
+
+// cobolStatements are single-word sentences that look like paragraph headers.
+var cobolStatements = map[string]bool{"EXIT": true, "GOBACK": true, "CONTINUE": true}
+
+// scanCOBOL finds GOTO usage, unreferenced elementary data items and
+// paragraphs that are neither referenced nor reachable by fall-through.
+func scanCOBOL(file, src string) []Finding {
+	type item struct {
+		name string
+		line int
+	}
+	var (
+		findings   []Finding
+		dataItems  []item
+		paragraphs []item
+		dead       = map[string]bool{}
+		uses       = map[string]int{}
+		inData     bool
+		inProc     bool
+		terminated bool
+	)
+
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		lineNo := i + 1
+		if isCOBOLComment(raw) {
+			continue
+		}
+		code := strings.ToUpper(cobolCode(raw))
+		trimmed := strings.TrimSpace(code)
+		if trimmed == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(trimmed, "DATA DIVISION"):
+			inData, inProc = true, false
+			continue
+		case strings.Contains(trimmed, "PROCEDURE DIVISION"):
+			inData, inProc = false, true
+			terminated = false
+			continue
+		}
+
+		if inData {
+			if m := cobolDataItem.FindStringSubmatch(code); m != nil && m[2] != "FILLER" {
+				elementary := m[1] == "77" || m[1] == "88" || strings.Contains(m[3], "PIC")
+				if elementary {
+					dataItems = append(dataItems, item{m[2], lineNo})
+				}
+			}
+			continue
+		}
+		if !inProc {
+			continue
+		}
+
+		// Paragraph and section headers start in Area A (first four columns).
+		inAreaA := len(code)-len(strings.TrimLeft(code, " ")) < 4
+		if m := cobolParagraph.FindStringSubmatch(code); m != nil && inAreaA && !cobolStatements[m[1]] {
+			if len(paragraphs) > 0 && terminated {
+				dead[m[1]] = true
+			}
+			paragraphs = append(paragraphs, item{m[1], lineNo})
+			terminated = false
+			continue
+		}
+
+		if cobolGoto.MatchString(code) {
+			findings = append(findings, Finding{File: file, Line: lineNo, Kind: KindGoto, Message: "GO TO statement"})
+		}
+		for _, m := range cobolPerform.FindAllStringSubmatch(code, -1) {
+			uses["@"+m[1]]++
+		}
+		for _, w := range cobolWord.FindAllString(code, -1) {
+			uses[w]++
+		}
+		terminated = cobolTerminal.MatchString(code)
+	}
+
+	for _, d := range dataItems {
+		if uses[d.name] == 0 {
+			findings = append(findings, Finding{File: file, Line: d.line, Kind: KindUnusedData, Message: fmt.Sprintf("data item %s is never referenced", d.name)})
+		}
+	}
+	for _, p := range paragraphs {
+		if dead[p.name] && uses["@"+p.name] == 0 {
+			findings = append(findings, Finding{File: file, Line: p.line, Kind: KindDeadCode, Message: fmt.Sprintf("paragraph %s is unreachable", p.name)})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// isCOBOLComment reports whether raw is a fixed-format comment line.
+func isCOBOLComment(raw string) bool {
+	return len(raw) >= 7 && (raw[6] == '*' || raw[6] == '/')
+}
+
+// cobolCode strips inline comments and, for fixed-format lines, the
+// sequence and indicator areas.
+func cobolCode(raw string) string {
+	if i := strings.Index(raw, "*>"); i >= 0 {
+		raw = raw[:i]
+	}
+	if len(raw) >= 7 && isSequenceArea(raw[:6]) {
+		raw = raw[7:]
+	}
+	return raw
+}
+
+func isSequenceArea(s string) bool {
+	for _, r := range s {
+		if r != ' ' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,523 @@
+package codesleuth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// goPackage is one type-checked package of the workspace module.
+type goPackage struct {
+	Path  string
+	Dir   string
+	Files []*ast.File
+	Types *gotypes.Package
+	Info  *gotypes.Info
+}
+
+// goModule is the result of parsing and type-checking every package under
+// a module root with go/parser and go/types, no external tools required.
+type goModule struct {
+	Root   string
+	Path   string
+	Fset   *token.FileSet
+	Pkgs   []*goPackage // sorted by import path
+	Errors []string
+
+	byPath map[string]*goPackage
+	dirs   map[string]string // import path -> directory
+}
+
+// deps type-checks packages from outside the module, such as the standard
+// library, from source. Checking them is most of the cost of a load, and
+// they don't change while Forger runs, so one importer and the packages it
+// has checked are shared by every load. The importer looks each path up
+// with go/build, which runs go list for modules, before its own cache, so
+// checked packages are also kept here by import path. The importer isn't
+// safe for concurrent use.
+var deps = struct {
+	sync.Mutex
+	importer gotypes.ImporterFrom
+	pkgs     map[string]*gotypes.Package
+}{
+	importer: importer.ForCompiler(token.NewFileSet(), "source", nil).(gotypes.ImporterFrom),
+	pkgs:     make(map[string]*gotypes.Package),
+}
+
+func isGo(path string) bool {
+	return strings.HasSuffix(path, ".go")
+}
+
+// loadGoModule type-checks the module rooted at root. Type errors are
+// collected rather than fatal so partial results are still useful.
+func loadGoModule(root string) (*goModule, error) {
+	modPath, err := readModulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	m := &goModule{
+		Root:   root,
+		Path:   modPath,
+		Fset:   token.NewFileSet(),
+		byPath: make(map[string]*goPackage),
+		dirs:   make(map[string]string),
+	}
+	for _, file := range sourceFiles(root, isGo) {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		rel, _ := filepath.Rel(root, filepath.Dir(file))
+		importPath := modPath
+		if rel != "." {
+			importPath = modPath + "/" + filepath.ToSlash(rel)
+		}
+		m.dirs[importPath] = filepath.Dir(file)
+	}
+
+	paths := make([]string, 0, len(m.dirs))
+	for path := range m.dirs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		// A directory whose files are all excluded by build constraints,
+		// such as one holding only a "//go:build ignore" tool, has no
+		// package to check.
+		var noGo *build.NoGoError
+		if _, err := m.ImportFrom(path, root, 0); err != nil && !errors.As(err, &noGo) {
+			m.Errors = append(m.Errors, err.Error())
+		}
+	}
+	for _, path := range paths {
+		if pkg := m.byPath[path]; pkg != nil {
+			m.Pkgs = append(m.Pkgs, pkg)
+		}
+	}
+	return m, nil
+}
+
+func readModulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", fmt.Errorf("no Go module found: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no module directive", gomod)
+}
+
+func (m *goModule) Import(path string) (*gotypes.Package, error) {
+	return m.ImportFrom(path, m.Root, 0)
+}
+
+// ImportFrom checks module packages from source on demand and delegates
+// everything else to the shared importer for dependencies.
+func (m *goModule) ImportFrom(path, dir string, mode gotypes.ImportMode) (*gotypes.Package, error) {
+	if pkg, ok := m.byPath[path]; ok {
+		return pkg.Types, nil
+	}
+	pkgDir, ok := m.dirs[path]
+	if !ok {
+		deps.Lock()
+		defer deps.Unlock()
+		if pkg, ok := deps.pkgs[path]; ok {
+			return pkg, nil
+		}
+		pkg, err := deps.importer.ImportFrom(path, dir, mode)
+		if err == nil {
+			deps.pkgs[path] = pkg
+		}
+		return pkg, err
+	}
+
+	// go/build picks the files the go command would build for this
+	// platform, honoring build constraints and _GOOS/_GOARCH suffixes.
+	bp, err := build.Default.ImportDir(pkgDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		f, err := parser.ParseFile(m.Fset, filepath.Join(pkgDir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return m.Fset.Position(files[i].Pos()).Filename < m.Fset.Position(files[j].Pos()).Filename
+	})
+
+	info := &gotypes.Info{
+		Defs: make(map[*ast.Ident]gotypes.Object),
+		Uses: make(map[*ast.Ident]gotypes.Object),
+	}
+	conf := gotypes.Config{
+		Importer:    m,
+		FakeImportC: true,
+		Error:       func(err error) { m.Errors = append(m.Errors, err.Error()) },
+	}
+	tpkg, _ := conf.Check(path, m.Fset, files, info)
+	m.byPath[path] = &goPackage{Path: path, Dir: pkgDir, Files: files, Types: tpkg, Info: info}
+	return tpkg, nil
+}
+
+func (m *goModule) position(pos token.Pos) token.Position {
+	p := m.Fset.Position(pos)
+	if rel, err := filepath.Rel(m.Root, p.Filename); err == nil {
+		p.Filename = filepath.ToSlash(rel)
+	}
+	return p
+}
+
+// local reports whether obj belongs to one of the module's packages.
+func (m *goModule) local(obj gotypes.Object) bool {
+	return obj != nil && obj.Pkg() != nil && m.byPath[obj.Pkg().Path()] != nil
+}
+
+// funcName returns a short display name such as "core.NewModel" or
+// "(*codesleuth.Plugin).View".
+func funcName(fn *gotypes.Func) string {
+	sig, _ := fn.Type().(*gotypes.Signature)
+	pkg := ""
+	if fn.Pkg() != nil {
+		pkg = fn.Pkg().Name()
+	}
+	if sig != nil && sig.Recv() != nil {
+		recv := gotypes.TypeString(sig.Recv().Type(), func(p *gotypes.Package) string { return p.Name() })
+		return fmt.Sprintf("(%s).%s", recv, fn.Name())
+	}
+	return pkg + "." + fn.Name()
+}
+
+// Summary lists packages with their files and declaration counts.
+func (m *goModule) Summary() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Module %s: %d packages\n", m.Path, len(m.Pkgs)))
+	for _, pkg := range m.Pkgs {
+		funcs, typesCount, vars := 0, 0, 0
+		if pkg.Types != nil {
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				switch obj := scope.Lookup(name).(type) {
+				case *gotypes.Func:
+					funcs++
+				case *gotypes.TypeName:
+					typesCount++
+					if named, ok := obj.Type().(*gotypes.Named); ok {
+						funcs += named.NumMethods()
+					}
+				case *gotypes.Var, *gotypes.Const:
+					vars++
+				}
+			}
+		}
+		sb.WriteString(fmt.Sprintf("\n%s (%d funcs, %d types, %d vars/consts)\n", pkg.Path, funcs, typesCount, vars))
+		for _, f := range pkg.Files {
+			sb.WriteString("  " + m.position(f.Pos()).Filename + "\n")
+		}
+	}
+	if len(m.Errors) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d type-check errors (first: %s)\n", len(m.Errors), m.Errors[0]))
+	}
+	return sb.String()
+}
+
+// References lists every use site of each package-level declaration.
+func (m *goModule) References() string {
+	refs := make(map[gotypes.Object][]token.Position)
+	for _, pkg := range m.Pkgs {
+		for id, obj := range pkg.Info.Uses {
+			if m.local(obj) && (obj.Parent() == obj.Pkg().Scope() || isMethod(obj)) {
+				refs[obj] = append(refs[obj], m.position(id.Pos()))
+			}
+		}
+	}
+
+	objs := make([]gotypes.Object, 0, len(refs))
+	for obj := range refs {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool { return objectName(objs[i]) < objectName(objs[j]) })
+
+	var sb strings.Builder
+	for _, obj := range objs {
+		positions := refs[obj]
+		sort.Slice(positions, func(i, j int) bool { return positions[i].String() < positions[j].String() })
+		sb.WriteString(fmt.Sprintf("%s (%d refs)\n", objectName(obj), len(positions)))
+		for _, pos := range positions {
+			sb.WriteString(fmt.Sprintf("  %s:%d\n", pos.Filename, pos.Line))
+		}
+	}
+	return sb.String()
+}
+
+func isMethod(obj gotypes.Object) bool {
+	fn, ok := obj.(*gotypes.Func)
+	if !ok {
+		return false
+	}
+	sig, _ := fn.Type().(*gotypes.Signature)
+	return sig != nil && sig.Recv() != nil
+}
+
+func objectName(obj gotypes.Object) string {
+	if fn, ok := obj.(*gotypes.Func); ok {
+		return funcName(fn)
+	}
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// callEdge is a static call from one module function to another.
+type callEdge struct {
+	Caller, Callee string
+}
+
+// CallGraph returns the static calls between module functions, resolved
+// through type information. Calls through interfaces and function values
+// are not followed.
+func (m *goModule) CallGraph() []callEdge {
+	seen := make(map[callEdge]bool)
+	var edges []callEdge
+	for _, pkg := range m.Pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				caller, ok := pkg.Info.Defs[fd.Name].(*gotypes.Func)
+				if !ok {
+					continue
+				}
+				ast.Inspect(fd.Body, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					var id *ast.Ident
+					switch fun := call.Fun.(type) {
+					case *ast.Ident:
+						id = fun
+					case *ast.SelectorExpr:
+						id = fun.Sel
+					}
+					if id == nil {
+						return true
+					}
+					if callee, ok := pkg.Info.Uses[id].(*gotypes.Func); ok && m.local(callee) {
+						e := callEdge{funcName(caller), funcName(callee)}
+						if !seen[e] {
+							seen[e] = true
+							edges = append(edges, e)
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Caller != edges[j].Caller {
+			return edges[i].Caller < edges[j].Caller
+		}
+		return edges[i].Callee < edges[j].Callee
+	})
+	return edges
+}
+
+// FormatCallGraph renders edges grouped by caller.
+func FormatCallGraph(edges []callEdge) string {
+	var sb strings.Builder
+	last := ""
+	for _, e := range edges {
+		if e.Caller != last {
+			sb.WriteString(e.Caller + "\n")
+			last = e.Caller
+		}
+		sb.WriteString("  → " + e.Callee + "\n")
+	}
+	return sb.String()
+}
+
+// IR renders FuncSummary followed by the Mermaid import graph.
+func (m *goModule) IR() string {
+	return m.FuncSummary() + "\n```mermaid\n" + m.ImportGraph() + "```\n"
+}
+
+// FuncSummary renders an IR-style summary of every function: signature,
+// size, branch count and callees.
+func (m *goModule) FuncSummary() string {
+	callees := make(map[string][]string)
+	for _, e := range m.CallGraph() {
+		callees[e.Caller] = append(callees[e.Caller], e.Callee)
+	}
+
+	var sb strings.Builder
+	for _, pkg := range m.Pkgs {
+		sb.WriteString("package " + pkg.Path + "\n")
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				fn, ok := pkg.Info.Defs[fd.Name].(*gotypes.Func)
+				if !ok {
+					continue
+				}
+				start, end := m.position(fd.Pos()), m.position(fd.End())
+				sb.WriteString(fmt.Sprintf("  %s%s\n", funcName(fn), strings.TrimPrefix(fn.Type().String(), "func")))
+				sb.WriteString(fmt.Sprintf("    loc=%d branches=%d calls=%d  %s:%d\n",
+					end.Line-start.Line+1, countBranches(fd), len(callees[funcName(fn)]), start.Filename, start.Line))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ImportGraph renders the imports between module packages as a Mermaid graph.
+func (m *goModule) ImportGraph() string {
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+	for _, pkg := range m.Pkgs {
+		seen := make(map[string]bool)
+		for _, file := range pkg.Files {
+			for _, imp := range file.Imports {
+				path := strings.Trim(imp.Path.Value, `"`)
+				if m.byPath[path] != nil && !seen[path] {
+					seen[path] = true
+					sb.WriteString(fmt.Sprintf("  %s --> %s\n", mermaidID(pkg.Path), mermaidID(path)))
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+func mermaidID(path string) string {
+	return strings.NewReplacer("/", "_", ".", "_", "-", "_").Replace(path) + "[" + path + "]"
+}
+
+func countBranches(fd *ast.FuncDecl) int {
+	n := 0
+	ast.Inspect(fd, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.CaseClause, *ast.CommClause:
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Findings reports unused unexported functions (dead code), unused
+// unexported package-level variables and constants, and goto statements.
+func (m *goModule) Findings() []Finding {
+	used := make(map[gotypes.Object]bool)
+	for _, pkg := range m.Pkgs {
+		for _, obj := range pkg.Info.Uses {
+			used[obj] = true
+		}
+	}
+
+	var findings []Finding
+	add := func(pos token.Pos, kind FindingKind, msg string) {
+		p := m.position(pos)
+		findings = append(findings, Finding{File: p.Filename, Line: p.Line, Kind: kind, Message: msg})
+	}
+	for _, pkg := range m.Pkgs {
+		for id, obj := range pkg.Info.Defs {
+			if obj == nil || obj.Exported() || used[obj] || obj.Parent() != pkg.Types.Scope() {
+				continue
+			}
+			switch obj.(type) {
+			case *gotypes.Func:
+				if obj.Name() != "main" && obj.Name() != "init" {
+					add(id.Pos(), KindDeadCode, fmt.Sprintf("function %s is never called", obj.Name()))
+				}
+			case *gotypes.Var, *gotypes.Const:
+				if obj.Name() != "_" {
+					add(id.Pos(), KindUnusedData, fmt.Sprintf("%s is never referenced", obj.Name()))
+				}
+			}
+		}
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				if br, ok := n.(*ast.BranchStmt); ok && br.Tok == token.GOTO {
+					add(br.Pos(), KindGoto, "goto "+br.Label.Name)
+				}
+				return true
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// workspaceGoModule returns the type-checked workspace module, reusing the
+// previous result while no Go file has changed.
+func (p *Plugin) workspaceGoModule() (*goModule, error) {
+	var fp strings.Builder
+	for _, file := range sourceFiles(".", isGo) {
+		if info, err := os.Stat(file); err == nil {
+			fp.WriteString(fmt.Sprintf("%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+	if info, err := os.Stat("go.mod"); err == nil {
+		fp.WriteString(fmt.Sprintf("go.mod:%d\n", info.ModTime().UnixNano()))
+	}
+
+	p.goMu.Lock()
+	defer p.goMu.Unlock()
+	if p.goMod != nil && p.goModKey == fp.String() {
+		return p.goMod, nil
+	}
+	mod, err := loadGoModule(".")
+	if err != nil {
+		return nil, err
+	}
+	p.goMod, p.goModKey = mod, fp.String()
+	return mod, nil
+}
+
+// runGoAnalysis serves the analysis modes from the built-in Go backend.
+func (p *Plugin) runGoAnalysis(mode, title string) CommandResultMsg {
+	mod, err := p.workspaceGoModule()
+	if err != nil {
+		return CommandResultMsg{Success: false, Output: fmt.Sprintf("Error running %s: %v", title, err)}
+	}
+	var out string
+	switch mode {
+	case "analyze":
+		out = mod.Summary() + "\nFindings:\n" + formatFindings(mod.Findings())
+	case "mermaid":
+		out = mod.IR()
+	case "references":
+		out = mod.References()
+	case "call-graph":
+		out = FormatCallGraph(mod.CallGraph())
+	}
+	return CommandResultMsg{Success: true, Output: fmt.Sprintf("%s (Go, built-in):\n%s", title, out)}
+}
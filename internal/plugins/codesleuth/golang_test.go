@@ -0,0 +1,73 @@
+package codesleuth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestLoadGoModuleBuildConstraints loads a module whose directories hold
+// files for other platforms, a "//go:build ignore" tool and a file behind
+// a build tag. Only the files the go command would build are checked, so
+// none of them cause redeclaration errors.
+func TestLoadGoModuleBuildConstraints(t *testing.T) {
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "linux"
+	}
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                           "module example.com/m\n\ngo 1.21\n",
+		"lib/lib.go":                       "package lib\n\nfunc Name() string { return platform }\n",
+		"lib/name_" + runtime.GOOS + ".go": "package lib\n\nconst platform = \"here\"\n",
+		"lib/name_" + other + ".go":        "package lib\n\nconst platform = \"there\"\n",
+		"lib/gen.go":                       "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"lib/extra.go":                     "//go:build forger_never\n\npackage lib\n\nfunc Name() string { return \"\" }\n",
+		"tools/tool.go":                    "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"app/app.go":                       "package app\n\nimport \"example.com/m/lib\"\n\nvar N = lib.Name()\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := loadGoModule(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Errors) > 0 {
+		t.Errorf("errors: %q", m.Errors)
+	}
+	var paths []string
+	for _, pkg := range m.Pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	if want := []string{"example.com/m/app", "example.com/m/lib"}; !equalStrings(paths, want) {
+		t.Fatalf("packages = %q, want %q", paths, want)
+	}
+	var names []string
+	for _, f := range m.Pkgs[1].Files {
+		names = append(names, filepath.Base(m.Fset.Position(f.Pos()).Filename))
+	}
+	if want := []string{"lib.go", "name_" + runtime.GOOS + ".go"}; !equalStrings(names, want) {
+		t.Errorf("lib files = %q, want %q", names, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+package codesleuth
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"forger/internal/runner"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PointKind says where the files for a Point come from.
+type PointKind string
+
+const (
+	PointWorktree PointKind = "worktree"
+	PointCommit   PointKind = "commit"
+	PointSnapshot PointKind = "snapshot"
+)
+
+// Point is a moment in the repository's history that can be analyzed.
+type Point struct {
+	Kind  PointKind
+	Ref   string // commit hash or ignoregrets snapshot id
+	Label string
+}
+
+func (pt Point) String() string {
+	if pt.Label != "" {
+		return pt.Label
+	}
+	if pt.Kind == PointWorktree {
+		return "working tree"
+	}
+	return fmt.Sprintf("%s %s", pt.Kind, shortRef(pt.Ref))
+}
+
+func shortRef(ref string) string {
+	if len(ref) > 8 {
+		return ref[:8]
+	}
+	return ref
+}
+
+// listPoints lists what can be analyzed: the working tree, the snapshot
+// selected in ignoregrets (if any) and recent commits.
+func listPoints(snapshot string) tea.Cmd {
+	return func() tea.Msg {
+		pts := []Point{{Kind: PointWorktree}}
+		if snapshot != "" {
+			pts = append(pts, Point{Kind: PointSnapshot, Ref: snapshot, Label: "ignoregrets snapshot " + shortRef(snapshot)})
+		}
+
+		if res := runner.Run("", "git", "log", "-n", "15", "--format=%H%x09%h %s"); res.Err == nil {
+			for _, line := range strings.Split(strings.TrimSpace(res.Output), "\n") {
+				if hash, label, ok := strings.Cut(line, "\t"); ok {
+					pts = append(pts, Point{Kind: PointCommit, Ref: hash, Label: label})
+				}
+			}
+		}
+		return PointsMsg{Points: pts}
+	}
+}
+
+// materialize makes the files for pt available in a directory. The returned
+// cleanup removes any temporary directory that was created.
+func (pt Point) materialize() (string, func(), error) {
+	if pt.Kind == PointWorktree {
+		return ".", func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "forger-codesleuth-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	// An ignoregrets snapshot only holds ignored files, so lay it over the
+	// tree of the commit it was taken at.
+	if err := gitArchive(pt.Ref, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if pt.Kind == PointSnapshot {
+		archive, err := findSnapshotArchive(pt.Ref)
+		if err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		if err := extractArchive(archive, dir); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	return dir, cleanup, nil
+}
+
+// gitArchive extracts the tree of rev into dir.
+func gitArchive(rev, dir string) error {
+	cmd := exec.Command("git", "archive", "--format=tar", rev)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git archive: %v", err)
+	}
+	extractErr := extractTar(stdout, dir)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive %s: %v %s", shortRef(rev), err, strings.TrimSpace(stderr.String()))
+	}
+	return extractErr
+}
+
+// findSnapshotArchive locates the ignoregrets archive whose name contains id.
+func findSnapshotArchive(id string) (string, error) {
+	matches, _ := filepath.Glob(filepath.Join(".ignoregrets", "snapshots", "*"+shortRef(id)+"*.tar.gz"))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no ignoregrets archive found for snapshot %s", shortRef(id))
+	}
+	return matches[len(matches)-1], nil
+}
+
+func extractArchive(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	defer gz.Close()
+	return extractTar(gz, dir)
+}
+
+// extractTar writes regular files from r under dir, refusing entries that
+// would escape it.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %q escapes target directory", hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// collectFindings analyzes every COBOL file under root, plus the Go module
+// at root if there is one. Finding paths are
+// relative to root so results from different points can be compared.
+func (p *Plugin) collectFindings(a analyzer, root string) ([]Finding, error) {
+	var all []Finding
+	for _, path := range sourceFiles(root, isCOBOL) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(root, path)
+		findings := scanCOBOL(filepath.ToSlash(rel), string(data))
+		if a.available {
+			output, _, _ := a.analyzeFile(root, rel, "analyze")
+			findings = mergeFindings(findings, parseFindings(filepath.ToSlash(rel), output))
+		}
+		all = append(all, findings...)
+	}
+	if len(sourceFiles(root, isGo)) > 0 {
+		if mod, err := loadGoModule(root); err == nil {
+			all = append(all, mod.Findings()...)
+		}
+	}
+	return all, nil
+}
+
+func (p *Plugin) analyzePoint(a analyzer, pt Point) tea.Msg {
+	dir, cleanup, err := pt.materialize()
+	if err != nil {
+		return PointAnalysisMsg{Point: pt, Err: err}
+	}
+	defer cleanup()
+	findings, err := p.collectFindings(a, dir)
+	return PointAnalysisMsg{Point: pt, Findings: findings, Err: err}
+}
+
+func (p *Plugin) comparePoints(a analyzer, base, target Point) tea.Msg {
+	var results [2][]Finding
+	for i, pt := range []Point{base, target} {
+		dir, cleanup, err := pt.materialize()
+		if err != nil {
+			return FindingDiffMsg{Base: base, Target: target, Err: err}
+		}
+		results[i], err = p.collectFindings(a, dir)
+		cleanup()
+		if err != nil {
+			return FindingDiffMsg{Base: base, Target: target, Err: err}
+		}
+	}
+	introduced, resolved := diffFindings(results[0], results[1])
+	return FindingDiffMsg{Base: base, Target: target, Introduced: introduced, Resolved: resolved}
+}
+
+// diffFindings reports findings present only in target (introduced) and
+// only in base (resolved). Line numbers are ignored since unrelated edits
+// shift them.
+func diffFindings(base, target []Finding) (introduced, resolved []Finding) {
+	key := func(f Finding) string { return f.File + "\x00" + string(f.Kind) + "\x00" + f.Message }
+	counts := make(map[string]int)
+	for _, f := range base {
+		counts[key(f)]++
+	}
+	for _, f := range target {
+		if counts[key(f)] > 0 {
+			counts[key(f)]--
+		} else {
+			introduced = append(introduced, f)
+		}
+	}
+	for _, f := range base {
+		if counts[key(f)] > 0 {
+			counts[key(f)]--
+			resolved = append(resolved, f)
+		}
+	}
+	return introduced, resolved
+}
+
+func formatFindings(findings []Finding) string {
+	if len(findings) == 0 {
+		return "  (none)\n"
+	}
+	var sb strings.Builder
+	for _, f := range findings {
+		sb.WriteString(fmt.Sprintf("  %s:%d [%s] %s\n", f.File, f.Line, f.Kind, f.Message))
+	}
+	return sb.String()
+}
+
+// PointsMsg carries the points in time available for analysis.
+type PointsMsg struct {
+	Points []Point
+}
+
+// PointAnalysisMsg carries the findings for a single point in time.
+type PointAnalysisMsg struct {
+	Point    Point
+	Findings []Finding
+	Err      error
+}
+
+// FindingDiffMsg carries the difference in findings between two points.
+type FindingDiffMsg struct {
+	Base, Target         Point
+	Introduced, Resolved []Finding
+	Err                  error
+}
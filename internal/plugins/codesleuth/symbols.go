@@ -0,0 +1,149 @@
+package codesleuth
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"forger/internal/types"
+)
+
+// Symbol is a top-level declaration in a workspace source file: a Go
+// function, method, type, constant or variable, or a COBOL paragraph or
+// section.
+type Symbol struct {
+	Name string // methods are qualified by their receiver, as in Plugin.Update
+	Kind string
+	File string
+	Line int
+}
+
+// findSymbols lists the declarations in files. Each file is parsed on its
+// own, without type checking, so this stays quick on large workspaces.
+func findSymbols(files []string) []Symbol {
+	var symbols []Symbol
+	fset := token.NewFileSet()
+	for _, file := range files {
+		switch {
+		case isGo(file):
+			f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+			if err != nil {
+				continue
+			}
+			symbols = append(symbols, goSymbols(fset, file, f)...)
+		case isCOBOL(file):
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			symbols = append(symbols, cobolSymbols(file, string(data))...)
+		}
+	}
+	return symbols
+}
+
+func goSymbols(fset *token.FileSet, file string, f *ast.File) []Symbol {
+	var symbols []Symbol
+	add := func(name, kind string, pos token.Pos) {
+		if name != "_" {
+			symbols = append(symbols, Symbol{Name: name, Kind: kind, File: file, Line: fset.Position(pos).Line})
+		}
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				add(d.Name.Name, "func", d.Name.Pos())
+			} else {
+				add(receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, "method", d.Name.Pos())
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Name.Name, "type", s.Name.Pos())
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						add(n.Name, d.Tok.String(), n.Pos())
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName returns the type name of a method receiver such as *T or
+// T[K, V].
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return "?"
+		}
+	}
+}
+
+// cobolSymbols lists the paragraphs and sections of a COBOL program's
+// procedure division, recognized as scanCOBOL does.
+func cobolSymbols(file, src string) []Symbol {
+	var symbols []Symbol
+	inProc := false
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if isCOBOLComment(raw) {
+			continue
+		}
+		code := strings.ToUpper(cobolCode(raw))
+		if strings.Contains(code, "PROCEDURE DIVISION") {
+			inProc = true
+			continue
+		}
+		inAreaA := len(code)-len(strings.TrimLeft(code, " ")) < 4
+		if m := cobolParagraph.FindStringSubmatch(code); inProc && m != nil && inAreaA && !cobolStatements[m[1]] {
+			kind := "paragraph"
+			if m[2] != "" {
+				kind = "section"
+			}
+			symbols = append(symbols, Symbol{Name: m[1], Kind: kind, File: file, Line: i + 1})
+		}
+	}
+	return symbols
+}
+
+// SearchItems offers the workspace's source files and their symbols to the
+// fuzzy finder. Both open in the source view.
+func (p *Plugin) SearchItems() []types.SearchItem {
+	items := make([]types.SearchItem, 0, len(p.files)+len(p.symbols))
+	for _, file := range p.files {
+		target := filepath.ToSlash(file)
+		items = append(items, types.SearchItem{
+			Plugin: p.Name(),
+			Kind:   "file",
+			Title:  target,
+			Ref:    &types.Ref{Plugin: p.Name(), Target: target},
+		})
+	}
+	for _, s := range p.symbols {
+		target := filepath.ToSlash(s.File)
+		items = append(items, types.SearchItem{
+			Plugin: p.Name(),
+			Kind:   "symbol",
+			Title:  s.Name,
+			Detail: fmt.Sprintf("%s %s:%d", s.Kind, target, s.Line),
+			Ref:    &types.Ref{Plugin: p.Name(), Target: target, Line: s.Line},
+		})
+	}
+	return items
+}
@@ -0,0 +1,429 @@
+// Package colorizer implements the ascii-colorizer plugin, which shows
+// PNG and JPEG images and other plugins' diagram output as colored text
+// art sized to the panel. Other plugins display an artifact by sending a
+// types.ColorizeMsg; references of the form forger://ascii-colorizer/<path>
+// open a file in the workspace.
+package colorizer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register decoders for image.Decode
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// maxArtifacts is how many recent artifacts are kept.
+	maxArtifacts = 10
+	// maxPixels guards against decoding huge images.
+	maxPixels = 50_000_000
+)
+
+// Config is the "ascii-colorizer" section of forger.json.
+type Config struct {
+	Colors string `json:"colors"` // auto (default), truecolor, 256, 16 or none
+	Mode   string `json:"mode"`   // blocks (default) or ascii
+}
+
+// artifact is something shown by the plugin: an image or diagram text.
+type artifact struct {
+	Title string
+	From  string
+	Path  string
+	Text  string
+	Image image.Image
+}
+
+type Plugin struct {
+	ctx       *types.Context
+	colors    ColorMode
+	ascii     bool
+	width     int
+	height    int
+	artifacts []artifact
+	current   int
+	scroll    int
+	lines     []string // the current artifact, rendered
+	rendered  string   // what lines were rendered for
+	opening   bool     // editing the path to open
+	openPath  string
+	result    string
+}
+
+func New(ctx *types.Context) types.Plugin {
+	p := &Plugin{ctx: ctx, colors: DetectColorMode(), width: 60, height: 20}
+	var cfg Config
+	err := ctx.LoadPluginConfig(types.ColorizerPlugin, &cfg)
+	if err == nil {
+		p.colors, err = ParseColorMode(cfg.Colors)
+	}
+	switch cfg.Mode {
+	case "", "blocks":
+	case "ascii":
+		p.ascii = true
+	default:
+		err = fmt.Errorf("unknown mode %q (use blocks or ascii)", cfg.Mode)
+	}
+	if err != nil {
+		p.result = "❌ Invalid config: " + err.Error()
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return types.ColorizerPlugin
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	return nil
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case types.ColorizeMsg:
+		title := msg.Title
+		if msg.Path != "" {
+			if title == "" {
+				title = filepath.Base(msg.Path)
+			}
+			p.result = "Loading " + msg.Path + "..."
+			return p, load(msg.Path, title, msg.From)
+		}
+		if title == "" {
+			title = "Output from " + msg.From
+		}
+		p.add(artifact{Title: title, From: msg.From, Text: msg.Text})
+		return p, nil
+	case types.OpenRefMsg:
+		if msg.Ref.Plugin != p.Name() {
+			return p, nil
+		}
+		// References arrive over chat; only follow ones inside the workspace.
+		path := filepath.FromSlash(msg.Ref.Target)
+		if !filepath.IsLocal(path) {
+			p.result = "❌ Refusing to open " + msg.Ref.Target + ": outside the workspace"
+			return p, nil
+		}
+		p.opening = false
+		return p, load(path, filepath.Base(path), "")
+	case ArtifactMsg:
+		if msg.Err != nil {
+			p.result = "❌ " + msg.Err.Error()
+			return p, types.Notify(p.Name(), types.SeverityError, "Can't display "+msg.Path+": "+msg.Err.Error())
+		}
+		p.add(artifact{Title: msg.Title, From: msg.From, Path: msg.Path, Text: msg.Text, Image: msg.Image})
+		return p, nil
+	case tea.WindowSizeMsg:
+		// Leave room for the sidebar, the panel border and padding, and
+		// the header and footer lines.
+		if w := msg.Width - 26; w > 10 {
+			p.width = w
+		}
+		if h := msg.Height - 8; h > 5 {
+			p.height = h
+		}
+		return p, nil
+	case tea.KeyMsg:
+		if p.opening {
+			return p, p.updateOpen(msg)
+		}
+		return p, p.updateKeys(msg)
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
+	}
+	return p, nil
+}
+
+func (p *Plugin) updateKeys(msg tea.KeyMsg) tea.Cmd {
+	page := max(1, p.artHeight()-1)
+	switch msg.String() {
+	case "up", "k":
+		p.scrollTo(p.scroll - 1)
+	case "down", "j":
+		p.scrollTo(p.scroll + 1)
+	case "pgup":
+		p.scrollTo(p.scroll - page)
+	case "pgdown", " ":
+		p.scrollTo(p.scroll + page)
+	case "home":
+		p.scrollTo(0)
+	case "end":
+		p.scrollTo(len(p.render()))
+	}
+	return nil
+}
+
+// Actions lists what can be done with the artifacts. Closing and sharing
+// apply to the one shown.
+func (p *Plugin) Actions() []types.Action {
+	showing := func() bool { return !p.opening && len(p.artifacts) > 0 }
+	idle := func() bool { return !p.opening }
+	return []types.Action{
+		{ID: "open", Title: "Open a file", Key: "o", Focus: true, Enabled: idle},
+		{ID: "colors", Title: "Cycle colors (truecolor, 256, 16, none)", Key: "m", Enabled: idle},
+		{ID: "style", Title: "Toggle ascii/blocks", Key: "a", Enabled: idle},
+		{ID: "prev", Title: "Previous artifact", Key: "[", Enabled: func() bool { return showing() && p.current > 0 }},
+		{ID: "next", Title: "Next artifact", Key: "]", Enabled: func() bool { return showing() && p.current < len(p.artifacts)-1 }},
+		{ID: "share", Title: "Share file to chat", Key: "p", Enabled: showing},
+		{ID: "close", Title: "Close artifact", Key: "x", Enabled: showing},
+	}
+}
+
+func (p *Plugin) runAction(id string) tea.Cmd {
+	switch id {
+	case "prev":
+		if p.current > 0 {
+			p.current--
+			p.scroll = 0
+		}
+	case "next":
+		if p.current < len(p.artifacts)-1 {
+			p.current++
+			p.scroll = 0
+		}
+	case "colors":
+		for i, c := range colorModes {
+			if c == p.colors {
+				p.colors = colorModes[(i+1)%len(colorModes)]
+				break
+			}
+		}
+		p.result = "Colors: " + p.colors.String()
+	case "style":
+		p.ascii = !p.ascii
+		p.result = "Style: " + p.style()
+	case "open":
+		p.opening = true
+	case "close":
+		if len(p.artifacts) > 0 {
+			p.artifacts = append(p.artifacts[:p.current], p.artifacts[p.current+1:]...)
+			p.current = min(p.current, max(0, len(p.artifacts)-1))
+			p.scroll = 0
+			p.rendered = ""
+		}
+	case "share":
+		if len(p.artifacts) > 0 {
+			a := p.artifacts[p.current]
+			if a.Path == "" {
+				p.result = "❌ Only files can be shared"
+				return nil
+			}
+			text := fmt.Sprintf("🎨 %s\n%s", a.Title, types.Ref{Plugin: p.Name(), Target: filepath.ToSlash(a.Path)})
+			p.result = "✅ Shared to chat"
+			return func() tea.Msg { return types.ShareMsg{From: p.Name(), Text: text} }
+		}
+	}
+	return nil
+}
+
+// CapturingInput reports whether a path is being typed, so typing q or c
+// there doesn't trigger Forger's global shortcuts.
+func (p *Plugin) CapturingInput() bool {
+	return p.opening
+}
+
+// updateOpen edits the path to open.
+func (p *Plugin) updateOpen(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		path := strings.TrimSpace(p.openPath)
+		p.opening = false
+		if path == "" {
+			return nil
+		}
+		p.result = "Loading " + path + "..."
+		return load(path, filepath.Base(path), "")
+	case "esc":
+		p.opening = false
+	case "backspace":
+		if r := []rune(p.openPath); len(r) > 0 {
+			p.openPath = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		p.openPath = ""
+	default:
+		if msg.Type == tea.KeyRunes {
+			p.openPath += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
+// add shows a as the current artifact, dropping the oldest beyond
+// maxArtifacts.
+func (p *Plugin) add(a artifact) {
+	p.artifacts = append(p.artifacts, a)
+	if len(p.artifacts) > maxArtifacts {
+		p.artifacts = p.artifacts[len(p.artifacts)-maxArtifacts:]
+	}
+	p.current = len(p.artifacts) - 1
+	p.scroll = 0
+	p.rendered = ""
+	p.result = ""
+}
+
+// load reads the file at path as an image, or failing that as text.
+func load(path, title, from string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ArtifactMsg{Path: path, Title: title, From: from}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			msg.Err = err
+			return msg
+		}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			if cfg.Width*cfg.Height > maxPixels {
+				msg.Err = fmt.Errorf("image is too large (%d×%d)", cfg.Width, cfg.Height)
+				return msg
+			}
+			msg.Image, _, msg.Err = image.Decode(bytes.NewReader(data))
+			return msg
+		}
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			msg.Err = fmt.Errorf("not a PNG, JPEG or text file")
+			return msg
+		}
+		msg.Text = string(data)
+		return msg
+	}
+}
+
+func (p *Plugin) style() string {
+	if p.ascii || p.colors == ColorNone {
+		return "ascii"
+	}
+	return "blocks"
+}
+
+// artHeight is how many lines of art fit under the header and above the
+// footer.
+func (p *Plugin) artHeight() int {
+	return max(1, p.height-3)
+}
+
+// render returns the current artifact drawn for the panel, re-rendering
+// only when the artifact, panel size or style has changed.
+func (p *Plugin) render() []string {
+	if len(p.artifacts) == 0 {
+		return nil
+	}
+	key := fmt.Sprintf("%d %d %d %d %t", p.current, p.width, p.artHeight(), p.colors, p.ascii)
+	if key == p.rendered {
+		return p.lines
+	}
+	a := p.artifacts[p.current]
+	if a.Image != nil {
+		p.lines = strings.Split(RenderImage(a.Image, p.width, p.artHeight(), p.colors, p.ascii), "\n")
+	} else {
+		p.lines = strings.Split(strings.TrimRight(a.Text, "\n"), "\n")
+		for i, line := range p.lines {
+			line = strings.ReplaceAll(stripANSI(line), "\t", "    ")
+			if r := []rune(line); len(r) > p.width {
+				line = string(r[:p.width-1]) + "…"
+			}
+			p.lines[i] = ColorizeText(line, p.colors)
+		}
+	}
+	p.rendered = key
+	return p.lines
+}
+
+func (p *Plugin) scrollTo(i int) {
+	p.scroll = max(0, min(i, len(p.render())-p.artHeight()))
+}
+
+func (p *Plugin) View() string {
+	if len(p.artifacts) == 0 {
+		return p.emptyView()
+	}
+
+	var sb strings.Builder
+	a := p.artifacts[p.current]
+	header := "ASCII Colorizer ─ " + a.Title
+	if a.From != "" {
+		header += " (from " + a.From + ")"
+	}
+	if len(p.artifacts) > 1 {
+		header += fmt.Sprintf(" [%d/%d]", p.current+1, len(p.artifacts))
+	}
+	sb.WriteString(header + "\n")
+
+	lines := p.render()
+	p.scrollTo(p.scroll)
+	end := min(p.scroll+p.artHeight(), len(lines))
+	for _, line := range lines[p.scroll:end] {
+		sb.WriteString(line + "\n")
+	}
+	status := fmt.Sprintf("%s, %s", p.colors, p.style())
+	if len(lines) > p.artHeight() {
+		status += fmt.Sprintf(" • lines %d-%d of %d", p.scroll+1, end, len(lines))
+	}
+	if p.result != "" {
+		status += " • " + p.result
+	}
+	sb.WriteString(status + "\n")
+	if p.opening {
+		sb.WriteString(fmt.Sprintf("Open: %s█  (Enter: open • Esc: cancel)", p.openPath))
+	} else {
+		sb.WriteString("↑/↓ scroll • " + types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())))
+	}
+	return sb.String()
+}
+
+func (p *Plugin) emptyView() string {
+	var sb strings.Builder
+	sb.WriteString("┌─ ASCII Colorizer ──────────────────────────────────────────┐\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  Nothing to show yet.                                       │\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  Open a PNG, JPEG or text file, or send a diagram here      │\n")
+	sb.WriteString("│  from another plugin (V in CodeSleuth reports).             │\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString(fmt.Sprintf("│  Colors: %-50s │\n", p.colors.String()+", "+p.style()))
+	if p.result != "" {
+		sb.WriteString(fmt.Sprintf("│  %-58s │\n", truncate(p.result, 58)))
+	}
+	sb.WriteString("│                                                             │\n")
+	if p.opening {
+		sb.WriteString(fmt.Sprintf("│  Open: [%-49s] │\n", truncate(p.openPath, 49)))
+		sb.WriteString("│  Enter: open • Ctrl+U: clear • Esc: cancel                  │\n")
+		sb.WriteString("│                                                             │\n")
+	}
+	sb.WriteString("│  Commands:                                                  │\n")
+	for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+		sb.WriteString(fmt.Sprintf("│  • %-56s │\n", line))
+	}
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
+
+// truncate shortens s to at most n runes, keeping the end, which is the
+// interesting part of a path.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return "…" + string(r[len(r)-n+1:])
+	}
+	return s
+}
+
+// ArtifactMsg carries a file loaded for display.
+type ArtifactMsg struct {
+	Path  string
+	Title string
+	From  string
+	Text  string
+	Image image.Image
+	Err   error
+}
@@ -0,0 +1,70 @@
+package colorizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pngOfSize returns a small PNG whose header claims width×height pixels.
+func pngOfSize(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// The IHDR chunk follows the 8-byte signature: length, type, then
+	// width and height, and its CRC covers the type and data.
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestLoad(t *testing.T) {
+	small := pngOfSize(t, 2, 2)
+	tests := []struct {
+		name  string
+		data  []byte
+		image bool
+		text  string
+		err   string
+	}{
+		{name: "image", data: small, image: true},
+		{name: "too many pixels", data: pngOfSize(t, 10000, 10000), err: "image is too large (10000×10000)"},
+		{name: "text", data: []byte("┌─┐\n└─┘\n"), text: "┌─┐\n└─┘\n"},
+		{name: "binary", data: []byte{0xff, 0xfe, 0x00, 0x01}, err: "not a PNG, JPEG or text file"},
+		{name: "nul byte", data: []byte("a\x00b"), err: "not a PNG, JPEG or text file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "artifact")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			msg := load(path, "title", "test")().(ArtifactMsg)
+			got := ""
+			if msg.Err != nil {
+				got = msg.Err.Error()
+			}
+			if got != tt.err {
+				t.Fatalf("err = %q, want %q", got, tt.err)
+			}
+			if (msg.Image != nil) != tt.image {
+				t.Errorf("decoded image = %t, want %t", msg.Image != nil, tt.image)
+			}
+			if msg.Text != tt.text {
+				t.Errorf("text = %q, want %q", msg.Text, tt.text)
+			}
+		})
+	}
+}
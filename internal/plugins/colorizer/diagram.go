@@ -0,0 +1,58 @@
+package colorizer
+
+import (
+	"image/color"
+	"regexp"
+	"strings"
+)
+
+// diagramToken matches the parts of a text diagram that get colored, one
+// group per kind: arrows, box drawing, bracketed labels and Mermaid or
+// Graphviz keywords.
+var diagramToken = regexp.MustCompile(
+	`(<?[-=.]+>|<[-=.]+|[→←↑↓↔⇒⇐▶◀▲▼►◄])` +
+		`|([─━│┃┌┐└┘├┤┬┴┼╭╮╯╰═║╔╗╚╝╠╣╦╩╬]+)` +
+		`|(\[[^\]\n]*\]|\([^)\n]*\)|\{[^}\n]*\}|"[^"\n]*")` +
+		`|\b(graph|flowchart|subgraph|end|sequenceDiagram|classDiagram|stateDiagram|erDiagram|participant|actor|digraph|node|edge|TD|TB|LR|RL|BT)\b`)
+
+// diagramColors holds the color for each group of diagramToken.
+var diagramColors = []color.RGBA{
+	{255, 215, 0, 255}, // arrows
+	{0, 175, 215, 255}, // box drawing
+	{0, 215, 95, 255},  // labels
+	{215, 0, 215, 255}, // keywords
+}
+
+// ColorizeText colors a text diagram, such as a call graph, an IR dump or
+// Mermaid source, for display in mode. Any escape sequences already in
+// text are removed first.
+func ColorizeText(text string, mode ColorMode) string {
+	text = stripANSI(strings.ReplaceAll(text, "\t", "    "))
+	if mode == ColorNone {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range diagramToken.FindAllStringSubmatchIndex(text, -1) {
+		group := 0
+		for g := 1; g*2 < len(m); g++ {
+			if m[g*2] >= 0 {
+				group = g
+				break
+			}
+		}
+		sb.WriteString(text[last:m[0]])
+		sb.WriteString(fg(diagramColors[group-1], mode))
+		sb.WriteString(text[m[0]:m[1]])
+		sb.WriteString(reset)
+		last = m[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
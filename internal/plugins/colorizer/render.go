@@ -0,0 +1,255 @@
+package colorizer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+)
+
+// ColorMode is how many colors art is drawn with.
+type ColorMode int
+
+const (
+	ColorNone ColorMode = iota
+	Color16
+	Color256
+	ColorTrue
+)
+
+// colorModes is the order M cycles through.
+var colorModes = []ColorMode{ColorTrue, Color256, Color16, ColorNone}
+
+func (m ColorMode) String() string {
+	switch m {
+	case ColorTrue:
+		return "truecolor"
+	case Color256:
+		return "256 colors"
+	case Color16:
+		return "16 colors"
+	}
+	return "no color"
+}
+
+// ParseColorMode reads a color mode from config: truecolor, 256, 16, none
+// or auto.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return DetectColorMode(), nil
+	case "truecolor", "24bit", "true":
+		return ColorTrue, nil
+	case "256":
+		return Color256, nil
+	case "16":
+		return Color16, nil
+	case "none", "mono":
+		return ColorNone, nil
+	}
+	return DetectColorMode(), fmt.Errorf("unknown colors %q (use auto, truecolor, 256, 16 or none)", s)
+}
+
+// DetectColorMode guesses what the terminal supports from the environment.
+func DetectColorMode() ColorMode {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return ColorNone
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorTrue
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("WT_SESSION") != "":
+		// Windows Terminal supports truecolor but doesn't say so.
+		return ColorTrue
+	case strings.Contains(term, "256"):
+		return Color256
+	case term == "dumb":
+		return ColorNone
+	}
+	return Color16
+}
+
+// asciiRamp runs from dark to light.
+const asciiRamp = " .:-=+*#%@"
+
+const reset = "\x1b[0m"
+
+// RenderImage draws img in at most cols × rows terminal cells. Blocks mode
+// packs two pixels into each cell with the upper half block, using the
+// foreground for the top pixel and the background for the bottom one;
+// ascii mode picks a character by brightness instead. Without color,
+// blocks fall back to ascii.
+func RenderImage(img image.Image, cols, rows int, mode ColorMode, ascii bool) string {
+	b := img.Bounds()
+	if b.Empty() || cols <= 0 || rows <= 0 {
+		return ""
+	}
+	if mode == ColorNone {
+		ascii = true
+	}
+
+	// Cells are about twice as tall as they are wide, so a cell covers
+	// one pixel column and two pixel rows of the scaled image.
+	scale := min(float64(cols)/float64(b.Dx()), float64(2*rows)/float64(b.Dy()))
+	w := max(1, int(float64(b.Dx())*scale))
+	h := max(2, int(float64(b.Dy())*scale))
+	h += h % 2
+
+	sample := func(x, y int) color.RGBA {
+		return average(img, b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h,
+			b.Min.X+(x+1)*b.Dx()/w, b.Min.Y+(y+1)*b.Dy()/h)
+	}
+
+	var sb strings.Builder
+	for y := 0; y < h; y += 2 {
+		last := ""
+		for x := 0; x < w; x++ {
+			top, bottom := sample(x, y), sample(x, y+1)
+			var seq string
+			var ch string
+			if ascii {
+				c := mix(top, bottom)
+				ch = string(asciiRamp[int(luminance(c)*float64(len(asciiRamp)-1)+0.5)])
+				seq = fg(c, mode)
+			} else {
+				ch = "▀"
+				seq = fg(top, mode) + bg(bottom, mode)
+			}
+			if seq != last {
+				sb.WriteString(seq)
+				last = seq
+			}
+			sb.WriteString(ch)
+		}
+		if mode != ColorNone {
+			sb.WriteString(reset)
+		}
+		if y+2 < h {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// average returns the mean color of the pixels in [x0,x1)×[y0,y1),
+// blended over black.
+func average(img image.Image, x0, y0, x1, y1 int) color.RGBA {
+	x1, y1 = max(x1, x0+1), max(y1, y0+1)
+	var r, g, b, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			// RGBA is alpha-premultiplied, which blends over black.
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r, g, b = r+uint64(cr), g+uint64(cg), b+uint64(cb)
+			n++
+		}
+	}
+	return color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), 255}
+}
+
+func mix(a, b color.RGBA) color.RGBA {
+	return color.RGBA{uint8((int(a.R) + int(b.R)) / 2), uint8((int(a.G) + int(b.G)) / 2), uint8((int(a.B) + int(b.B)) / 2), 255}
+}
+
+// luminance is perceived brightness from 0 to 1.
+func luminance(c color.RGBA) float64 {
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
+
+// fg returns the escape sequence selecting c as the foreground color.
+func fg(c color.RGBA, mode ColorMode) string {
+	switch mode {
+	case ColorTrue:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	case Color256:
+		return fmt.Sprintf("\x1b[38;5;%dm", nearest256(c))
+	case Color16:
+		i := nearest16(c)
+		if i < 8 {
+			return fmt.Sprintf("\x1b[%dm", 30+i)
+		}
+		return fmt.Sprintf("\x1b[%dm", 90+i-8)
+	}
+	return ""
+}
+
+// bg returns the escape sequence selecting c as the background color.
+func bg(c color.RGBA, mode ColorMode) string {
+	switch mode {
+	case ColorTrue:
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	case Color256:
+		return fmt.Sprintf("\x1b[48;5;%dm", nearest256(c))
+	case Color16:
+		i := nearest16(c)
+		if i < 8 {
+			return fmt.Sprintf("\x1b[%dm", 40+i)
+		}
+		return fmt.Sprintf("\x1b[%dm", 100+i-8)
+	}
+	return ""
+}
+
+// cubeLevels are the channel values of the xterm 6×6×6 color cube.
+var cubeLevels = []int{0, 95, 135, 175, 215, 255}
+
+// nearest256 maps c to the closest xterm-256 color in the color cube
+// (16-231) or the grayscale ramp (232-255).
+func nearest256(c color.RGBA) int {
+	level := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := level(c.R), level(c.G), level(c.B)
+	cube := color.RGBA{uint8(cubeLevels[r]), uint8(cubeLevels[g]), uint8(cubeLevels[b]), 255}
+
+	gray := (int(c.R) + int(c.G) + int(c.B)) / 3
+	step := min(23, max(0, (gray-8+5)/10))
+	grayColor := color.RGBA{uint8(8 + 10*step), uint8(8 + 10*step), uint8(8 + 10*step), 255}
+
+	if distance(c, grayColor) < distance(c, cube) {
+		return 232 + step
+	}
+	return 16 + 36*r + 6*g + b
+}
+
+// palette16 is the xterm default palette for the 16 ANSI colors.
+var palette16 = []color.RGBA{
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+func nearest16(c color.RGBA) int {
+	best := 0
+	for i, p := range palette16 {
+		if distance(c, p) < distance(c, palette16[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// distance is the squared distance between colors, weighted toward green
+// as the eye is.
+func distance(a, b color.RGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return 2*dr*dr + 4*dg*dg + 3*db*db
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
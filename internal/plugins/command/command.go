@@ -0,0 +1,297 @@
+// Package command turns a plugin definition in forger.json into a plugin:
+// run a command, parse its output into a list, and act on the selected
+// item with key-bound actions. Commands go through the shared runner and
+// action output is shown in the shared pager, as in the built-in plugins.
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"forger/internal/runner"
+	"forger/internal/types"
+	"forger/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// visibleItems is how many items the list shows at once.
+const visibleItems = 12
+
+// Plugin is a command plugin.
+type Plugin struct {
+	ctx      *types.Context
+	name     string
+	spec     Spec
+	items    []Item
+	selected int
+	offset   int
+	loading  bool
+	result   string    // feedback from the last list run or action
+	errorMsg string    // an invalid definition or failed list command
+	invalid  bool      // the definition can't be used
+	pager    *ui.Pager // action output, while shown
+}
+
+// New returns the command plugin called name, defined by its section in
+// ctx.PluginConfig.
+func New(ctx *types.Context, name string) *Plugin {
+	p := &Plugin{ctx: ctx, name: name}
+	spec, err := ParseSpec(ctx.PluginConfig[name])
+	if err != nil {
+		p.errorMsg = fmt.Sprintf("Invalid definition: %v", err)
+		p.invalid = true
+	}
+	p.spec = spec
+	if p.spec.Title == "" {
+		p.spec.Title = name
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	return p.refresh()
+}
+
+// refresh re-runs the list command.
+func (p *Plugin) refresh() tea.Cmd {
+	if p.invalid {
+		return nil
+	}
+	p.loading = true
+	name, spec := p.name, p.spec
+	return func() tea.Msg {
+		res := runner.Run(spec.Dir, spec.Command[0], spec.Command[1:]...)
+		if res.Err != nil {
+			return ListMsg{Plugin: name, Err: fmt.Errorf("%v\n%s", res.Err, res.Output)}
+		}
+		items, err := spec.Parse.parse(res.Output)
+		for i := range items {
+			items[i].Label = label(spec.Label, items[i].Fields)
+		}
+		return ListMsg{Plugin: name, Items: items, Err: err}
+	}
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ListMsg:
+		// Every plugin sees every message; only take our own.
+		if msg.Plugin != p.name {
+			return p, nil
+		}
+		p.loading = false
+		if msg.Err != nil {
+			p.errorMsg = "List command failed: " + types.FirstLine(msg.Err.Error())
+			return p, types.Notify(p.name, types.SeverityError, p.errorMsg)
+		}
+		p.errorMsg = ""
+		p.items = msg.Items
+		p.result = fmt.Sprintf("%d items", len(p.items))
+		p.moveTo(p.selected)
+		return p, nil
+	case ActionResultMsg:
+		if msg.Plugin != p.name {
+			return p, nil
+		}
+		title := fmt.Sprintf("%s: %s", msg.Action.Name, strings.Join(msg.Result.Args, " "))
+		text := msg.Result.Output
+		var cmds []tea.Cmd
+		if msg.Result.Err != nil {
+			text = fmt.Sprintf("Error: %v\n%s", msg.Result.Err, text)
+			cmds = append(cmds, types.Notify(p.name, types.SeverityError, msg.Action.Name+" failed: "+msg.Result.Err.Error()))
+		}
+		if strings.TrimSpace(text) == "" {
+			p.result = fmt.Sprintf("✅ %s finished with no output", msg.Action.Name)
+		} else {
+			p.pager = ui.NewPager(title, text)
+		}
+		if msg.Action.Refresh {
+			cmds = append(cmds, p.refresh())
+		}
+		return p, tea.Batch(cmds...)
+	case tea.KeyMsg:
+		if p.pager != nil {
+			if msg.String() == "esc" || msg.String() == "q" {
+				p.pager = nil
+			} else {
+				p.pager.Update(msg)
+			}
+			return p, nil
+		}
+		switch msg.String() {
+		case "up", "k":
+			p.moveTo(p.selected - 1)
+		case "down", "j":
+			p.moveTo(p.selected + 1)
+		}
+	case types.ActionMsg:
+		if msg.Plugin != p.name {
+			return p, nil
+		}
+		if msg.ID == "refresh" {
+			return p, p.refresh()
+		}
+		for _, a := range p.spec.Actions {
+			if a.Name == msg.ID && !p.invalid {
+				return p, p.run(a)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Actions lists refreshing and the actions from the definition, which
+// run on the selected item.
+func (p *Plugin) Actions() []types.Action {
+	listing := func() bool { return p.pager == nil }
+	actions := []types.Action{{ID: "refresh", Title: "Refresh", Key: "r", Enabled: listing}}
+	if p.invalid {
+		return actions
+	}
+	for _, a := range p.spec.Actions {
+		actions = append(actions, types.Action{ID: a.Name, Title: a.Name, Key: a.Key, Focus: len(a.Command) > 0, Enabled: listing})
+	}
+	return actions
+}
+
+// run performs action a on the selected item.
+func (p *Plugin) run(a Action) tea.Cmd {
+	if p.selected >= len(p.items) {
+		if len(a.Command) == 0 && a.Refresh {
+			return p.refresh()
+		}
+		p.result = "❌ Nothing selected"
+		return nil
+	}
+	item := p.items[p.selected]
+	fields := map[string]string{"label": item.Label}
+	for k, v := range item.Fields {
+		fields[k] = v
+	}
+
+	var cmds []tea.Cmd
+	if a.Share != "" {
+		share := types.ShareMsg{From: p.name, Text: expand(a.Share, fields)}
+		cmds = append(cmds, func() tea.Msg { return share })
+		p.result = "Shared " + item.Label
+	}
+	if a.Ref != "" {
+		refs := types.ParseRefs(expand(a.Ref, fields))
+		if len(refs) == 0 {
+			p.result = "❌ Not a reference: " + expand(a.Ref, fields)
+		} else {
+			ref := refs[0]
+			cmds = append(cmds, func() tea.Msg { return types.OpenRefMsg{Ref: ref} })
+		}
+	}
+	if len(a.Command) > 0 {
+		args := make([]string, len(a.Command))
+		for i, arg := range a.Command {
+			args[i] = expand(arg, fields)
+		}
+		p.result = fmt.Sprintf("Running %s...", a.Name)
+		name, dir := p.name, p.spec.Dir
+		cmds = append(cmds, func() tea.Msg {
+			return ActionResultMsg{Plugin: name, Action: a, Result: runner.Run(dir, args[0], args[1:]...)}
+		})
+	} else if a.Refresh {
+		cmds = append(cmds, p.refresh())
+	}
+	return tea.Batch(cmds...)
+}
+
+func (p *Plugin) moveTo(i int) {
+	if i >= len(p.items) {
+		i = len(p.items) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	p.selected = i
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+visibleItems {
+		p.offset = p.selected - visibleItems + 1
+	}
+}
+
+func (p *Plugin) View() string {
+	if p.pager != nil {
+		return p.pager.View() + "\n\nEsc: Back to the list"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("┌─ %s %s┐\n", p.spec.Title, strings.Repeat("─", max(0, 58-len([]rune(p.spec.Title))))))
+	sb.WriteString("│                                                             │\n")
+	if len(p.spec.Command) > 0 {
+		sb.WriteString(boxLine("$ " + strings.Join(p.spec.Command, " ")))
+		sb.WriteString("│                                                             │\n")
+	}
+	if p.errorMsg != "" {
+		for _, line := range strings.Split(p.errorMsg, "\n") {
+			sb.WriteString(boxLine("❌ " + line))
+		}
+		sb.WriteString("│                                                             │\n")
+	}
+
+	switch {
+	case p.loading && len(p.items) == 0:
+		sb.WriteString(boxLine("Loading..."))
+	case len(p.items) == 0:
+		sb.WriteString(boxLine("No items"))
+	default:
+		end := min(p.offset+visibleItems, len(p.items))
+		for i := p.offset; i < end; i++ {
+			prefix := "  "
+			if i == p.selected {
+				prefix = "> "
+			}
+			sb.WriteString(boxLine(prefix + p.items[i].Label))
+		}
+		if len(p.items) > visibleItems {
+			sb.WriteString(boxLine(fmt.Sprintf("  [%d-%d of %d]", p.offset+1, end, len(p.items))))
+		}
+	}
+	sb.WriteString("│                                                             │\n")
+	if p.result != "" {
+		sb.WriteString(boxLine("Status: " + p.result))
+		sb.WriteString("│                                                             │\n")
+	}
+
+	sb.WriteString("│  Commands:                                                  │\n")
+	sb.WriteString("│  • ↑/↓: Navigate                                            │\n")
+	for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+		sb.WriteString(boxLine("• " + line))
+	}
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
+
+// boxLine pads or truncates text to one line of the box.
+func boxLine(text string) string {
+	text = strings.ReplaceAll(text, "\t", " ")
+	if r := []rune(text); len(r) > 58 {
+		text = string(r[:55]) + "..."
+	}
+	return fmt.Sprintf("│  %-58s │\n", text)
+}
+
+// ListMsg carries the parsed output of a command plugin's list command.
+type ListMsg struct {
+	Plugin string
+	Items  []Item
+	Err    error
+}
+
+// ActionResultMsg carries the result of running an action's command.
+type ActionResultMsg struct {
+	Plugin string
+	Action Action
+	Result runner.Result
+}
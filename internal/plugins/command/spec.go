@@ -0,0 +1,274 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Spec defines a command plugin. It is the plugin's section of "plugins"
+// in forger.json; a section with a "command" makes the plugin a command
+// plugin.
+type Spec struct {
+	Title   string   `json:"title"`
+	Command []string `json:"command"` // program and arguments; no shell is involved
+	Dir     string   `json:"dir"`     // working directory; defaults to the workspace
+	Parse   Parser   `json:"parse"`
+	Label   string   `json:"label"` // item template, such as "{file}:{line}"
+	Actions []Action `json:"actions"`
+}
+
+// Parser turns command output into items.
+type Parser struct {
+	Format  string `json:"format"`  // lines (default), regex, json or jsonl
+	Pattern string `json:"pattern"` // regex: named groups become fields
+	Path    string `json:"path"`    // json: dotted path to the array of items
+}
+
+// Action is something to do with the selected item, bound to a key.
+// Templates in Command, Ref and Share are filled from the item's fields.
+type Action struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`    // unique; also the action's ID in the keymap
+	Command []string `json:"command"` // run and show the output in a pager
+	Ref     string   `json:"ref"`     // follow a forger:// reference
+	Share   string   `json:"share"`   // post to chat
+	Refresh bool     `json:"refresh"` // re-run the list command afterwards
+}
+
+// Item is one parsed entry of the list output.
+type Item struct {
+	Label  string
+	Fields map[string]string
+}
+
+// reservedKeys are handled by the plugin itself.
+var reservedKeys = map[string]bool{"up": true, "down": true, "k": true, "j": true, "esc": true, "r": true}
+
+// IsSpec reports whether raw, a plugin's config section, defines a command
+// plugin.
+func IsSpec(raw json.RawMessage) bool {
+	var probe struct {
+		Command []string `json:"command"`
+	}
+	return json.Unmarshal(raw, &probe) == nil && len(probe.Command) > 0
+}
+
+// ParseSpec decodes and checks a command plugin definition.
+func ParseSpec(raw json.RawMessage) (Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return spec, err
+	}
+	if len(spec.Command) == 0 {
+		return spec, fmt.Errorf("no command")
+	}
+	switch spec.Parse.Format {
+	case "", "lines", "json", "jsonl":
+	case "regex":
+		if _, err := regexp.Compile(spec.Parse.Pattern); err != nil {
+			return spec, fmt.Errorf("parse.pattern: %v", err)
+		}
+	default:
+		return spec, fmt.Errorf("unknown parse.format %q (use lines, regex, json or jsonl)", spec.Parse.Format)
+	}
+	keys, names := make(map[string]bool), make(map[string]bool)
+	for _, a := range spec.Actions {
+		switch {
+		case a.Name == "":
+			return spec, fmt.Errorf("action with key %q has no name", a.Key)
+		case a.Name == "refresh":
+			return spec, fmt.Errorf("action name %q is reserved", a.Name)
+		case names[a.Name]:
+			return spec, fmt.Errorf("action name %q is used more than once", a.Name)
+		case a.Key == "":
+			return spec, fmt.Errorf("action %q has no key", a.Name)
+		case reservedKeys[a.Key]:
+			return spec, fmt.Errorf("action %q: key %q is reserved", a.Name, a.Key)
+		case keys[a.Key]:
+			return spec, fmt.Errorf("key %q is bound to more than one action", a.Key)
+		case len(a.Command) == 0 && a.Ref == "" && a.Share == "" && !a.Refresh:
+			return spec, fmt.Errorf("action %q does nothing", a.Name)
+		}
+		keys[a.Key] = true
+		names[a.Name] = true
+	}
+	return spec, nil
+}
+
+// parse turns output into items according to p.
+func (p Parser) parse(output string) ([]Item, error) {
+	var records []map[string]string
+	switch p.Format {
+	case "", "lines":
+		for _, line := range splitLines(output) {
+			records = append(records, map[string]string{"line": line})
+		}
+	case "regex":
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range splitLines(output) {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			fields := map[string]string{"line": line}
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					fields[name] = m[i]
+				}
+			}
+			records = append(records, fields)
+		}
+	case "json":
+		var doc interface{}
+		if err := json.Unmarshal([]byte(output), &doc); err != nil {
+			return nil, fmt.Errorf("output is not JSON: %v", err)
+		}
+		v, err := lookup(doc, p.Path)
+		if err != nil {
+			return nil, err
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%q is not an array", p.Path)
+		}
+		for _, elem := range list {
+			records = append(records, flatten(elem))
+		}
+	case "jsonl":
+		for _, line := range splitLines(output) {
+			var elem interface{}
+			if err := json.Unmarshal([]byte(line), &elem); err != nil {
+				continue
+			}
+			records = append(records, flatten(elem))
+		}
+	}
+
+	items := make([]Item, len(records))
+	for i, fields := range records {
+		items[i] = Item{Fields: fields}
+	}
+	return items, nil
+}
+
+func splitLines(output string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// lookup follows a dotted path such as "data.items" or "results.0.files";
+// numeric segments index arrays.
+func lookup(v interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+	for _, seg := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[seg]
+			if !ok {
+				return nil, fmt.Errorf("path %q: no %q", path, seg)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("path %q: bad index %q", path, seg)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("path %q: %q is not an object or array", path, seg)
+		}
+	}
+	return v, nil
+}
+
+// flatten turns a JSON value into fields: nested objects get dotted keys
+// and anything that isn't an object is the single field "value".
+func flatten(v interface{}) map[string]string {
+	fields := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fields[prefix] = scalar(v)
+			return
+		}
+		for k, child := range obj {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			walk(k, child)
+		}
+	}
+	if _, ok := v.(map[string]interface{}); ok {
+		walk("", v)
+	} else {
+		fields["value"] = scalar(v)
+	}
+	return fields
+}
+
+func scalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+var placeholder = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// expand fills {field} placeholders in tmpl from fields; unknown fields
+// are empty.
+func expand(tmpl string, fields map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return fields[m[1:len(m)-1]]
+	})
+}
+
+// label renders an item for the list: the label template if there is
+// one, otherwise the raw line or the item's fields.
+func label(tmpl string, fields map[string]string) string {
+	if tmpl != "" {
+		return expand(tmpl, fields)
+	}
+	if line, ok := fields["line"]; ok {
+		return line
+	}
+	if v, ok := fields["value"]; ok {
+		return v
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + fields[k]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParserParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser Parser
+		output string
+		want   []map[string]string
+		err    bool
+	}{
+		{
+			name:   "lines skip blanks and CR",
+			output: "a\r\n\n  \nb\n",
+			want:   []map[string]string{{"line": "a"}, {"line": "b"}},
+		},
+		{
+			name:   "regex named groups",
+			parser: Parser{Format: "regex", Pattern: `^(?P<file>[^:]+):(?P<n>\d+)`},
+			output: "main.go:12: unused\nnoise\nx.go:3",
+			want: []map[string]string{
+				{"line": "main.go:12: unused", "file": "main.go", "n": "12"},
+				{"line": "x.go:3", "file": "x.go", "n": "3"},
+			},
+		},
+		{
+			name:   "json path",
+			parser: Parser{Format: "json", Path: "data.items"},
+			output: `{"data":{"items":[{"id":1,"meta":{"ok":true}},"plain",null]}}`,
+			want:   []map[string]string{{"id": "1", "meta.ok": "true"}, {"value": "plain"}, {"value": ""}},
+		},
+		{
+			name:   "json root array",
+			parser: Parser{Format: "json"},
+			output: `[{"tags":["a","b"]}]`,
+			want:   []map[string]string{{"tags": `["a","b"]`}},
+		},
+		{
+			name:   "json not an array",
+			parser: Parser{Format: "json", Path: "data"},
+			output: `{"data":{}}`,
+			err:    true,
+		},
+		{
+			name:   "json invalid",
+			parser: Parser{Format: "json"},
+			output: `not json`,
+			err:    true,
+		},
+		{
+			name:   "jsonl skips bad lines",
+			parser: Parser{Format: "jsonl"},
+			output: "{\"n\":1.5}\nbroken\n{\"n\":2}\n",
+			want:   []map[string]string{{"n": "1.5"}, {"n": "2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := tt.parser.parse(tt.output)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %t", err, tt.err)
+			}
+			var got []map[string]string
+			for _, item := range items {
+				got = append(got, item.Fields)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"results":[{"files":["a.go","b.go"]}],"n":3}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want interface{}
+		err  bool
+	}{
+		{"", doc, false},
+		{"n", 3.0, false},
+		{"results.0.files.1", "b.go", false},
+		{"results.1", nil, true},
+		{"results.x", nil, true},
+		{"results.-1", nil, true},
+		{"missing", nil, true},
+		{"n.deeper", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := lookup(doc, tt.path)
+		if (err != nil) != tt.err {
+			t.Errorf("lookup(%q) err = %v, want error %t", tt.path, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookup(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		name, spec, err string
+	}{
+		{"minimal", `{"command":["ls"]}`, ""},
+		{"actions", `{"command":["ls"],"actions":[{"key":"o","name":"Open","ref":"forger://x"},{"key":"p","name":"Share","share":"{line}"}]}`, ""},
+		{"no command", `{"command":[]}`, "no command"},
+		{"bad format", `{"command":["ls"],"parse":{"format":"xml"}}`, `unknown parse.format "xml" (use lines, regex, json or jsonl)`},
+		{"bad pattern", `{"command":["ls"],"parse":{"format":"regex","pattern":"("}}`, "parse.pattern: error parsing regexp: missing closing ): `(`"},
+		{"no name", `{"command":["ls"],"actions":[{"key":"o","ref":"x"}]}`, `action with key "o" has no name`},
+		{"refresh name", `{"command":["ls"],"actions":[{"key":"o","name":"refresh","refresh":true}]}`, `action name "refresh" is reserved`},
+		{"duplicate name", `{"command":["ls"],"actions":[{"key":"o","name":"Go","ref":"x"},{"key":"p","name":"Go","ref":"y"}]}`, `action name "Go" is used more than once`},
+		{"no key", `{"command":["ls"],"actions":[{"name":"Go","ref":"x"}]}`, `action "Go" has no key`},
+		{"reserved key", `{"command":["ls"],"actions":[{"key":"r","name":"Go","ref":"x"}]}`, `action "Go": key "r" is reserved`},
+		{"duplicate key", `{"command":["ls"],"actions":[{"key":"o","name":"A","ref":"x"},{"key":"o","name":"B","ref":"y"}]}`, `key "o" is bound to more than one action`},
+		{"does nothing", `{"command":["ls"],"actions":[{"key":"o","name":"Go"}]}`, `action "Go" does nothing`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSpec(json.RawMessage(tt.spec))
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.err {
+				t.Errorf("error = %q, want %q", got, tt.err)
+			}
+		})
+	}
+}
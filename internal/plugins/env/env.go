@@ -0,0 +1,287 @@
+// Package env implements the env plugin, a diagnostics page for the
+// environment Forger runs in: the Go toolchain, the tools other plugins
+// depend on, relevant environment variables with secrets masked, the
+// terminal's capabilities and the disk space Forger's directories use.
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"forger/internal/types"
+	"forger/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Results of the probes that run commands or walk directories, which run
+// in the background.
+type (
+	goEnvMsg struct {
+		vars map[string]string
+		err  error
+	}
+	toolsMsg struct {
+		tools []Tool
+	}
+	usageMsg struct {
+		usage []Usage
+	}
+)
+
+type Plugin struct {
+	ctx      *types.Context
+	goEnv    map[string]string
+	goErr    error
+	tools    []Tool // nil until probed
+	usage    []Usage
+	probing  int // probes still running
+	allGoEnv bool
+	width    int // the whole terminal, as reported to Forger
+	height   int
+	pager    *ui.Pager
+}
+
+func New(ctx *types.Context) types.Plugin {
+	p := &Plugin{ctx: ctx}
+	p.pager = ui.NewPager("Environment", "")
+	p.render()
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return "env"
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	return p.refresh()
+}
+
+// refresh reruns the background probes.
+func (p *Plugin) refresh() tea.Cmd {
+	p.probing = 3
+	p.render()
+	return tea.Batch(
+		func() tea.Msg {
+			vars, err := probeGoEnv()
+			return goEnvMsg{vars: vars, err: err}
+		},
+		func() tea.Msg { return toolsMsg{tools: probeTools()} },
+		func() tea.Msg { return usageMsg{usage: probeUsage()} },
+	)
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case goEnvMsg:
+		p.goEnv, p.goErr = msg.vars, msg.err
+		p.probed()
+	case toolsMsg:
+		p.tools = msg.tools
+		p.probed()
+	case usageMsg:
+		p.usage = msg.usage
+		p.probed()
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+		if h := msg.Height - 10; h > 5 {
+			p.pager.Height = h
+		}
+		p.render()
+	case tea.KeyMsg:
+		p.pager.Update(msg)
+	case types.ActionMsg:
+		if msg.Plugin != p.Name() {
+			return p, nil
+		}
+		switch msg.ID {
+		case "refresh":
+			return p, p.refresh()
+		case "go-env":
+			p.allGoEnv = !p.allGoEnv
+			p.render()
+		case "share":
+			text := p.summary()
+			return p, func() tea.Msg { return types.ShareMsg{From: p.Name(), Text: text} }
+		}
+	}
+	return p, nil
+}
+
+// Actions lists what can be done with the report.
+func (p *Plugin) Actions() []types.Action {
+	return []types.Action{
+		{ID: "refresh", Title: "Refresh", Key: "r"},
+		{ID: "go-env", Title: "Toggle all go env variables", Key: "g"},
+		{ID: "share", Title: "Share summary to chat", Key: "p"},
+	}
+}
+
+func (p *Plugin) probed() {
+	if p.probing > 0 {
+		p.probing--
+	}
+	p.render()
+}
+
+// render rebuilds the report, keeping the scroll position.
+func (p *Plugin) render() {
+	p.pager.SetText(p.report())
+}
+
+func (p *Plugin) report() string {
+	var sb strings.Builder
+	p.writeGoEnv(&sb)
+	p.writeTools(&sb)
+	writeVars(&sb)
+	p.writeTerminal(&sb)
+	p.writeUsage(&sb)
+	return sb.String()
+}
+
+func (p *Plugin) writeGoEnv(sb *strings.Builder) {
+	sb.WriteString("Go toolchain\n")
+	switch {
+	case p.goErr != nil:
+		sb.WriteString("  ❌ " + p.goErr.Error() + "\n")
+	case p.goEnv == nil:
+		sb.WriteString("  Running go env...\n")
+	default:
+		keys := goEnvKeys
+		if p.allGoEnv {
+			keys = make([]string, 0, len(p.goEnv))
+			for k := range p.goEnv {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+		}
+		for _, k := range keys {
+			v, ok := p.goEnv[k]
+			if !ok {
+				continue
+			}
+			if v == "" {
+				v = "(empty)"
+			}
+			sb.WriteString(fmt.Sprintf("  %-12s %s\n", k, mask(k, v)))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func (p *Plugin) writeTools(sb *strings.Builder) {
+	sb.WriteString("Tools\n")
+	if p.tools == nil {
+		sb.WriteString("  Looking for tools...\n\n")
+		return
+	}
+	for _, t := range p.tools {
+		if t.Path == "" {
+			sb.WriteString(fmt.Sprintf("  ❌ %-15s not found (used by %s)\n", t.Name, t.Plugins))
+			if t.Expected != "" {
+				sb.WriteString("     expected at " + t.Expected + "\n")
+			}
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  ✅ %-15s %s (used by %s)\n", t.Name, t.Version, t.Plugins))
+		sb.WriteString("     " + t.Path + "\n")
+		if t.Expected != "" && t.Path != t.Expected {
+			sb.WriteString("     ⚠️  found on PATH, but " + t.Plugins + " runs " + t.Expected + "\n")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func writeVars(sb *strings.Builder) {
+	sb.WriteString("Environment variables\n")
+	for _, v := range probeEnv() {
+		switch {
+		case !v.Set:
+			sb.WriteString(fmt.Sprintf("  %-15s (not set)\n", v.Name))
+		case v.Name == "PATH":
+			sb.WriteString("  PATH\n")
+			for _, dir := range filepath.SplitList(v.Value) {
+				sb.WriteString("    " + dir + "\n")
+			}
+		default:
+			sb.WriteString(fmt.Sprintf("  %-15s %s\n", v.Name, v.Value))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func (p *Plugin) writeTerminal(sb *strings.Builder) {
+	t := probeTerminal(p.width, p.height)
+	sb.WriteString("OS and terminal\n")
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "OS", t.OS))
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Built with", t.Runtime))
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Terminal", t.Term))
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Colors", t.Colors))
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Unicode", t.Unicode))
+	size := "unknown"
+	if t.Width > 0 {
+		size = fmt.Sprintf("%d×%d", t.Width, t.Height)
+	}
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Size", size))
+	sb.WriteString(fmt.Sprintf("  %-12s %s\n", "Input", t.Stdin))
+	sb.WriteString("\n")
+}
+
+func (p *Plugin) writeUsage(sb *strings.Builder) {
+	wd, _ := os.Getwd()
+	sb.WriteString("Disk usage in " + wd + "\n")
+	if p.usage == nil {
+		sb.WriteString("  Measuring...\n")
+		return
+	}
+	for _, u := range p.usage {
+		switch {
+		case u.Missing:
+			sb.WriteString(fmt.Sprintf("  %-28s not present\n", u.Path+"/"))
+			continue
+		case u.Err != nil:
+			sb.WriteString(fmt.Sprintf("  %-28s ❌ %v\n", u.Path+"/", u.Err))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %-28s %10s  %d files\n", u.Path+"/", formatBytes(u.Bytes), u.Files))
+		for _, c := range u.Children {
+			sb.WriteString(fmt.Sprintf("    %-26s %10s  %d files\n", filepath.Base(c.Path)+"/", formatBytes(c.Bytes), c.Files))
+		}
+	}
+}
+
+// summary is the report shared to chat: the versions and capabilities
+// that matter when asking for help, without paths or variables.
+func (p *Plugin) summary() string {
+	var sb strings.Builder
+	t := probeTerminal(p.width, p.height)
+	sb.WriteString("Forger environment\n")
+	if v := p.goEnv["GOVERSION"]; v != "" {
+		sb.WriteString(fmt.Sprintf("go: %s %s/%s\n", v, p.goEnv["GOOS"], p.goEnv["GOARCH"]))
+	}
+	for _, tool := range p.tools {
+		if tool.Name == "go" {
+			continue
+		}
+		version := "not found"
+		if tool.Path != "" {
+			version = tool.Version
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", tool.Name, version))
+	}
+	sb.WriteString(fmt.Sprintf("os: %s\nterminal: %s, %s, unicode %s", t.OS, t.Term, t.Colors, t.Unicode))
+	return sb.String()
+}
+
+func (p *Plugin) View() string {
+	var sb strings.Builder
+	sb.WriteString(p.pager.View() + "\n")
+	if p.probing > 0 {
+		sb.WriteString("Probing...\n")
+	}
+	sb.WriteString("↑/↓ PgUp/PgDn scroll • " + types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())))
+	return sb.String()
+}
@@ -0,0 +1,307 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"forger/internal/runner"
+	"forger/internal/types"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Tool is a program a plugin depends on, and where it was found.
+type Tool struct {
+	Name     string
+	Plugins  string // the plugins that use it
+	Expected string // the path the plugin runs it from, for plugins that don't search PATH
+	Path     string // where it was found; empty when missing
+	Version  string
+}
+
+// toolSpec describes how to find and version a tool.
+type toolSpec struct {
+	name    string
+	plugins string
+	gopath  bool     // the plugin runs it from GOPATH\bin rather than searching PATH
+	version []string // arguments that print its version; nil for Go binaries, read with go version -m
+}
+
+var toolSpecs = []toolSpec{
+	{name: "go", plugins: "codesleuth, gotest", version: []string{"version"}},
+	{name: "git", plugins: "ignoregrets, codesleuth", version: []string{"--version"}},
+	{name: "ignoregrets", plugins: "ignoregrets", gopath: true},
+	{name: "codesleuth", plugins: "codesleuth", gopath: true},
+	{name: "marchat-server", plugins: "marchat", gopath: true},
+}
+
+// probeTools finds each tool and asks it for its version. Go binaries
+// aren't run; their version is read from the build info go embeds.
+func probeTools() []Tool {
+	tools := make([]Tool, len(toolSpecs))
+	for i, spec := range toolSpecs {
+		t := Tool{Name: spec.name, Plugins: spec.plugins}
+		if spec.gopath {
+			t.Expected = runner.GopathTool(spec.name)
+			if _, err := os.Stat(t.Expected); err == nil {
+				t.Path = t.Expected
+			}
+		}
+		if t.Path == "" {
+			t.Path, _ = exec.LookPath(spec.name)
+		}
+		if t.Path != "" {
+			t.Version = toolVersion(t.Path, spec.version)
+		}
+		tools[i] = t
+	}
+	return tools
+}
+
+func toolVersion(path string, args []string) string {
+	if args != nil {
+		res := runner.Run("", path, args...)
+		if res.Err != nil {
+			return "unknown"
+		}
+		return strings.TrimSpace(types.FirstLine(res.Output))
+	}
+	res := runner.Run("", "go", "version", "-m", path)
+	if res.Err != nil {
+		return "unknown"
+	}
+	// The main module's line reads "\tmod\t<module>\t<version>\t<sum>".
+	for _, line := range strings.Split(res.Output, "\n") {
+		if f := strings.Fields(line); len(f) >= 3 && f[0] == "mod" {
+			return f[2]
+		}
+	}
+	return "unknown"
+}
+
+// goEnvKeys are the go env variables shown by default.
+var goEnvKeys = []string{"GOVERSION", "GOOS", "GOARCH", "GOROOT", "GOPATH", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GOFLAGS", "CGO_ENABLED", "GOTOOLCHAIN", "GOMOD", "GOWORK"}
+
+// probeGoEnv runs go env -json.
+func probeGoEnv() (map[string]string, error) {
+	res := runner.Run("", "go", "env", "-json")
+	if res.Err != nil {
+		return nil, fmt.Errorf("go env: %v", res.Err)
+	}
+	vars := make(map[string]string)
+	if err := json.Unmarshal([]byte(res.Output), &vars); err != nil {
+		return nil, fmt.Errorf("go env: %v", err)
+	}
+	return vars, nil
+}
+
+// envKeys are the environment variables that affect Forger and its tools.
+// Variables starting with FORGER_ or MARCHAT_ are shown as well.
+var envKeys = []string{
+	"GOPATH", "GOROOT", "GOBIN", "GOFLAGS", "GOPROXY", "GOPRIVATE", "GOTOOLCHAIN", "CGO_ENABLED",
+	"HOME", "USERPROFILE", "SHELL", "COMSPEC", "EDITOR",
+	"TERM", "COLORTERM", "TERM_PROGRAM", "WT_SESSION", "NO_COLOR", "CLICOLOR_FORCE",
+	"LANG", "LC_ALL", "LC_CTYPE", "PATH",
+}
+
+var envPrefixes = []string{"FORGER_", "MARCHAT_"}
+
+// Var is an environment variable, masked if it may hold a secret.
+type Var struct {
+	Name  string
+	Value string
+	Set   bool
+}
+
+// probeEnv returns the relevant environment variables in display order.
+func probeEnv() []Var {
+	seen := make(map[string]bool)
+	var vars []Var
+	for _, k := range envKeys {
+		v, ok := os.LookupEnv(k)
+		vars = append(vars, Var{Name: k, Value: mask(k, v), Set: ok})
+		seen[k] = true
+	}
+	var extra []Var
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if seen[k] {
+			continue
+		}
+		for _, prefix := range envPrefixes {
+			if strings.HasPrefix(strings.ToUpper(k), prefix) {
+				extra = append(extra, Var{Name: k, Value: mask(k, v), Set: true})
+				break
+			}
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Name < extra[j].Name })
+	return append(vars, extra...)
+}
+
+var (
+	// secretName matches variable names whose values shouldn't be shown,
+	// such as MARCHAT_ADMIN_KEY or GITHUB_TOKEN.
+	secretName = regexp.MustCompile(`(?i)(^|_)(KEY|APIKEY|TOKEN|SECRET|PASSWORD|PASSWD|PASS|CREDENTIALS?|AUTH)($|_)`)
+	// urlPassword matches the password in a URL such as https://user:pw@host.
+	urlPassword = regexp.MustCompile(`(://[^:/@\s]+:)[^@/\s]+@`)
+)
+
+// mask hides the value of a variable that may hold a secret, keeping its
+// length so an empty or truncated value still shows.
+func mask(name, value string) string {
+	if secretName.MatchString(name) && value != "" {
+		return fmt.Sprintf("•••••••• (%d chars)", len(value))
+	}
+	return urlPassword.ReplaceAllString(value, "${1}••••@")
+}
+
+// Terminal describes the OS and terminal Forger is running in.
+type Terminal struct {
+	OS      string
+	Runtime string // the Go version Forger was built with
+	Term    string
+	Colors  string
+	Unicode string
+	Width   int
+	Height  int
+	Stdin   string // whether input is piped into Forger
+}
+
+func probeTerminal(width, height int) Terminal {
+	t := Terminal{
+		OS:      fmt.Sprintf("%s/%s, %d CPUs", runtime.GOOS, runtime.GOARCH, runtime.NumCPU()),
+		Runtime: runtime.Version(),
+		Width:   width,
+		Height:  height,
+	}
+	t.Term = os.Getenv("TERM")
+	if prog := os.Getenv("TERM_PROGRAM"); prog != "" {
+		t.Term += " (" + prog + ")"
+	} else if os.Getenv("WT_SESSION") != "" {
+		t.Term += " (Windows Terminal)"
+	}
+	if strings.TrimSpace(t.Term) == "" {
+		t.Term = "unknown"
+	}
+
+	switch lipgloss.ColorProfile() {
+	case termenv.TrueColor:
+		t.Colors = "truecolor (24-bit)"
+	case termenv.ANSI256:
+		t.Colors = "256 colors"
+	case termenv.ANSI:
+		t.Colors = "16 colors"
+	default:
+		t.Colors = "no color"
+	}
+
+	locale := ""
+	for _, k := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(k); v != "" {
+			locale = v
+			break
+		}
+	}
+	upper := strings.ToUpper(locale)
+	switch {
+	case strings.Contains(upper, "UTF-8") || strings.Contains(upper, "UTF8"):
+		t.Unicode = "yes (" + locale + ")"
+	case os.Getenv("WT_SESSION") != "":
+		t.Unicode = "yes (Windows Terminal)"
+	case locale == "":
+		t.Unicode = "unknown (no locale set)"
+	default:
+		t.Unicode = "no (" + locale + "); box drawing and emoji may not display"
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		t.Stdin = "stdin is piped"
+	} else {
+		t.Stdin = "interactive"
+	}
+	return t
+}
+
+// Usage is the disk space used under a directory.
+type Usage struct {
+	Path     string
+	Missing  bool
+	Err      error
+	Files    int
+	Bytes    int64
+	Children []Usage // immediate subdirectories, largest first
+}
+
+// usageDirs are the directories Forger and its tools write to.
+var usageDirs = []string{".forger", ".ignoregrets"}
+
+func probeUsage() []Usage {
+	usage := make([]Usage, len(usageDirs))
+	for i, dir := range usageDirs {
+		usage[i] = dirUsage(dir)
+	}
+	return usage
+}
+
+// dirUsage totals the files under dir and each of its subdirectories.
+func dirUsage(dir string) Usage {
+	u := Usage{Path: dir}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		u.Missing = os.IsNotExist(err)
+		u.Err = err
+		return u
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			child := walkUsage(filepath.Join(dir, e.Name()))
+			u.Files += child.Files
+			u.Bytes += child.Bytes
+			u.Children = append(u.Children, child)
+		} else if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			u.Files++
+			u.Bytes += info.Size()
+		}
+	}
+	sort.Slice(u.Children, func(i, j int) bool { return u.Children[i].Bytes > u.Children[j].Bytes })
+	return u
+}
+
+// walkUsage totals the files anywhere under dir.
+func walkUsage(dir string) Usage {
+	u := Usage{Path: dir}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				u.Files++
+				u.Bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	return u
+}
+
+// formatBytes writes n in the largest unit that keeps it at least 1.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
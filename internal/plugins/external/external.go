@@ -0,0 +1,320 @@
+// Package external runs plugins as separate executables that speak
+// JSON-RPC 2.0 over stdin and stdout, one JSON object per line. Forger
+// starts the plugin, forwards key presses, terminal size and events to it,
+// and draws the views it sends back. The plugin runs in its own process,
+// so when it crashes, hangs or writes garbage, only its own view shows an
+// error; the rest of Forger carries on.
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logDir holds each external plugin's stderr, relative to the workspace.
+const logDir = ".forger/logs/plugins"
+
+// Plugin is a types.Plugin backed by an external process.
+type Plugin struct {
+	ctx          *types.Context
+	manifest     Manifest
+	proc         *process // nil while not running
+	view         View
+	capabilities []string
+	capturing    bool
+	width        int
+	height       int
+	status       string   // why the plugin isn't running, or a protocol problem
+	stderr       []string // last stderr lines of a process that exited
+}
+
+// New returns a plugin that runs m once initialized.
+func New(ctx *types.Context, m Manifest) *Plugin {
+	return &Plugin{ctx: ctx, manifest: m}
+}
+
+func (p *Plugin) Name() string {
+	return p.manifest.Name
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	return p.start()
+}
+
+// start launches the process and sends initialize.
+func (p *Plugin) start() tea.Cmd {
+	proc, err := startProcess(p.manifest, filepath.Join(logDir, p.manifest.Name+".log"))
+	if err != nil {
+		p.status = err.Error()
+		return types.Notify(p.Name(), types.SeverityError, err.Error())
+	}
+	p.proc = proc
+	p.status = ""
+	p.stderr = nil
+	p.view = View{}
+	proc.Call(MethodInitialize, InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Name:            p.manifest.Name,
+		Config:          p.ctx.PluginConfig[p.manifest.Name],
+		Width:           p.width,
+		Height:          p.height,
+	})
+	return proc.Listen()
+}
+
+// Close asks the process to exit and kills it if it doesn't.
+func (p *Plugin) Close() error {
+	if p.proc != nil {
+		p.proc.Stop()
+		p.proc = nil
+	}
+	return nil
+}
+
+// CapturingInput reports whether the plugin asked for every key.
+func (p *Plugin) CapturingInput() bool {
+	return p.proc != nil && p.capturing
+}
+
+// Actions lists what can be done when the plugin's process has stopped;
+// while it runs, keys go to the process.
+func (p *Plugin) Actions() []types.Action {
+	return []types.Action{
+		{ID: "restart", Title: "Restart", Key: "r", Enabled: func() bool { return p.proc == nil }},
+	}
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case frameMsg:
+		// Every plugin sees every message; only handle our own process.
+		if msg.proc != p.proc {
+			return p, nil
+		}
+		return p, tea.Batch(p.handle(msg.msg), p.proc.Listen())
+	case exitedMsg:
+		if msg.proc != p.proc {
+			return p, nil
+		}
+		p.status = "Plugin exited: " + msg.err.Error()
+		p.stderr = msg.proc.Stderr()
+		p.proc = nil
+		p.capturing = false
+		return p, types.Notify(p.Name(), types.SeverityError, p.status)
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+		p.notify(MethodResize, ResizeParams{Width: msg.Width, Height: msg.Height})
+	case types.OpenRefMsg:
+		if msg.Ref.Plugin == p.Name() {
+			p.notify(MethodEvent, EventParams{Type: "open_ref", Ref: msg.Ref.String()})
+		}
+	case types.ShareMsg:
+		if p.can("events") {
+			p.notify(MethodEvent, EventParams{Type: "share", From: msg.From, Text: msg.Text})
+		}
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() && msg.ID == "restart" {
+			return p, p.start()
+		}
+	case tea.KeyMsg:
+		if p.proc == nil {
+			return p, nil
+		}
+		params := KeyParams{Key: msg.String()}
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			params.Text = string(msg.Runes)
+		}
+		p.notify(MethodKey, params)
+	}
+	return p, nil
+}
+
+// notify sends a notification if the plugin is running.
+func (p *Plugin) notify(method string, params interface{}) {
+	if p.proc == nil {
+		return
+	}
+	if err := p.proc.Notify(method, params); err != nil {
+		p.status = err.Error()
+	}
+}
+
+func (p *Plugin) can(capability string) bool {
+	for _, c := range p.capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// handle acts on one frame from the plugin.
+func (p *Plugin) handle(msg rpcMessage) tea.Cmd {
+	if msg.Method == "" {
+		if msg.ID != nil {
+			p.handleReply(p.proc.replyTo(*msg.ID), msg)
+		}
+		return nil
+	}
+
+	var cmd tea.Cmd
+	var err error
+	switch msg.Method {
+	case MethodRender:
+		var v View
+		if err = json.Unmarshal(msg.Params, &v); err == nil {
+			p.view = v
+		}
+	case MethodNotify:
+		var n NotifyParams
+		if err = json.Unmarshal(msg.Params, &n); err == nil {
+			note := types.NotifyMsg{Source: p.Name(), Severity: parseSeverity(n.Severity), Text: n.Text}
+			if refs := types.ParseRefs(n.Ref); len(refs) > 0 {
+				note.Ref = &refs[0]
+			}
+			cmd = func() tea.Msg { return note }
+		}
+	case MethodShare:
+		var s ShareParams
+		if err = json.Unmarshal(msg.Params, &s); err == nil {
+			share := types.ShareMsg{From: p.Name(), Text: s.Text}
+			cmd = func() tea.Msg { return share }
+		}
+	case MethodOpenRef:
+		var o OpenRefParams
+		if err = json.Unmarshal(msg.Params, &o); err == nil {
+			refs := types.ParseRefs(o.Ref)
+			if len(refs) == 0 {
+				err = fmt.Errorf("not a reference: %q", o.Ref)
+				break
+			}
+			ref := refs[0]
+			cmd = func() tea.Msg { return types.OpenRefMsg{Ref: ref} }
+		}
+	case MethodCapture:
+		var c CaptureParams
+		if err = json.Unmarshal(msg.Params, &c); err == nil {
+			p.capturing = c.Capturing
+		}
+	default:
+		if msg.ID != nil {
+			id := *msg.ID
+			p.proc.send(rpcMessage{ID: &id, Error: &rpcError{Code: -32601, Message: "method not found: " + msg.Method}}, nil)
+		}
+	}
+	if err != nil {
+		p.status = fmt.Sprintf("Bad %s from plugin: %v", msg.Method, err)
+	}
+	return cmd
+}
+
+func (p *Plugin) handleReply(method string, msg rpcMessage) {
+	if msg.Error != nil {
+		p.status = fmt.Sprintf("%s failed: %s", method, msg.Error.Message)
+		return
+	}
+	if method != MethodInitialize {
+		return
+	}
+	var result InitializeResult
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		p.status = "Bad initialize result: " + err.Error()
+		return
+	}
+	p.capabilities = result.Capabilities
+	if result.View != nil {
+		p.view = *result.View
+	}
+}
+
+func parseSeverity(s string) types.Severity {
+	switch strings.ToLower(s) {
+	case "success":
+		return types.SeveritySuccess
+	case "warning":
+		return types.SeverityWarning
+	case "error":
+		return types.SeverityError
+	}
+	return types.SeverityInfo
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	footerStyle   = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+func (p *Plugin) View() (view string) {
+	// A view that breaks our rendering mustn't take Forger down with it.
+	defer func() {
+		if r := recover(); r != nil {
+			view = errorStyle.Render(fmt.Sprintf("%s: could not render view: %v", p.Name(), r))
+		}
+	}()
+
+	if p.proc == nil {
+		return p.stoppedView()
+	}
+	var sb strings.Builder
+	switch {
+	case p.view.Widget != nil:
+		sb.WriteString(renderWidget(*p.view.Widget))
+	case p.view.Text != "":
+		sb.WriteString(sanitize(p.view.Text))
+	default:
+		sb.WriteString("Starting " + p.Name() + "...")
+	}
+	if p.status != "" {
+		sb.WriteString("\n\n" + errorStyle.Render(p.status))
+	}
+	return sb.String()
+}
+
+func (p *Plugin) stoppedView() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(p.Name()) + "\n\n")
+	sb.WriteString(errorStyle.Render(p.status) + "\n")
+	if len(p.stderr) > 0 {
+		sb.WriteString("\nLast output on stderr:\n")
+		for _, line := range p.stderr {
+			sb.WriteString("  " + sanitize(line) + "\n")
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\nLog: %s\n", filepath.Join(logDir, p.Name()+".log")))
+	sb.WriteString(footerStyle.Render(types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions()))))
+	return sb.String()
+}
+
+func renderWidget(w Widget) string {
+	var sb strings.Builder
+	if w.Title != "" {
+		sb.WriteString(titleStyle.Render(sanitize(w.Title)) + "\n\n")
+	}
+	for _, line := range w.Lines {
+		sb.WriteString(sanitize(line) + "\n")
+	}
+	if len(w.Lines) > 0 && len(w.Items) > 0 {
+		sb.WriteString("\n")
+	}
+	for i, item := range w.Items {
+		item = sanitize(strings.ReplaceAll(item, "\n", " "))
+		if i == w.Selected {
+			sb.WriteString(selectedStyle.Render("> "+item) + "\n")
+		} else {
+			sb.WriteString("  " + item + "\n")
+		}
+	}
+	if w.Footer != "" {
+		sb.WriteString("\n" + footerStyle.Render(sanitize(w.Footer)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,373 @@
+package external
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LockFile records installed plugins in the plugins directory. It isn't
+// executable, so discovery ignores it.
+const LockFile = "plugins.lock"
+
+// Lock is the lockfile: what was installed, from where, and the checksum
+// of the installed files, so changes after installation can be detected.
+type Lock struct {
+	Plugins map[string]LockEntry `json:"plugins"`
+}
+
+// LockEntry records one installed plugin.
+type LockEntry struct {
+	Version   string    `json:"version,omitempty"`
+	Source    string    `json:"source"`
+	Commit    string    `json:"commit,omitempty"` // for git sources
+	Path      string    `json:"path,omitempty"`   // installed directory, relative to the plugins directory
+	Checksum  string    `json:"checksum"`         // see Checksum
+	Installed time.Time `json:"installed"`
+}
+
+// LoadLock reads the lockfile in dir; a missing one is empty.
+func LoadLock(dir string) (*Lock, error) {
+	lock := &Lock{Plugins: make(map[string]LockEntry)}
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Join(dir, LockFile), err)
+	}
+	if lock.Plugins == nil {
+		lock.Plugins = make(map[string]LockEntry)
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile to dir.
+func (l *Lock) Save(dir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, LockFile), append(data, '\n'), 0o644)
+}
+
+// Index is a registry index file mapping plugin names to where they can
+// be installed from.
+type Index struct {
+	Plugins map[string]IndexEntry `json:"plugins"`
+}
+
+// IndexEntry is one plugin in an index. A relative Source is resolved
+// against the index file's directory.
+type IndexEntry struct {
+	Source      string `json:"source"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LoadIndex reads a registry index file.
+func LoadIndex(path string) (Index, error) {
+	var index Index
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return index, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, fmt.Errorf("invalid index %s: %v", path, err)
+	}
+	for name, e := range index.Plugins {
+		if e.Source != "" && !IsGitSource(e.Source) && !filepath.IsAbs(e.Source) {
+			e.Source = filepath.Join(filepath.Dir(path), e.Source)
+			index.Plugins[name] = e
+		}
+	}
+	return index, nil
+}
+
+// scpSource matches scp-style git sources such as
+// "git@example.com:me/todo.git". The host must be longer than one
+// character, so Windows paths such as "C:\todo.git" don't match, and
+// "host://" is a URL with a scheme git may not know.
+var scpSource = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?[A-Za-z0-9.-]{2,}:/?[^/\\]`)
+
+// IsGitSource reports whether source is a git URL rather than a local
+// path: one with a URL scheme git understands, or an scp-style
+// "[user@]host:path" ending in ".git". A "#ref" suffix selects a branch or
+// tag. Local repositories are installed from file:// URLs.
+func IsGitSource(source string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	url, _, _ := strings.Cut(source, "#")
+	return scpSource.MatchString(url) && strings.HasSuffix(url, ".git")
+}
+
+// InstallOptions controls Install.
+type InstallOptions struct {
+	Force bool // replace an installed plugin of the same name
+}
+
+// Install installs the plugin at source, a local directory or git URL,
+// into the plugins directory dir and records it in the lockfile.
+func Install(dir, source string, opts InstallOptions) (Manifest, error) {
+	src, commit := source, ""
+	if IsGitSource(source) {
+		tmp, err := os.MkdirTemp("", "forger-plugin-")
+		if err != nil {
+			return Manifest{}, err
+		}
+		defer os.RemoveAll(tmp)
+		// Clone into a directory named after the repository, which
+		// ReadManifest uses when the manifest has no name.
+		src = filepath.Join(tmp, repoName(source))
+		if commit, err = gitClone(source, src); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	m, err := ReadManifest(src)
+	if os.IsNotExist(err) {
+		return m, fmt.Errorf("%s has no %s", source, ManifestFile)
+	}
+	if err != nil {
+		return m, err
+	}
+	if _, err := os.Stat(m.Path()); err != nil {
+		return m, fmt.Errorf("plugin command: %v", err)
+	}
+
+	lock, err := LoadLock(dir)
+	if err != nil {
+		return m, err
+	}
+	target := filepath.Join(dir, m.Name)
+	if _, err := os.Lstat(target); err == nil && !opts.Force {
+		return m, fmt.Errorf("plugin %q is already installed (use --force to replace it)", m.Name)
+	}
+
+	// Copy next to the target and swap it in, so a failed copy never
+	// leaves a half-installed plugin.
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return m, err
+	}
+	staging, err := os.MkdirTemp(dir, ".install-"+m.Name+"-")
+	if err != nil {
+		return m, err
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return m, err
+	}
+	if err := copyTree(src, staging); err != nil {
+		return m, err
+	}
+	sum, err := Checksum(staging)
+	if err != nil {
+		return m, err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return m, err
+	}
+	if err := os.Rename(staging, target); err != nil {
+		return m, err
+	}
+
+	if !IsGitSource(source) {
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+	}
+	lock.Plugins[m.Name] = LockEntry{Version: m.Version, Source: source, Commit: commit, Path: m.Name, Checksum: sum, Installed: time.Now().UTC()}
+	m.Dir = target
+	return m, lock.Save(dir)
+}
+
+// Remove deletes the named plugin from dir and the lockfile. A plugin is
+// removed from where it was found or recorded as installed, which need
+// not be named after it.
+func Remove(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid plugin name %q", name)
+	}
+	lock, err := LoadLock(dir)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	plugins, _ := Discover(dir)
+	for _, m := range plugins {
+		if m.Name != name {
+			continue
+		}
+		if m.Dir == dir {
+			paths = append(paths, m.Path()) // a bare executable
+		} else {
+			paths = append(paths, m.Dir)
+		}
+	}
+	entry, locked := lock.Plugins[name]
+	if locked {
+		// Installed plugins are directories; remove one even if its
+		// manifest no longer reads. Lockfiles from before paths were
+		// recorded installed into a directory named after the plugin.
+		rel := entry.Path
+		if rel == "" {
+			rel = name
+		}
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("%s: install path %q of %q is outside %s", LockFile, entry.Path, name, dir)
+		}
+		paths = append(paths, filepath.Join(dir, rel))
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("plugin %q is not installed", name)
+	}
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	if locked {
+		delete(lock.Plugins, name)
+		return lock.Save(dir)
+	}
+	return nil
+}
+
+// repoName is the last path element of a git source, without any "#ref"
+// or ".git" suffix: "todo" for "git@example.com:me/todo.git#v1". Sources
+// without a usable name get "plugin".
+func repoName(source string) string {
+	url, _, _ := strings.Cut(source, "#")
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if !validName.MatchString(name) {
+		return "plugin"
+	}
+	return name
+}
+
+// gitClone shallow-clones source into dir and returns the commit.
+func gitClone(source, dir string) (string, error) {
+	url, ref, _ := strings.Cut(source, "#")
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	// "--" keeps a URL starting with "-" from being read as an option.
+	args = append(args, "--", url, dir)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git clone %s: %v: %s", url, err, strings.TrimSpace(string(out)))
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// copyTree copies the regular files and directories under src into dst,
+// keeping permissions. The .git directory and symlinks are left out.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Checksum hashes the files under root: each file's slash-separated path,
+// whether it is executable, and its contents, in path order. It is
+// "sha256:" followed by the hex digest.
+func Checksum(root string) (string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%t\x00", filepath.ToSlash(rel), info.Mode().Perm()&0o111 != 0)
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,244 @@
+package external
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	base := map[string]string{"plugin.json": `{"command":"run"}`, "run": "#!/bin/sh\n", "lib/a.txt": "a"}
+	tests := []struct {
+		name   string
+		change func(t *testing.T, dir string)
+		same   bool
+	}{
+		{"unchanged", func(t *testing.T, dir string) {}, true},
+		{"ignores .git", func(t *testing.T, dir string) {
+			writeFiles(t, dir, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+		}, true},
+		{"content", func(t *testing.T, dir string) {
+			writeFiles(t, dir, map[string]string{"lib/a.txt": "b"})
+		}, false},
+		{"new file", func(t *testing.T, dir string) {
+			writeFiles(t, dir, map[string]string{"lib/b.txt": ""})
+		}, false},
+		{"rename", func(t *testing.T, dir string) {
+			if err := os.Rename(filepath.Join(dir, "lib", "a.txt"), filepath.Join(dir, "lib", "c.txt")); err != nil {
+				t.Fatal(err)
+			}
+		}, false},
+		{"executable bit", func(t *testing.T, dir string) {
+			if err := os.Chmod(filepath.Join(dir, "run"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}, false},
+	}
+
+	ref := t.TempDir()
+	writeFiles(t, ref, base)
+	want, err := Checksum(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(want, "sha256:") {
+		t.Fatalf("Checksum = %q, want a sha256: prefix", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, base)
+			tt.change(t, dir)
+			got, err := Checksum(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == want) != tt.same {
+				t.Errorf("Checksum = %s, reference %s, want same=%t", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"https://github.com/me/todo.git", "todo"},
+		{"https://github.com/me/todo.git#v0.2.0", "todo"},
+		{"https://github.com/me/todo/", "todo"},
+		{"git@github.com:me/todo.git", "todo"},
+		{"git@github.com:todo.git", "todo"},
+		{"file:///srv/git/todo-plugin.git", "todo-plugin"},
+		{"ssh://host/", "host"},
+		{"https://example.com/..", "plugin"},
+		{"https://example.com/my.plugin.git", "plugin"},
+	}
+	for _, tt := range tests {
+		if got := repoName(tt.source); got != tt.want {
+			t.Errorf("repoName(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestInstallGitName installs from a git repository whose manifest has no
+// name, which is then taken from the repository URL.
+func TestInstallGitName(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "todo.git")
+	writeFiles(t, repo, map[string]string{"plugin.json": `{"command":"run"}`, "run": "#!/bin/sh\n"})
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+
+	dir := t.TempDir()
+	m, err := Install(dir, "file://"+filepath.ToSlash(repo), InstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "todo" {
+		t.Errorf("Name = %q, want todo", m.Name)
+	}
+	lock, err := LoadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := lock.Plugins["todo"]
+	if !ok {
+		t.Fatalf("lockfile has %v, want todo", lock.Plugins)
+	}
+	if entry.Path != "todo" {
+		t.Errorf("lockfile path %q, want todo", entry.Path)
+	}
+	if sum, _ := Checksum(filepath.Join(dir, "todo")); sum != entry.Checksum {
+		t.Errorf("lockfile checksum %s, installed files %s", entry.Checksum, sum)
+	}
+}
+
+func TestIsGitSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"https://github.com/me/todo.git", true},
+		{"https://github.com/me/todo", true},
+		{"file:///srv/git/todo.git#v1", true},
+		{"git@github.com:me/todo.git", true},
+		{"github.com:me/todo.git#main", true},
+		{"git@example.com:/srv/todo.git", true},
+		{"todo.git", false},
+		{"./plugins/todo.git", false},
+		{"/srv/git/todo.git", false},
+		{`C:\plugins\todo.git`, false},
+		{"C:/plugins/todo.git", false},
+		{"weird://example.com/todo.git", false},
+		{"git@github.com:me/todo", false},
+		{"todo", false},
+	}
+	for _, tt := range tests {
+		if got := IsGitSource(tt.source); got != tt.want {
+			t.Errorf("IsGitSource(%q) = %t, want %t", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestGitCloneOptionURL checks that a source starting with "-" reaches git
+// as the repository to clone, not as an option.
+func TestGitCloneOptionURL(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("LC_ALL", "C")
+	source := "--upload-pack=touch " + filepath.Join(t.TempDir(), "ran")
+	_, err := gitClone(source, filepath.Join(t.TempDir(), "clone"))
+	if err == nil {
+		t.Fatal("clone succeeded")
+	}
+	if want := "repository '" + source + "'"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q doesn't mention %s", err, want)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	t.Run("recorded path", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"todo-v2/plugin.json": `{"name":"todo","command":"run"}`,
+			"todo-v2/run":         "#!/bin/sh\n",
+			"keep/plugin.json":    `{"name":"keep","command":"run"}`,
+			"keep/run":            "#!/bin/sh\n",
+		})
+		lock := &Lock{Plugins: map[string]LockEntry{"todo": {Source: "/src/todo", Path: "todo-v2"}}}
+		if err := lock.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := Remove(dir, "todo"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "todo-v2")); !os.IsNotExist(err) {
+			t.Errorf("todo-v2 not removed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "keep", "run")); err != nil {
+			t.Errorf("other plugin removed: %v", err)
+		}
+		if lock, _ := LoadLock(dir); len(lock.Plugins) != 0 {
+			t.Errorf("lockfile still has %v", lock.Plugins)
+		}
+	})
+
+	t.Run("discovered under another name", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"tools/plugin.json": `{"name":"todo","command":"run"}`,
+			"tools/run":         "#!/bin/sh\n",
+		})
+		if err := Remove(dir, "todo"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "tools")); !os.IsNotExist(err) {
+			t.Errorf("tools not removed: %v", err)
+		}
+	})
+
+	t.Run("path outside the plugins directory", func(t *testing.T) {
+		root := t.TempDir()
+		dir := filepath.Join(root, "plugins")
+		writeFiles(t, root, map[string]string{"precious/file": "keep me"})
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		lock := &Lock{Plugins: map[string]LockEntry{"todo": {Path: "../precious"}}}
+		if err := lock.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := Remove(dir, "todo"); err == nil {
+			t.Error("removed a plugin recorded outside the plugins directory")
+		}
+		if _, err := os.Stat(filepath.Join(root, "precious", "file")); err != nil {
+			t.Errorf("file outside the plugins directory removed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ManifestFile names the manifest in a plugin's directory.
+const ManifestFile = "plugin.json"
+
+// DirEnv overrides the plugins directory.
+const DirEnv = "FORGER_PLUGIN_DIR"
+
+// validName matches plugin names. They appear in forger:// references and
+// log file names, so they are kept to letters, digits, '-' and '_'.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Manifest describes an external plugin. A plugin is either a directory in
+// the plugins directory holding plugin.json, or a bare executable there,
+// whose manifest is derived from its file name.
+type Manifest struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Command      string   `json:"command"` // relative to the plugin's directory unless absolute
+	Args         []string `json:"args,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	Dir string `json:"-"` // directory the command runs in
+}
+
+// Path returns the command to run.
+func (m Manifest) Path() string {
+	if filepath.IsAbs(m.Command) {
+		return m.Command
+	}
+	return filepath.Join(m.Dir, m.Command)
+}
+
+// Dir returns the plugins directory: $FORGER_PLUGIN_DIR if set, otherwise
+// ~/.config/forger/plugins.
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "forger", "plugins"), nil
+}
+
+// ReadManifest loads the manifest of the plugin in dir.
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid %s: %v", filepath.Join(dir, ManifestFile), err)
+	}
+	m.Dir = dir
+	if m.Name == "" {
+		m.Name = filepath.Base(dir)
+	}
+	if !validName.MatchString(m.Name) {
+		return m, fmt.Errorf("%s: invalid plugin name %q", filepath.Join(dir, ManifestFile), m.Name)
+	}
+	if m.Command == "" {
+		return m, fmt.Errorf("%s: no command", filepath.Join(dir, ManifestFile))
+	}
+	return m, nil
+}
+
+// Discover lists the plugins in dir, sorted by name. A missing directory
+// has no plugins; entries that can't be read are reported in errs and
+// skipped.
+func Discover(dir string) (plugins []Manifest, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch {
+		case info.IsDir():
+			m, err := ReadManifest(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			plugins = append(plugins, m)
+		case executable(info):
+			name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+			if runtime.GOOS != "windows" {
+				name = e.Name()
+			}
+			if !validName.MatchString(name) {
+				errs = append(errs, fmt.Errorf("%s: invalid plugin name %q", path, name))
+				continue
+			}
+			plugins = append(plugins, Manifest{Name: name, Command: e.Name(), Dir: dir})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, errs
+}
+
+func executable(info os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
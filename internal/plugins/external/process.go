@@ -0,0 +1,296 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	maxFrame        = 4 * 1024 * 1024 // longest line a plugin may write
+	outgoingBuffer  = 256             // frames queued for a slow plugin
+	shutdownTimeout = 2 * time.Second
+	stderrLines     = 20 // stderr lines kept for the crash view
+)
+
+var errBusy = errors.New("plugin is not reading its input")
+
+// process is one run of a plugin executable. It reads frames from the
+// plugin's stdout and delivers them as Bubble Tea messages through Listen,
+// and writes frames to its stdin from a queue, so a plugin that hangs or
+// floods its output never blocks the TUI.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	out    chan []byte
+	events chan tea.Msg
+	done   chan struct{} // closed once the process has exited
+	quit   chan struct{} // closed by Stop
+	stderr *tailWriter
+	once   sync.Once
+
+	mu     sync.Mutex
+	nextID int64
+	calls  map[int64]string // method of each request awaiting a reply
+	err    error            // why the process exited
+}
+
+// frameMsg is a frame received from a plugin process.
+type frameMsg struct {
+	proc *process
+	msg  rpcMessage
+}
+
+// exitedMsg reports that a plugin process has exited.
+type exitedMsg struct {
+	proc *process
+	err  error
+}
+
+// startProcess launches m's command, logging its stderr to logPath.
+func startProcess(m Manifest, logPath string) (*process, error) {
+	cmd := exec.Command(m.Path(), m.Args...)
+	cmd.Dir = m.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(logFile, "--- starting %s at %s ---\n", m.Path(), time.Now().Format(time.RFC3339))
+	tail := &tailWriter{w: logFile}
+	cmd.Stderr = tail
+
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("failed to start %s: %v", m.Path(), err)
+	}
+
+	p := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		out:    make(chan []byte, outgoingBuffer),
+		events: make(chan tea.Msg, 64),
+		done:   make(chan struct{}),
+		quit:   make(chan struct{}),
+		stderr: tail,
+		calls:  make(map[int64]string),
+	}
+	go p.write()
+	go func() {
+		readErr := p.read(stdout)
+		waitErr := cmd.Wait()
+		logFile.Close()
+		p.mu.Lock()
+		switch {
+		case readErr != nil:
+			p.err = readErr
+		case waitErr != nil:
+			p.err = waitErr
+		default:
+			p.err = errors.New("exited")
+		}
+		p.mu.Unlock()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// read delivers frames until stdout closes. A frame that isn't JSON-RPC
+// is logged and skipped; a frame too long to buffer ends the plugin. Once
+// stopping, frames are read and dropped so the plugin can't block on a
+// full pipe while it exits.
+func (p *process) read(stdout io.Reader) error {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxFrame)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			fmt.Fprintf(p.stderr, "forger: ignoring invalid frame: %v\n", err)
+			continue
+		}
+		select {
+		case p.events <- frameMsg{proc: p, msg: msg}:
+		case <-p.quit:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		p.cmd.Process.Kill()
+		return fmt.Errorf("reading plugin output: %v", err)
+	}
+	return nil
+}
+
+// write sends queued frames. When stopping, it flushes the queue and
+// closes stdin, which tells the plugin no more input is coming.
+func (p *process) write() {
+	defer p.stdin.Close()
+	for {
+		select {
+		case data := <-p.out:
+			if _, err := p.stdin.Write(data); err != nil {
+				return
+			}
+		case <-p.quit:
+			for {
+				select {
+				case data := <-p.out:
+					if _, err := p.stdin.Write(data); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Listen returns a command that waits for the next frame, or for the
+// process to exit.
+func (p *process) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-p.events:
+			return msg
+		case <-p.done:
+			// Deliver anything read before the exit first.
+			select {
+			case msg := <-p.events:
+				return msg
+			default:
+			}
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return exitedMsg{proc: p, err: p.err}
+		}
+	}
+}
+
+// Call sends a request and returns its ID; the reply arrives as a frameMsg.
+func (p *process) Call(method string, params interface{}) (int64, error) {
+	p.mu.Lock()
+	p.nextID++
+	id := p.nextID
+	p.calls[id] = method
+	p.mu.Unlock()
+	return id, p.send(rpcMessage{ID: &id, Method: method}, params)
+}
+
+// Notify sends a notification.
+func (p *process) Notify(method string, params interface{}) error {
+	return p.send(rpcMessage{Method: method}, params)
+}
+
+// replyTo returns the method a response answers and forgets the request.
+func (p *process) replyTo(id int64) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	method := p.calls[id]
+	delete(p.calls, id)
+	return method
+}
+
+func (p *process) send(msg rpcMessage, params interface{}) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-p.done:
+		return errors.New("plugin has exited")
+	default:
+	}
+	select {
+	case p.out <- append(data, '\n'):
+		return nil
+	default:
+		return errBusy
+	}
+}
+
+// Stop asks the plugin to shut down, closes its input and kills it if it
+// hasn't exited within shutdownTimeout.
+func (p *process) Stop() {
+	p.once.Do(func() {
+		p.Call(MethodShutdown, nil)
+		close(p.quit)
+	})
+	select {
+	case <-p.done:
+	case <-time.After(shutdownTimeout):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+// Stderr returns the last lines the plugin wrote to stderr.
+func (p *process) Stderr() []string {
+	return p.stderr.Lines()
+}
+
+// tailWriter writes through to w and remembers the last stderrLines lines.
+type tailWriter struct {
+	w       io.Writer
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+func (t *tailWriter) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	text := t.partial + string(b)
+	parts := strings.Split(text, "\n")
+	t.partial = parts[len(parts)-1]
+	t.lines = append(t.lines, parts[:len(parts)-1]...)
+	if len(t.lines) > stderrLines {
+		t.lines = t.lines[len(t.lines)-stderrLines:]
+	}
+	return t.w.Write(b)
+}
+
+// Lines returns the remembered lines, including an unfinished last line.
+func (t *tailWriter) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string(nil), t.lines...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	return lines
+}
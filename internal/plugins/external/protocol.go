@@ -0,0 +1,169 @@
+package external
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// ProtocolVersion is the version of the external plugin protocol Forger
+// speaks. It is sent in initialize; plugins should refuse versions they
+// don't know.
+const ProtocolVersion = 1
+
+// Methods Forger calls on a plugin.
+const (
+	MethodInitialize = "initialize" // request; the result is an InitializeResult
+	MethodKey        = "key"        // notification with KeyParams
+	MethodResize     = "resize"     // notification with ResizeParams
+	MethodEvent      = "event"      // notification with EventParams
+	MethodShutdown   = "shutdown"   // request; the plugin should reply, then exit
+)
+
+// Methods a plugin calls on Forger, all as notifications.
+const (
+	MethodRender  = "render"   // View: replace what Forger shows for the plugin
+	MethodNotify  = "notify"   // NotifyParams: raise a notification
+	MethodShare   = "share"    // ShareParams: post text to chat
+	MethodOpenRef = "open_ref" // OpenRefParams: follow a forger:// reference
+	MethodCapture = "capture"  // CaptureParams: take over the keyboard
+)
+
+// rpcMessage is a JSON-RPC 2.0 request, notification or response. Frames
+// are single lines of JSON on the plugin's stdin and stdout.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// InitializeParams is sent when the plugin starts.
+type InitializeParams struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Name            string          `json:"name"`
+	Config          json.RawMessage `json:"config,omitempty"` // the plugin's forger.json section
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+}
+
+// InitializeResult is the plugin's reply to initialize.
+type InitializeResult struct {
+	Capabilities []string `json:"capabilities,omitempty"` // e.g. "events"
+	View         *View    `json:"view,omitempty"`         // first view, if ready
+}
+
+// KeyParams is a key press, named as Bubble Tea names them ("a", "enter",
+// "ctrl+c"). Text holds the typed or pasted characters, if any.
+type KeyParams struct {
+	Key  string `json:"key"`
+	Text string `json:"text,omitempty"`
+}
+
+// ResizeParams is the terminal size.
+type ResizeParams struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// EventParams forwards something that happened elsewhere in Forger.
+// Type is "open_ref" (Ref is set; always sent when the ref names this
+// plugin) or "share" (From and Text are set; only sent to plugins with the
+// "events" capability).
+type EventParams struct {
+	Type string `json:"type"`
+	Ref  string `json:"ref,omitempty"`
+	From string `json:"from,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// View is what a plugin asks Forger to draw: preformatted text, which may
+// use ANSI colors, or a widget Forger lays out itself.
+type View struct {
+	Text   string  `json:"text,omitempty"`
+	Widget *Widget `json:"widget,omitempty"`
+}
+
+// Widget is a titled list with optional text above it and a footer.
+type Widget struct {
+	Title    string   `json:"title,omitempty"`
+	Lines    []string `json:"lines,omitempty"`
+	Items    []string `json:"items,omitempty"`
+	Selected int      `json:"selected"` // index into Items; negative for none
+	Footer   string   `json:"footer,omitempty"`
+}
+
+// NotifyParams raises a notification. Severity is info, success, warning
+// or error.
+type NotifyParams struct {
+	Severity string `json:"severity"`
+	Text     string `json:"text"`
+	Ref      string `json:"ref,omitempty"`
+}
+
+// ShareParams posts text to the current chat channel.
+type ShareParams struct {
+	Text string `json:"text"`
+}
+
+// OpenRefParams follows a forger:// reference.
+type OpenRefParams struct {
+	Ref string `json:"ref"`
+}
+
+// CaptureParams turns keyboard capture on or off. While on, Forger's
+// global shortcuts other than ctrl+c are suspended.
+type CaptureParams struct {
+	Capturing bool `json:"capturing"`
+}
+
+var (
+	csiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+	oscPattern = regexp.MustCompile(`\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+	sgrPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// sanitize keeps the colors in plugin output but drops every other escape
+// sequence and control character, so a plugin can't move the cursor, clear
+// the screen or retitle the terminal from under the TUI.
+func sanitize(text string) string {
+	text = oscPattern.ReplaceAllString(text, "")
+	text = csiPattern.ReplaceAllStringFunc(text, func(seq string) string {
+		if sgrPattern.MatchString(seq) {
+			return seq
+		}
+		return ""
+	})
+	var sb strings.Builder
+	last := 0
+	for _, loc := range sgrPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(stripControls(text[last:loc[0]]))
+		sb.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(stripControls(text[last:]))
+	return sb.String()
+}
+
+// stripControls drops control characters other than newlines, and turns
+// tabs, which the layout can't measure, into spaces.
+func stripControls(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t':
+			return ' '
+		case r < 0x20 || r == 0x7f:
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,26 @@
+package external
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "hello\nworld", "hello\nworld"},
+		{"keeps colors", "\x1b[1;31mred\x1b[0m", "\x1b[1;31mred\x1b[0m"},
+		{"drops cursor movement", "a\x1b[2Jb\x1b[10;5Hc\x1b[?25l", "abc"},
+		{"drops title", "\x1b]0;pwned\x07ok\x1b]2;again\x1b\\", "ok"},
+		{"tabs become spaces", "a\tb", "a b"},
+		{"drops controls", "a\rb\x07c\x08d\x7fe", "abcde"},
+		{"lone escape", "a\x1bb", "ab"},
+		{"controls between colors", "\x1b[32mok\r\x1b[0m\x00", "\x1b[32mok\x1b[0m"},
+		{"unicode", "héllo ✓", "héllo ✓"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.in); got != tt.want {
+				t.Errorf("sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
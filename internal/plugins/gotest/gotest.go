@@ -0,0 +1,503 @@
+// Package gotest implements the gotest plugin, a dashboard for go test.
+// It runs go test -json through the shared runner, shows each package and
+// test with its status and duration as results stream in, reruns single
+// tests, and shows a test's output in the shared pager.
+package gotest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"forger/internal/runner"
+	"forger/internal/types"
+	"forger/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultCommand runs every test in the workspace.
+var defaultCommand = []string{"go", "test", "-json", "./..."}
+
+// Config is the "gotest" section of forger.json.
+type Config struct {
+	Command []string `json:"command"` // must write go test -json output; defaults to go test -json ./...
+	Dir     string   `json:"dir"`     // working directory; defaults to the workspace
+}
+
+// row is a line of the tree: a package, or a test within one.
+type row struct {
+	pkg  *pkg
+	test *test
+}
+
+type Plugin struct {
+	ctx          *types.Context
+	cfg          Config
+	tree         *tree
+	stream       *runner.Stream // the run in progress, if any
+	stopping     bool           // the run in progress was stopped with S
+	args         []string       // the command of the last run
+	result       string
+	selected     int
+	offset       int
+	failuresOnly bool
+	height       int
+	pager        *ui.Pager // output of the selected package or test
+}
+
+func New(ctx *types.Context) types.Plugin {
+	p := &Plugin{ctx: ctx, tree: newTree(), height: 20}
+	if err := ctx.LoadPluginConfig("gotest", &p.cfg); err != nil {
+		p.result = "❌ Invalid config: " + err.Error()
+	}
+	if len(p.cfg.Command) == 0 {
+		p.cfg.Command = defaultCommand
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return "gotest"
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	return nil
+}
+
+// Close stops a run in progress.
+func (p *Plugin) Close() error {
+	if p.stream != nil {
+		p.stream.Stop()
+	}
+	return nil
+}
+
+// start runs args, stopping any run in progress. The tree is updated in
+// place, so a rerun of some tests keeps the other results.
+func (p *Plugin) start(args []string) tea.Cmd {
+	if p.stream != nil {
+		p.stream.Stop()
+	}
+	stream, err := runner.Start(p.cfg.Dir, args[0], args[1:]...)
+	if err != nil {
+		p.stream = nil
+		p.result = "❌ " + err.Error()
+		return types.Notify(p.Name(), types.SeverityError, "Can't run tests: "+err.Error())
+	}
+	p.stream, p.args, p.stopping = stream, args, false
+	p.result = "Running " + strings.Join(args, " ") + "..."
+	return listen(stream)
+}
+
+// listen waits for the next output of stream.
+func listen(stream *runner.Stream) tea.Cmd {
+	return func() tea.Msg {
+		lines, ok := stream.Next(200)
+		if !ok {
+			return finishedMsg{stream: stream, result: stream.Result()}
+		}
+		return outputMsg{stream: stream, lines: lines}
+	}
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case outputMsg:
+		// Output of a run that was stopped for another is dropped.
+		if msg.stream != p.stream {
+			return p, nil
+		}
+		for _, line := range msg.lines {
+			p.tree.add(line)
+		}
+		p.moveTo(p.selected)
+		return p, listen(p.stream)
+	case finishedMsg:
+		if msg.stream != p.stream {
+			return p, nil
+		}
+		p.stream = nil
+		p.tree.stopped()
+		p.moveTo(p.selected)
+		return p, p.finished(msg.result)
+	case tea.WindowSizeMsg:
+		if h := msg.Height - 8; h > 5 {
+			p.height = h
+			if p.pager != nil {
+				p.pager.Height = h
+			}
+		}
+		p.moveTo(p.selected)
+		return p, nil
+	case tea.KeyMsg:
+		if p.pager != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				p.pager = nil
+			default:
+				p.pager.Update(msg)
+			}
+			return p, nil
+		}
+		return p, p.updateKeys(msg)
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
+	}
+	return p, nil
+}
+
+// finished reports the outcome of a run.
+func (p *Plugin) finished(res runner.Result) tea.Cmd {
+	counts := p.tree.count()
+	summary := fmt.Sprintf("%d passed, %d failed, %d skipped in %s", counts[statusPass], counts[statusFail], counts[statusSkip], res.Duration.Round(100*time.Millisecond))
+	failedPkgs := 0
+	for _, pkg := range p.tree.pkgs {
+		if pkg.status == statusFail {
+			failedPkgs++
+		}
+	}
+	switch {
+	case p.stopping:
+		p.result = "⏹ Stopped: " + summary
+		return nil
+	case counts[statusFail] > 0 || failedPkgs > 0:
+		p.result = "❌ " + summary
+		return types.Notify(p.Name(), types.SeverityError, "Tests failed: "+summary)
+	case res.Err != nil && len(p.tree.pkgs) == 0:
+		// The command itself failed, e.g. go isn't installed or the
+		// pattern matches no packages.
+		p.result = fmt.Sprintf("❌ %s: %v", strings.Join(res.Args, " "), res.Err)
+		return types.Notify(p.Name(), types.SeverityError, p.result)
+	}
+	p.result = "✅ " + summary
+	return types.Notify(p.Name(), types.SeveritySuccess, "Tests passed: "+summary)
+}
+
+func (p *Plugin) updateKeys(msg tea.KeyMsg) tea.Cmd {
+	rows := p.rows()
+	switch msg.String() {
+	case "up", "k":
+		p.moveTo(p.selected - 1)
+	case "down", "j":
+		p.moveTo(p.selected + 1)
+	case "pgup":
+		p.moveTo(p.selected - p.visibleRows())
+	case "pgdown":
+		p.moveTo(p.selected + p.visibleRows())
+	case "enter":
+		if p.selected < len(rows) {
+			if r := rows[p.selected]; r.test == nil {
+				r.pkg.expanded = !r.pkg.expanded
+				p.moveTo(p.selected)
+			} else {
+				p.showOutput(r)
+			}
+		}
+	}
+	return nil
+}
+
+// Actions lists the run controls and what can be done with the selected
+// row.
+func (p *Plugin) Actions() []types.Action {
+	idle := func() bool { return p.pager == nil }
+	selected := func() bool { return idle() && p.selected < len(p.rows()) }
+	return []types.Action{
+		{ID: "run", Title: "Run the tests", Key: "r", Enabled: idle},
+		{ID: "stop", Title: "Stop the run", Key: "s", Enabled: func() bool { return idle() && p.stream != nil }},
+		{ID: "rerun", Title: "Rerun the selected test or package", Key: "t", Enabled: selected},
+		{ID: "output", Title: "Show output of the selected row", Key: "o", Focus: true, Enabled: selected},
+		{ID: "next-failure", Title: "Next failure", Key: "n", Enabled: selected},
+		{ID: "failures", Title: "Toggle showing failures only", Key: "a", Enabled: idle},
+	}
+}
+
+func (p *Plugin) runAction(id string) tea.Cmd {
+	rows := p.rows()
+	switch id {
+	case "run":
+		p.tree = newTree()
+		p.selected, p.offset = 0, 0
+		return p.start(p.cfg.Command)
+	case "stop":
+		if p.stream != nil {
+			p.stream.Stop()
+			p.stopping = true
+			p.result = "Stopping..."
+		}
+	case "failures":
+		p.failuresOnly = !p.failuresOnly
+		p.selected, p.offset = 0, 0
+	case "next-failure":
+		for i := 1; i <= len(rows); i++ {
+			r := rows[(p.selected+i)%len(rows)]
+			if r.test != nil && r.test.status == statusFail {
+				p.moveTo((p.selected + i) % len(rows))
+				break
+			}
+		}
+	case "output":
+		if p.selected < len(rows) {
+			p.showOutput(rows[p.selected])
+		}
+	case "rerun":
+		if p.selected < len(rows) {
+			return p.rerun(rows[p.selected])
+		}
+	}
+	return nil
+}
+
+// rerun runs just the selected test, or the selected package's tests,
+// with the configured command's flags. Caching is turned off so the test
+// really runs again.
+func (p *Plugin) rerun(r row) tea.Cmd {
+	run := ""
+	if r.test != nil {
+		run = runPattern(r.test.name)
+	} else {
+		// Start the package afresh, in case tests were removed.
+		expanded := r.pkg.expanded
+		p.tree.pkgs[r.pkg.name] = &pkg{name: r.pkg.name, byName: make(map[string]*test), expanded: expanded}
+	}
+	return p.start(rerunArgs(p.cfg.Command, r.pkg.name, run))
+}
+
+// valueFlags are the go test and build flags that take a separate value,
+// which mustn't be mistaken for a package pattern.
+var valueFlags = map[string]bool{
+	"run": true, "skip": true, "count": true, "timeout": true, "parallel": true, "cpu": true,
+	"bench": true, "benchtime": true, "fuzz": true, "fuzztime": true, "list": true, "shuffle": true,
+	"p": true, "tags": true, "mod": true, "modfile": true, "overlay": true, "pkgdir": true,
+	"ldflags": true, "gcflags": true, "asmflags": true, "exec": true, "o": true, "C": true, "vet": true,
+	"coverprofile": true, "covermode": true, "coverpkg": true, "outputdir": true, "trace": true,
+	"cpuprofile": true, "memprofile": true, "blockprofile": true, "mutexprofile": true,
+}
+
+// rerunArgs rebuilds command to run pkg: its package patterns are
+// replaced by pkg, -count=1 is added, and so is -run when run is set.
+// Flags after "test" are kept, as is anything from -args on.
+func rerunArgs(command []string, pkg, run string) []string {
+	start := 1
+	for i, arg := range command {
+		if arg == "test" {
+			start = i + 1
+			break
+		}
+	}
+	args := append([]string(nil), command[:start]...)
+	var tail []string
+	for i := start; i < len(command); i++ {
+		arg := command[i]
+		if arg == "-args" || arg == "--args" {
+			tail = command[i:]
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue // a package pattern
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesNext := valueFlags[name] && !hasValue && i+1 < len(command)
+		if name == "count" || (name == "run" && run != "") {
+			if takesNext {
+				i++
+			}
+			continue
+		}
+		args = append(args, arg)
+		if takesNext {
+			i++
+			args = append(args, command[i])
+		}
+	}
+	args = append(args, "-count=1")
+	if run != "" {
+		args = append(args, "-run", run)
+	}
+	args = append(args, pkg)
+	return append(args, tail...)
+}
+
+// runPattern matches exactly the test called name, which may be a
+// subtest: -run splits its pattern on slashes the same way.
+func runPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+func (p *Plugin) showOutput(r row) {
+	var title, text string
+	if r.test != nil {
+		title = fmt.Sprintf("%s %s (%s)", r.test.status.icon(), r.test.name, r.pkg.name)
+		// Include subtests, where a failure's details usually are.
+		for _, t := range r.pkg.tests {
+			if t == r.test || strings.HasPrefix(t.name, r.test.name+"/") {
+				text += strings.Join(t.output, "")
+			}
+		}
+	} else {
+		title = fmt.Sprintf("%s %s", r.pkg.status.icon(), r.pkg.name)
+		text = strings.Join(r.pkg.output, "")
+		if r.pkg.status == statusFail && len(p.tree.stray) > 0 {
+			// Build errors aren't attributed to a package.
+			text += "\nOther output:\n" + strings.Join(p.tree.stray, "")
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		text = "(no output)"
+	}
+	p.pager = ui.NewPager(title, text)
+	p.pager.Height = p.height
+	p.pager.Update(tea.KeyMsg{Type: tea.KeyEnd})
+}
+
+// rows lists the tree as shown: packages, and the tests of expanded ones.
+func (p *Plugin) rows() []row {
+	var rows []row
+	for _, pkg := range p.tree.sorted() {
+		if p.failuresOnly && pkg.status != statusFail {
+			continue
+		}
+		rows = append(rows, row{pkg: pkg})
+		if !pkg.expanded {
+			continue
+		}
+		for _, t := range pkg.tests {
+			if p.failuresOnly && t.status != statusFail {
+				continue
+			}
+			rows = append(rows, row{pkg: pkg, test: t})
+		}
+	}
+	return rows
+}
+
+// visibleRows is how many rows fit under the header.
+func (p *Plugin) visibleRows() int {
+	return max(1, p.height-4)
+}
+
+func (p *Plugin) moveTo(i int) {
+	n := len(p.rows())
+	p.selected = max(0, min(i, n-1))
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+p.visibleRows() {
+		p.offset = p.selected - p.visibleRows() + 1
+	}
+	p.offset = max(0, min(p.offset, n-p.visibleRows()))
+}
+
+func (p *Plugin) View() string {
+	if p.pager != nil {
+		return p.pager.View() + "\n↑/↓ PgUp/PgDn scroll • Esc back"
+	}
+	rows := p.rows()
+	if len(rows) == 0 && p.stream == nil {
+		return p.emptyView()
+	}
+
+	var sb strings.Builder
+	counts := p.tree.count()
+	state := "finished"
+	if p.stream != nil {
+		state = "running"
+	}
+	sb.WriteString(fmt.Sprintf("Go Tests ─ $ %s • %s • ✅ %d ❌ %d ⏭ %d\n",
+		strings.Join(p.args, " "), state, counts[statusPass], counts[statusFail], counts[statusSkip]))
+	if p.failuresOnly {
+		sb.WriteString("Showing failures only (A to show all)\n")
+	} else {
+		sb.WriteString("\n")
+	}
+
+	if len(rows) == 0 {
+		sb.WriteString("  Building...\n")
+		if n := len(p.tree.stray); n > 0 {
+			sb.WriteString("  " + strings.TrimSpace(p.tree.stray[n-1]) + "\n")
+		}
+	}
+	end := min(p.offset+p.visibleRows(), len(rows))
+	for i := p.offset; i < end; i++ {
+		prefix := "  "
+		if i == p.selected {
+			prefix = "> "
+		}
+		sb.WriteString(prefix + formatRow(rows[i]) + "\n")
+	}
+	if p.result != "" {
+		sb.WriteString(p.result + "\n")
+	}
+	sb.WriteString("Enter expand/output • " + types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())))
+	return sb.String()
+}
+
+func formatRow(r row) string {
+	if r.test == nil {
+		arrow := "▸"
+		if r.pkg.expanded {
+			arrow = "▾"
+		}
+		line := fmt.Sprintf("%s %s %s", arrow, r.pkg.status.icon(), r.pkg.name)
+		switch {
+		case r.pkg.status == statusSkip && len(r.pkg.tests) == 0:
+			line += "  (no test files)"
+		case r.pkg.status != statusRunning:
+			line += fmt.Sprintf("  %.2fs", r.pkg.elapsed)
+		}
+		return line
+	}
+	name := r.test.name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	line := fmt.Sprintf("%s%s %s", strings.Repeat("  ", r.test.depth()+2), r.test.status.icon(), name)
+	if r.test.status != statusRunning {
+		line += fmt.Sprintf("  %.2fs", r.test.elapsed)
+	}
+	return line
+}
+
+func (p *Plugin) emptyView() string {
+	var sb strings.Builder
+	sb.WriteString("┌─ Go Tests ─────────────────────────────────────────────────┐\n")
+	sb.WriteString("│                                                             │\n")
+	command := strings.Join(p.cfg.Command, " ")
+	if r := []rune(command); len(r) > 56 {
+		command = string(r[:53]) + "..."
+	}
+	sb.WriteString(fmt.Sprintf("│  $ %-56s │\n", command))
+	sb.WriteString("│                                                             │\n")
+	if p.result != "" {
+		sb.WriteString(fmt.Sprintf("│  %-58s │\n", types.FirstLine(p.result)))
+		sb.WriteString("│                                                             │\n")
+	}
+	sb.WriteString("│  Commands:                                                  │\n")
+	for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+		sb.WriteString(fmt.Sprintf("│  • %-56s │\n", line))
+	}
+	sb.WriteString("│  • ↑/↓: Select  • Enter: Expand a package or show output    │\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
+
+// outputMsg carries lines of output from a test run.
+type outputMsg struct {
+	stream *runner.Stream
+	lines  []string
+}
+
+// finishedMsg reports that a test run has ended.
+type finishedMsg struct {
+	stream *runner.Stream
+	result runner.Result
+}
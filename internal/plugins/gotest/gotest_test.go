@@ -0,0 +1,42 @@
+package gotest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRerunArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		run     string
+		want    string
+	}{
+		{"go test -json ./...", "", "go test -json -count=1 p"},
+		{"go test -json ./...", "^TestA$", "go test -json -count=1 -run ^TestA$ p"},
+		{"go test -json -race -tags integration ./internal/... ./cmd/...", "^TestA$", "go test -json -race -tags integration -count=1 -run ^TestA$ p"},
+		{"go test -json -count 3 -run Slow ./...", "^TestA$", "go test -json -count=1 -run ^TestA$ p"},
+		{"go test -json -run=Slow -count=2 ./...", "", "go test -json -run=Slow -count=1 p"},
+		{"go -C sub test -json -timeout 2m ./... -args -update", "^TestA$", "go -C sub test -json -timeout 2m -count=1 -run ^TestA$ p -args -update"},
+		{"gotestsum-json -v ./...", "", "gotestsum-json -v -count=1 p"},
+	}
+	for _, tt := range tests {
+		got := rerunArgs(strings.Fields(tt.command), "p", tt.run)
+		if want := strings.Fields(tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("rerunArgs(%q, %q) = %q, want %q", tt.command, tt.run, got, want)
+		}
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	tests := map[string]string{
+		"TestA":            "^TestA$",
+		"TestA/sub_case":   "^TestA$/^sub_case$",
+		"TestA/a.b(c)+[d]": `^TestA$/^a\.b\(c\)\+\[d\]$`,
+	}
+	for name, want := range tests {
+		if got := runPattern(name); got != want {
+			t.Errorf("runPattern(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package gotest
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// event is one line of go test -json output, as written by test2json.
+type event struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// status is the state of a package or test.
+type status int
+
+const (
+	statusRunning status = iota
+	statusPass
+	statusFail
+	statusSkip
+	statusStopped // the run ended before it finished
+)
+
+func (s status) icon() string {
+	switch s {
+	case statusPass:
+		return "✅"
+	case statusFail:
+		return "❌"
+	case statusSkip:
+		return "⏭ "
+	case statusStopped:
+		return "⏹ "
+	}
+	return "⏳"
+}
+
+// test is a test or subtest and what it printed.
+type test struct {
+	name    string // full name, such as TestParse/empty
+	status  status
+	elapsed float64
+	output  []string
+}
+
+// depth is how deeply a subtest is nested.
+func (t *test) depth() int {
+	return strings.Count(t.name, "/")
+}
+
+// pkg is a package and its tests, in the order they started.
+type pkg struct {
+	name     string
+	status   status
+	elapsed  float64
+	output   []string // output not belonging to a test, such as build errors
+	tests    []*test
+	byName   map[string]*test
+	expanded bool
+}
+
+func (p *pkg) failed() bool {
+	for _, t := range p.tests {
+		if t.status == statusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// tree is the results of a run, kept up to date as events arrive. A rerun
+// of some tests updates the tree in place.
+type tree struct {
+	pkgs  map[string]*pkg
+	stray []string // output that isn't test2json, such as build errors
+}
+
+func newTree() *tree {
+	return &tree{pkgs: make(map[string]*pkg)}
+}
+
+// sorted returns the packages in name order.
+func (t *tree) sorted() []*pkg {
+	pkgs := make([]*pkg, 0, len(t.pkgs))
+	for _, p := range t.pkgs {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].name < pkgs[j].name })
+	return pkgs
+}
+
+func (t *tree) pkg(name string) *pkg {
+	p, ok := t.pkgs[name]
+	if !ok {
+		p = &pkg{name: name, byName: make(map[string]*test)}
+		t.pkgs[name] = p
+	}
+	return p
+}
+
+// add applies one line of output.
+func (t *tree) add(line string) {
+	var ev event
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil || ev.Action == "" {
+		t.stray = append(t.stray, line+"\n")
+		return
+	}
+	if ev.Package == "" {
+		t.stray = append(t.stray, ev.Output)
+		return
+	}
+	p := t.pkg(ev.Package)
+	if ev.Test == "" {
+		switch ev.Action {
+		case "start":
+			p.status = statusRunning
+		case "output":
+			p.output = append(p.output, ev.Output)
+		case "pass", "fail", "skip":
+			p.status, p.elapsed = finished(ev.Action), ev.Elapsed
+			// A rerun of one test passing doesn't clear the others.
+			if p.status == statusPass && p.failed() {
+				p.status = statusFail
+			}
+			if p.status == statusFail {
+				p.expanded = true
+			}
+		}
+		return
+	}
+
+	tt, ok := p.byName[ev.Test]
+	if !ok {
+		tt = &test{name: ev.Test}
+		p.byName[ev.Test] = tt
+		p.tests = append(p.tests, tt)
+	}
+	switch ev.Action {
+	case "run":
+		// Starting again in a rerun: forget the last result.
+		tt.status, tt.elapsed, tt.output = statusRunning, 0, nil
+		p.status = statusRunning
+	case "output":
+		tt.output = append(tt.output, ev.Output)
+	case "pass", "fail", "skip":
+		tt.status, tt.elapsed = finished(ev.Action), ev.Elapsed
+	}
+}
+
+func finished(action string) status {
+	switch action {
+	case "pass":
+		return statusPass
+	case "fail":
+		return statusFail
+	}
+	return statusSkip
+}
+
+// count returns how many tests, not counting subtests, have each status.
+func (t *tree) count() map[status]int {
+	counts := make(map[status]int)
+	for _, p := range t.pkgs {
+		for _, tt := range p.tests {
+			if tt.depth() == 0 {
+				counts[tt.status]++
+			}
+		}
+	}
+	return counts
+}
+
+// stopped marks everything still running as stopped, once the command
+// has exited.
+func (t *tree) stopped() {
+	for _, p := range t.pkgs {
+		for _, tt := range p.tests {
+			if tt.status == statusRunning {
+				tt.status = statusStopped
+			}
+		}
+		if p.status == statusRunning {
+			p.status = statusStopped
+		}
+	}
+}
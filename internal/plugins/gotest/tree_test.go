@@ -0,0 +1,117 @@
+package gotest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTreeAdd(t *testing.T) {
+	type want struct {
+		pkg    status
+		tests  map[string]status
+		output map[string]string // test name to its output
+		stray  int
+	}
+	tests := []struct {
+		name  string
+		lines []string
+		want  want
+	}{
+		{
+			name: "pass",
+			lines: []string{
+				`{"Action":"start","Package":"p"}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"output","Package":"p","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.1}`,
+				`{"Action":"pass","Package":"p","Elapsed":0.2}`,
+			},
+			want: want{pkg: statusPass, tests: map[string]status{"TestA": statusPass}, output: map[string]string{"TestA": "=== RUN   TestA\n"}},
+		},
+		{
+			name: "subtest failure fails the package",
+			lines: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"run","Package":"p","Test":"TestA/case"}`,
+				`{"Action":"fail","Package":"p","Test":"TestA/case"}`,
+				`{"Action":"fail","Package":"p","Test":"TestA"}`,
+				`{"Action":"skip","Package":"p","Test":"TestB"}`,
+				`{"Action":"fail","Package":"p"}`,
+			},
+			want: want{pkg: statusFail, tests: map[string]status{"TestA": statusFail, "TestA/case": statusFail, "TestB": statusSkip}},
+		},
+		{
+			name: "rerun forgets the last result",
+			lines: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"output","Package":"p","Test":"TestA","Output":"boom\n"}`,
+				`{"Action":"fail","Package":"p","Test":"TestA"}`,
+				`{"Action":"fail","Package":"p"}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p"}`,
+			},
+			want: want{pkg: statusPass, tests: map[string]status{"TestA": statusPass}, output: map[string]string{"TestA": ""}},
+		},
+		{
+			name: "rerun of one test keeps another failure",
+			lines: []string{
+				`{"Action":"fail","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestB"}`,
+				`{"Action":"fail","Package":"p"}`,
+				`{"Action":"run","Package":"p","Test":"TestB"}`,
+				`{"Action":"pass","Package":"p","Test":"TestB"}`,
+				`{"Action":"pass","Package":"p"}`,
+			},
+			want: want{pkg: statusFail, tests: map[string]status{"TestA": statusFail, "TestB": statusPass}},
+		},
+		{
+			name: "build errors and other output are stray",
+			lines: []string{
+				`# p`,
+				`./x.go:3:1: syntax error`,
+				`{"Action":"output","Output":"FAIL\tp [build failed]\n"}`,
+				`{"Output":"no action"}`,
+				`{"Action":"fail","Package":"p"}`,
+			},
+			want: want{pkg: statusFail, tests: map[string]status{}, stray: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTree()
+			for _, line := range tt.lines {
+				tr.add(line)
+			}
+			p := tr.pkgs["p"]
+			if p == nil {
+				t.Fatal("package p missing")
+			}
+			if p.status != tt.want.pkg {
+				t.Errorf("package status = %d, want %d", p.status, tt.want.pkg)
+			}
+			if p.status == statusFail && !p.expanded {
+				t.Error("failed package not expanded")
+			}
+			got := make(map[string]status)
+			for _, test := range p.tests {
+				got[test.name] = test.status
+			}
+			if !reflect.DeepEqual(got, tt.want.tests) {
+				t.Errorf("tests = %v, want %v", got, tt.want.tests)
+			}
+			for name, output := range tt.want.output {
+				var text string
+				for _, s := range p.byName[name].output {
+					text += s
+				}
+				if text != output {
+					t.Errorf("%s output = %q, want %q", name, text, output)
+				}
+			}
+			if len(tr.stray) != tt.want.stray {
+				t.Errorf("stray = %q, want %d lines", tr.stray, tt.want.stray)
+			}
+		})
+	}
+}
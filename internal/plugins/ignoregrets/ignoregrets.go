@@ -2,11 +2,10 @@ package ignoregrets
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
+	"forger/internal/runner"
 	"forger/internal/types"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -20,14 +19,22 @@ type Plugin struct {
 	output        string
 	errorMsg      string
 	status        string
-	result        string // Add result field for command feedback
+	result        string           // Add result field for command feedback
+	preview       CommandResultMsg // latest restore preview, shared with its snapshot
+	known         map[string]bool  // snapshot commits seen so far; nil before the first list
+	creating      bool             // a snapshot we asked for is on its way
 }
 
+// pollInterval is how often the snapshot list is refreshed to notice
+// snapshots created outside Forger, such as by git hooks.
+const pollInterval = 15 * time.Second
+
 type Snapshot struct {
 	Commit    string    `json:"commit"`
 	Timestamp time.Time `json:"timestamp"`
 	Index     int       `json:"index"`
 	FileCount int       `json:"file_count"`
+	Note      string    `json:"note,omitempty"`
 }
 
 func New(ctx *types.Context) types.Plugin {
@@ -43,9 +50,8 @@ func (p *Plugin) Init() tea.Cmd {
 }
 
 func (p *Plugin) checkAvailability() tea.Msg {
-	ignoregretsPath := os.Getenv("GOPATH") + "\\bin\\ignoregrets.exe"
-	cmd := exec.Command(ignoregretsPath, "--help")
-	if err := cmd.Run(); err != nil {
+	ignoregretsPath := runner.GopathTool("ignoregrets")
+	if res := runner.Run("", ignoregretsPath, "--help"); res.Err != nil {
 		return AvailabilityMsg{Available: false, Error: "ignoregrets not found"}
 	}
 	return AvailabilityMsg{Available: true}
@@ -56,13 +62,22 @@ func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
 	case AvailabilityMsg:
 		p.available = msg.Available
 		if msg.Available {
-			return p, p.listSnapshots
+			return p, tea.Batch(p.listSnapshots, p.poll())
 		}
 		return p, nil
+	case pollMsg:
+		return p, tea.Batch(p.listSnapshots, p.poll())
 	case SnapshotsMsg:
 		p.snapshots = msg.Snapshots
-		return p, nil
+		if p.selectedIndex >= len(p.snapshots) {
+			p.selectedIndex = 0
+		}
+		p.shareSelection()
+		return p, p.noticeNewSnapshots()
 	case CommandResultMsg:
+		if msg.Commit != "" {
+			p.preview = msg
+		}
 		// Display command results
 		if msg.Success {
 			p.result = "✅ " + msg.Output
@@ -70,44 +85,182 @@ func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
 			p.result = "❌ " + msg.Output
 		}
 		// Clear result after 3 seconds (in a real app, you'd use a timer)
+		if !msg.Success {
+			p.creating = false
+			return p, types.Notify(p.Name(), types.SeverityError, types.FirstLine(msg.Output))
+		}
+		if p.creating {
+			// The refresh reports the new snapshot.
+			return p, p.listSnapshots
+		}
 		return p, nil
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "up":
 			if p.selectedIndex > 0 {
 				p.selectedIndex--
+				p.shareSelection()
 			}
 		case "down":
 			if p.selectedIndex < len(p.snapshots)-1 {
 				p.selectedIndex++
+				p.shareSelection()
 			}
 		case "enter":
-			if len(p.snapshots) > 0 && p.selectedIndex < len(p.snapshots) {
-				return p, func() tea.Msg { return p.restoreSnapshot(p.snapshots[p.selectedIndex]) }
-			}
-		case "s":
-			return p, p.createSnapshot
-		case "r":
-			if len(p.snapshots) > 0 {
-				return p, func() tea.Msg { return p.restoreSnapshot(p.snapshots[p.selectedIndex]) }
-			}
-		case "l":
-			return p, p.listSnapshots
-		case "d":
-			if len(p.snapshots) > 0 {
-				return p, func() tea.Msg { return p.deleteSnapshot(p.snapshots[p.selectedIndex]) }
+			if p.hasSelection() {
+				return p, p.runAction("restore")
 			}
 		case "ctrl+c":
 			return p, tea.Quit
 		}
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
+	case types.OpenRefMsg:
+		if msg.Ref.Plugin != p.Name() {
+			return p, nil
+		}
+		for i, s := range p.snapshots {
+			if strings.HasPrefix(s.Commit, msg.Ref.Target) || strings.HasPrefix(msg.Ref.Target, s.Commit) {
+				p.selectedIndex = i
+				p.shareSelection()
+				p.result = "Opened shared snapshot " + shortCommit(s.Commit)
+				return p, nil
+			}
+		}
+		p.result = "❌ Shared snapshot " + shortCommit(msg.Ref.Target) + " not found; press L to refresh"
 	}
 	return p, nil
 }
 
+// Actions lists the snapshot commands.
+func (p *Plugin) Actions() []types.Action {
+	return []types.Action{
+		{ID: "create", Title: "Create snapshot", Key: "s"},
+		{ID: "restore", Title: "Restore snapshot (preview)", Key: "r", Enabled: p.hasSelection},
+		{ID: "delete", Title: "Delete old snapshots", Key: "d", Enabled: p.hasSelection},
+		{ID: "refresh", Title: "Refresh list", Key: "l"},
+		{ID: "share", Title: "Share snapshot to chat", Key: "p", Enabled: p.hasSelection},
+	}
+}
+
+func (p *Plugin) hasSelection() bool {
+	return p.selectedIndex < len(p.snapshots)
+}
+
+// runAction runs the action with id on the selected snapshot.
+func (p *Plugin) runAction(id string) tea.Cmd {
+	if id != "create" && id != "refresh" && !p.hasSelection() {
+		return nil
+	}
+	switch id {
+	case "create":
+		p.creating = true
+		return p.createSnapshot
+	case "refresh":
+		return p.listSnapshots
+	case "restore":
+		s := p.snapshots[p.selectedIndex]
+		return func() tea.Msg { return p.restoreSnapshot(s) }
+	case "delete":
+		s := p.snapshots[p.selectedIndex]
+		return func() tea.Msg { return p.deleteSnapshot(s) }
+	case "share":
+		return p.share(p.snapshots[p.selectedIndex])
+	}
+	return nil
+}
+
+// share posts a summary of snapshot to chat, with an excerpt of its
+// restore preview if one has been run.
+func (p *Plugin) share(s Snapshot) tea.Cmd {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📸 Snapshot %s (%d files, %s)", shortCommit(s.Commit), s.FileCount, s.Timestamp.Format("2006-01-02 15:04")))
+	if s.Note != "" {
+		sb.WriteString(": " + s.Note)
+	}
+	if p.preview.Commit == s.Commit && p.preview.Success {
+		sb.WriteString("\n" + types.Excerpt(p.preview.Output, 8))
+	}
+	sb.WriteString("\n" + types.Ref{Plugin: p.Name(), Target: s.Commit}.String())
+	text := sb.String()
+	p.result = "✅ Shared snapshot " + shortCommit(s.Commit) + " to chat"
+	return func() tea.Msg { return types.ShareMsg{From: p.Name(), Text: text} }
+}
+
+func (p *Plugin) poll() tea.Cmd {
+	return tea.Tick(pollInterval, func(time.Time) tea.Msg { return pollMsg{} })
+}
+
+// noticeNewSnapshots raises a notification for snapshots that appeared
+// since the last list without Forger creating them.
+func (p *Plugin) noticeNewSnapshots() tea.Cmd {
+	first := p.known == nil
+	known := make(map[string]bool, len(p.snapshots))
+	var cmds []tea.Cmd
+	for _, s := range p.snapshots {
+		known[s.Commit] = true
+		if first || p.known[s.Commit] {
+			continue
+		}
+		severity, text := types.SeverityInfo, "Snapshot "+shortCommit(s.Commit)+" created outside Forger (hook)"
+		if p.creating {
+			severity, text = types.SeveritySuccess, "Snapshot "+shortCommit(s.Commit)+" created"
+		}
+		ref := types.Ref{Plugin: p.Name(), Target: s.Commit}
+		cmds = append(cmds, func() tea.Msg {
+			return types.NotifyMsg{Source: p.Name(), Severity: severity, Text: text, Ref: &ref}
+		})
+	}
+	if len(cmds) > 0 {
+		p.creating = false
+	}
+	p.known = known
+	return tea.Batch(cmds...)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// shareSelection publishes the selected snapshot so other plugins, such as
+// codesleuth, can operate on it.
+func (p *Plugin) shareSelection() {
+	if p.selectedIndex < len(p.snapshots) {
+		p.ctx.GlobalState[types.StateSnapshot] = p.snapshots[p.selectedIndex].Commit
+	} else {
+		delete(p.ctx.GlobalState, types.StateSnapshot)
+	}
+}
+
+// SearchItems offers the snapshots to the fuzzy finder, matched by commit
+// and note.
+func (p *Plugin) SearchItems() []types.SearchItem {
+	items := make([]types.SearchItem, 0, len(p.snapshots))
+	for _, s := range p.snapshots {
+		title := shortCommit(s.Commit)
+		if s.Note != "" {
+			title += " " + s.Note
+		}
+		items = append(items, types.SearchItem{
+			Plugin: p.Name(),
+			Kind:   "snapshot",
+			Title:  title,
+			Detail: fmt.Sprintf("%s, %d files", s.Timestamp.Format("2006-01-02 15:04"), s.FileCount),
+			Ref:    &types.Ref{Plugin: p.Name(), Target: s.Commit},
+		})
+	}
+	return items
+}
+
 func (p *Plugin) createSnapshot() tea.Msg {
-	ignoregretsPath := os.Getenv("GOPATH") + "\\bin\\ignoregrets.exe"
-	cmd := exec.Command(ignoregretsPath, "snapshot")
-	output, err := cmd.CombinedOutput()
+	ignoregretsPath := runner.GopathTool("ignoregrets")
+	res := runner.Run("", ignoregretsPath, "snapshot")
+	output, err := res.Output, res.Err
 	if err != nil {
 		return CommandResultMsg{
 			Success: false,
@@ -121,22 +274,21 @@ func (p *Plugin) createSnapshot() tea.Msg {
 }
 
 func (p *Plugin) listSnapshots() tea.Msg {
-	ignoregretsPath := os.Getenv("GOPATH") + "\\bin\\ignoregrets.exe"
-	cmd := exec.Command(ignoregretsPath, "list")
-	output, err := cmd.Output()
-	if err != nil {
+	ignoregretsPath := runner.GopathTool("ignoregrets")
+	res := runner.Run("", ignoregretsPath, "list")
+	if res.Err != nil {
 		return SnapshotsMsg{Snapshots: []Snapshot{}}
 	}
 
 	// Parse the output to extract snapshots
-	snapshots := p.parseSnapshots(string(output))
+	snapshots := p.parseSnapshots(res.Output)
 	return SnapshotsMsg{Snapshots: snapshots}
 }
 
 func (p *Plugin) restoreSnapshot(snapshot Snapshot) tea.Msg {
-	ignoregretsPath := os.Getenv("GOPATH") + "\\bin\\ignoregrets.exe"
-	cmd := exec.Command(ignoregretsPath, "restore", "--dry-run")
-	output, err := cmd.CombinedOutput()
+	ignoregretsPath := runner.GopathTool("ignoregrets")
+	res := runner.Run("", ignoregretsPath, "restore", "--dry-run")
+	output, err := res.Output, res.Err
 	if err != nil {
 		return CommandResultMsg{
 			Success: false,
@@ -146,13 +298,14 @@ func (p *Plugin) restoreSnapshot(snapshot Snapshot) tea.Msg {
 	return CommandResultMsg{
 		Success: true,
 		Output:  fmt.Sprintf("Restore preview for %s:\n%s", snapshot.Commit[:8], output),
+		Commit:  snapshot.Commit,
 	}
 }
 
 func (p *Plugin) deleteSnapshot(snapshot Snapshot) tea.Msg {
-	ignoregretsPath := os.Getenv("GOPATH") + "\\bin\\ignoregrets.exe"
-	cmd := exec.Command(ignoregretsPath, "prune", "--retention", "0")
-	output, err := cmd.CombinedOutput()
+	ignoregretsPath := runner.GopathTool("ignoregrets")
+	res := runner.Run("", ignoregretsPath, "prune", "--retention", "0")
+	output, err := res.Output, res.Err
 	if err != nil {
 		return CommandResultMsg{
 			Success: false,
@@ -170,6 +323,10 @@ func (p *Plugin) parseSnapshots(output string) []Snapshot {
 	lines := strings.Split(output, "\n")
 
 	for _, line := range lines {
+		if i := strings.Index(line, "Note:"); i >= 0 && len(snapshots) > 0 {
+			snapshots[len(snapshots)-1].Note = strings.TrimSpace(line[i+len("Note:"):])
+			continue
+		}
 		if strings.Contains(line, "Commit:") {
 			// Parse commit line
 			parts := strings.Fields(line)
@@ -250,10 +407,9 @@ func (p *Plugin) View() string {
 		sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
 		sb.WriteString("│                                                             │\n")
 		sb.WriteString("│  Commands:                                                │\n")
-		sb.WriteString("│  • S: Create snapshot                                    │\n")
-		sb.WriteString("│  • R: Restore snapshot (preview)                         │\n")
-		sb.WriteString("│  • D: Delete old snapshots                               │\n")
-		sb.WriteString("│  • L: Refresh list                                       │\n")
+		for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+			sb.WriteString(fmt.Sprintf("│  • %-53s │\n", line))
+		}
 		sb.WriteString("│  • ↑/↓: Navigate snapshots                               │\n")
 		sb.WriteString("│  • Enter: Restore selected snapshot                      │\n")
 	}
@@ -273,6 +429,9 @@ type AvailabilityMsg struct {
 	Error     string
 }
 
+// pollMsg triggers a periodic refresh of the snapshot list.
+type pollMsg struct{}
+
 type SnapshotsMsg struct {
 	Snapshots []Snapshot
 }
@@ -280,4 +439,5 @@ type SnapshotsMsg struct {
 type CommandResultMsg struct {
 	Success bool
 	Output  string
+	Commit  string // snapshot a restore preview is for
 }
@@ -0,0 +1,304 @@
+package marchat
+
+import (
+	"fmt"
+	"strings"
+
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// systemSender is the sender marchat uses for server replies, including
+// the results of admin commands.
+const systemSender = "System"
+
+// maxAdminLog bounds the server replies kept for the admin panel.
+const maxAdminLog = 50
+
+// adminAction is an admin command waiting for confirmation.
+type adminAction struct {
+	Command string // kick, ban, unban, cleardb or stats
+	Target  string
+}
+
+func (a adminAction) String() string {
+	if a.Target == "" {
+		return ":" + a.Command
+	}
+	return ":" + a.Command + " " + a.Target
+}
+
+// adminState is the admin panel: the connected users, a pending
+// confirmation, the unban prompt and recent server replies.
+type adminState struct {
+	open      bool
+	cursor    int
+	confirm   *adminAction
+	unbanning bool // typing a name to unban; banned users aren't listed
+	unbanName string
+	clearing  bool // cleardb is being sent or awaits the server's reply
+	log       []Message
+}
+
+// AdminSentMsg reports whether an admin command reached the server.
+type AdminSentMsg struct {
+	Action adminAction
+	Err    error
+}
+
+// openAdmin shows the admin panel if we are configured as an admin.
+func (p *Plugin) openAdmin() {
+	if !p.admin {
+		p.result = "❌ The admin panel needs the admin role (see MarChat Settings)"
+		return
+	}
+	p.adminPanel.open = true
+	p.adminPanel.cursor = 0
+}
+
+// adminReply records a server reply shown in the admin panel. The first
+// reply after cleardb says whether the server cleared its database; only
+// then is the local history cleared too.
+func (p *Plugin) adminReply(msg Message) tea.Cmd {
+	log := append(p.adminPanel.log, msg)
+	if len(log) > maxAdminLog {
+		log = log[len(log)-maxAdminLog:]
+	}
+	p.adminPanel.log = log
+
+	if p.adminPanel.clearing {
+		p.adminPanel.clearing = false
+		if clearConfirmed(msg.Content) {
+			return p.clearLocalHistory()
+		}
+		p.result = "❌ The server didn't clear its history; local history was kept"
+	}
+	return nil
+}
+
+// clearConfirmed reports whether a server reply to cleardb says the
+// database was cleared, as in "Cleared 12 messages" or "Database cleared".
+func clearConfirmed(reply string) bool {
+	reply = strings.ToLower(reply)
+	if !strings.Contains(reply, "clear") {
+		return false
+	}
+	for _, failure := range []string{"fail", "error", "denied", "require", "unknown", "usage", "not "} {
+		if strings.Contains(reply, failure) {
+			return false
+		}
+	}
+	return true
+}
+
+// clearLocalHistory drops every channel's messages, in memory and on disk,
+// after the server has cleared its database.
+func (p *Plugin) clearLocalHistory() tea.Cmd {
+	for _, ch := range p.channels {
+		ch.Messages = nil
+	}
+	p.scroll, p.selected = 0, -1
+	return p.history.Clear()
+}
+
+// localCleared reports the outcome of clearing the history on disk.
+func (p *Plugin) localCleared(err error) {
+	if err != nil {
+		p.errorMsg = "Failed to clear local history: " + err.Error()
+		return
+	}
+	p.result = "✅ Cleared the server's and the local history"
+}
+
+// adminSent handles the outcome of sending an admin command.
+func (p *Plugin) adminSent(msg AdminSentMsg) {
+	if msg.Err != nil {
+		if msg.Action.Command == "cleardb" {
+			p.adminPanel.clearing = false
+		}
+		p.result = fmt.Sprintf("❌ %s: %v", msg.Action, msg.Err)
+		return
+	}
+	if msg.Action.Command != "cleardb" || p.adminPanel.clearing {
+		p.result = "Sent " + msg.Action.String()
+	}
+}
+
+// adminIdle reports whether the admin panel is open with no confirmation
+// or unban prompt pending.
+func (p *Plugin) adminIdle() bool {
+	return p.adminPanel.open && p.adminPanel.confirm == nil && !p.adminPanel.unbanning
+}
+
+// userSelected reports whether the admin panel can act on a listed user.
+func (p *Plugin) userSelected() bool {
+	return p.adminIdle() && p.adminPanel.cursor < len(p.users)
+}
+
+// runAdminAction starts an admin panel action. Every command that changes
+// server state asks for confirmation first.
+func (p *Plugin) runAdminAction(id string) tea.Cmd {
+	a := &p.adminPanel
+	switch id {
+	case "kick", "ban":
+		if a.cursor >= len(p.users) {
+			return nil
+		}
+		user := p.users[a.cursor]
+		if strings.EqualFold(user, p.username) {
+			p.result = "❌ You can't kick or ban yourself"
+			return nil
+		}
+		a.confirm = &adminAction{Command: id, Target: user}
+	case "unban":
+		a.unbanning = true
+		a.unbanName = ""
+	case "cleardb":
+		a.confirm = &adminAction{Command: "cleardb"}
+	case "stats":
+		return p.runAdmin(adminAction{Command: "stats"})
+	}
+	return nil
+}
+
+// updateAdmin handles the keys of the admin panel that aren't actions:
+// moving the selection, answering a confirmation and typing a name to
+// unban.
+func (p *Plugin) updateAdmin(msg tea.KeyMsg) tea.Cmd {
+	a := &p.adminPanel
+	if a.confirm != nil {
+		action := *a.confirm
+		a.confirm = nil
+		if msg.String() == "y" {
+			return p.runAdmin(action)
+		}
+		p.result = "Cancelled " + action.String()
+		return nil
+	}
+	if a.unbanning {
+		switch msg.String() {
+		case "enter":
+			a.unbanning = false
+			if name := strings.TrimSpace(a.unbanName); name != "" {
+				a.confirm = &adminAction{Command: "unban", Target: name}
+			}
+		case "esc":
+			a.unbanning = false
+		case "backspace":
+			if r := []rune(a.unbanName); len(r) > 0 {
+				a.unbanName = string(r[:len(r)-1])
+			}
+		default:
+			if msg.Type == tea.KeyRunes {
+				a.unbanName += string(msg.Runes)
+			}
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "up":
+		if a.cursor > 0 {
+			a.cursor--
+		}
+	case "down":
+		if a.cursor < len(p.users)-1 {
+			a.cursor++
+		}
+	case "esc":
+		a.open = false
+	}
+	return nil
+}
+
+// runAdmin sends an admin command. Clearing the server's database also
+// clears every channel's local log once the server confirms it, since the
+// server no longer has them.
+func (p *Plugin) runAdmin(action adminAction) tea.Cmd {
+	if !p.connected {
+		p.result = "❌ Not connected"
+		return nil
+	}
+	p.result = "Sending " + action.String() + "..."
+	if action.Command == "cleardb" {
+		// Set before sending: the reply may arrive before AdminSentMsg.
+		p.adminPanel.clearing = true
+	}
+	client := p.client
+	return func() tea.Msg {
+		return AdminSentMsg{Action: action, Err: client.AdminCommand(action.Command, action.Target)}
+	}
+}
+
+func (p *Plugin) adminView() string {
+	a := &p.adminPanel
+	var sb strings.Builder
+
+	sb.WriteString("┌─ MarChat Admin ─────────────────────────────────────────────┐\n")
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString(fmt.Sprintf("│  Server: %-50s │\n", p.serverURL))
+	sb.WriteString(fmt.Sprintf("│  Signed in as %-20s %3d users online         │\n", p.username, len(p.users)))
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  Users:                                                     │\n")
+	if len(p.users) == 0 {
+		sb.WriteString("│    (no users reported by the server)                        │\n")
+	}
+	for i, user := range p.users {
+		prefix := "  "
+		if i == a.cursor {
+			prefix = "> "
+		}
+		if strings.EqualFold(user, p.username) {
+			user += " (you)"
+		}
+		sb.WriteString(fmt.Sprintf("│  %s%-56s │\n", prefix, user))
+	}
+	sb.WriteString("│                                                             │\n")
+
+	sb.WriteString("│  Server replies:                                            │\n")
+	start := len(a.log) - 6
+	if start < 0 {
+		start = 0
+	}
+	if len(a.log) == 0 {
+		sb.WriteString("│    (none yet)                                               │\n")
+	}
+	for _, m := range a.log[start:] {
+		for _, line := range strings.Split(m.Content, "\n") {
+			line = fmt.Sprintf("[%s] %s", m.Timestamp.Format("15:04"), line)
+			if r := []rune(line); len(r) > 58 {
+				line = string(r[:55]) + "..."
+			}
+			sb.WriteString(fmt.Sprintf("│  %-58s │\n", line))
+		}
+	}
+	sb.WriteString("│                                                             │\n")
+
+	switch {
+	case a.confirm != nil:
+		sb.WriteString(fmt.Sprintf("│  Send %-40s? (y/n)       │\n", a.confirm))
+	case a.unbanning:
+		sb.WriteString(fmt.Sprintf("│  Unban: [%-49s] │\n", a.unbanName))
+	case p.result != "":
+		sb.WriteString(fmt.Sprintf("│  %-58s │\n", p.result))
+	}
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("│  Commands:                                                  │\n")
+	sb.WriteString("│  • ↑/↓: Select user                                         │\n")
+	for _, action := range p.ctx.Keys.Bind(p.Name(), p.Actions()) {
+		if action.Mode == "admin" {
+			for _, line := range types.ActionHelp([]types.Action{action}) {
+				sb.WriteString(fmt.Sprintf("│  • %-56s │\n", line))
+			}
+		}
+	}
+	sb.WriteString("│  • Esc: Back to chat                                        │\n")
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
+
+// isSystem reports whether msg is a server reply rather than chat.
+func isSystem(msg Message) bool {
+	return strings.EqualFold(msg.Username, systemSender) || msg.Type == "system"
+}
@@ -0,0 +1,187 @@
+package marchat
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// channel is one conversation: a named channel, or a direct-message thread
+// whose name is the other user's name prefixed with "@".
+type channel struct {
+	Name     string
+	Messages []Message
+	Unread   int // messages received while another channel was shown
+	Mentions int // unread messages mentioning us, including every DM
+}
+
+func isDM(name string) bool {
+	return strings.HasPrefix(name, "@")
+}
+
+// channelLabel formats a channel name for display.
+func channelLabel(name string) string {
+	if isDM(name) {
+		return name
+	}
+	return "#" + name
+}
+
+// normalizeChannel accepts "#name", "name" or "@user".
+func normalizeChannel(name string) string {
+	name = strings.TrimSpace(name)
+	if isDM(name) {
+		return name
+	}
+	return strings.ToLower(strings.TrimPrefix(name, "#"))
+}
+
+// channelOf returns the conversation msg belongs to. Messages from servers
+// without channel support land in the default channel.
+func (p *Plugin) channelOf(msg Message) string {
+	switch {
+	case msg.Recipient != "" && strings.EqualFold(msg.Username, p.username):
+		return "@" + msg.Recipient
+	case msg.Recipient != "":
+		return "@" + msg.Username
+	case msg.Channel != "":
+		return normalizeChannel(msg.Channel)
+	}
+	return defaultChannel
+}
+
+// ensureChannel returns the named channel, creating it and loading its
+// stored history the first time it is seen.
+func (p *Plugin) ensureChannel(name string) (*channel, tea.Cmd) {
+	if ch, ok := p.channels[name]; ok {
+		return ch, nil
+	}
+	ch := &channel{Name: name}
+	p.channels[name] = ch
+	return ch, p.loadHistory(name)
+}
+
+// current returns the channel being shown.
+func (p *Plugin) current() *channel {
+	ch, _ := p.ensureChannel(p.channel)
+	return ch
+}
+
+// channelNames lists channels in sidebar order: the default channel, other
+// channels alphabetically, then direct messages.
+func (p *Plugin) channelNames() []string {
+	names := make([]string, 0, len(p.channels))
+	for name := range p.channels {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if (a == defaultChannel) != (b == defaultChannel) {
+			return a == defaultChannel
+		}
+		if isDM(a) != isDM(b) {
+			return !isDM(a)
+		}
+		return a < b
+	})
+	return names
+}
+
+// switchTo shows the named channel, joining it on the server if needed.
+func (p *Plugin) switchTo(name string) tea.Cmd {
+	ch, cmd := p.ensureChannel(name)
+	p.channel = name
+	ch.Unread, ch.Mentions = 0, 0
+	p.scroll, p.selected = 0, -1
+	p.exportPath = ""
+	if isDM(name) || p.client == nil {
+		return cmd
+	}
+	client := p.client
+	return tea.Batch(cmd, func() tea.Msg {
+		if err := client.Join(name); err != nil && err != errNotConnected {
+			return SendResultMsg{Err: fmt.Errorf("join %s: %v", channelLabel(name), err)}
+		}
+		return nil
+	})
+}
+
+// cycleChannel switches to the channel n places away in sidebar order.
+func (p *Plugin) cycleChannel(n int) tea.Cmd {
+	names := p.channelNames()
+	for i, name := range names {
+		if name == p.channel {
+			return p.switchTo(names[((i+n)%len(names)+len(names))%len(names)])
+		}
+	}
+	return nil
+}
+
+// mentionPattern matches @username as a whole word.
+func mentionPattern(username string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(username) + `\b`)
+}
+
+// mentions reports whether msg is addressed to us: a DM from someone else,
+// or a message naming us with @.
+func (p *Plugin) mentions(msg Message) bool {
+	if strings.EqualFold(msg.Username, p.username) {
+		return false
+	}
+	return msg.Recipient != "" || p.mention.MatchString(msg.Content)
+}
+
+// runCommand handles a composer line starting with "/".
+func (p *Plugin) runCommand(line string) tea.Cmd {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "/join", "/j":
+		if len(fields) < 2 {
+			p.result = "Usage: /join #channel"
+			return nil
+		}
+		return p.switchTo(normalizeChannel(fields[1]))
+	case "/leave", "/part":
+		if p.channel == defaultChannel {
+			p.result = "Can't leave " + channelLabel(defaultChannel)
+			return nil
+		}
+		name := p.channel
+		delete(p.channels, name)
+		cmd := p.switchTo(defaultChannel)
+		if isDM(name) || p.client == nil {
+			return cmd
+		}
+		client := p.client
+		return tea.Batch(cmd, func() tea.Msg {
+			client.Leave(name)
+			return nil
+		})
+	case "/dm", "/msg":
+		if len(fields) < 2 {
+			p.result = "Usage: /dm user [message]"
+			return nil
+		}
+		cmd := p.switchTo("@" + strings.TrimPrefix(fields[1], "@"))
+		if len(fields) > 2 {
+			return tea.Batch(cmd, p.send(strings.Join(fields[2:], " ")))
+		}
+		return cmd
+	case "/channels", "/list":
+		if p.client == nil {
+			return nil
+		}
+		client := p.client
+		return func() tea.Msg {
+			if err := client.ListChannels(); err != nil {
+				return SendResultMsg{Err: err}
+			}
+			return nil
+		}
+	}
+	p.result = "Unknown command " + fields[0] + " (try /join, /leave, /dm, /channels)"
+	return nil
+}
@@ -0,0 +1,208 @@
+package marchat
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	minBackoff   = 500 * time.Millisecond
+	maxBackoff   = 30 * time.Second
+	writeTimeout = 5 * time.Second
+	pingInterval = 30 * time.Second
+)
+
+var errNotConnected = errors.New("not connected to marchat server")
+
+// Client keeps a single WebSocket connection to a marchat server,
+// reconnecting with exponential backoff, and delivers everything it
+// receives as Bubble Tea messages through Listen.
+type Client struct {
+	URL      string
+	Username string
+	Admin    bool
+	AdminKey string
+
+	events chan tea.Msg
+	stop   chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// NewClient returns a Client that is not yet connected; call Run.
+func NewClient(url, username string, admin bool, adminKey string) *Client {
+	return &Client{
+		URL:      url,
+		Username: username,
+		Admin:    admin,
+		AdminKey: adminKey,
+		events:   make(chan tea.Msg, 64),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Listen returns a command that waits for the next client event. The
+// plugin re-issues it after handling each event.
+func (c *Client) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-c.events:
+			return msg
+		case <-c.stop:
+			return nil
+		}
+	}
+}
+
+// Run connects and reads until Close is called, reconnecting with backoff
+// whenever the connection drops.
+func (c *Client) Run() {
+	backoff := minBackoff
+	for {
+		err := c.connectAndRead(func() { backoff = minBackoff })
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+
+		c.emit(DisconnectedMsg{Err: err, RetryIn: backoff})
+		select {
+		case <-time.After(backoff):
+		case <-c.stop:
+			return
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (c *Client) connectAndRead(onConnect func()) error {
+	conn, _, err := websocket.DefaultDialer.Dial(c.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(handshake{Username: c.Username, Admin: c.Admin, AdminKey: c.AdminKey}); err != nil {
+		return fmt.Errorf("handshake: %v", err)
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	onConnect()
+	c.emit(ConnectedMsg{})
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go c.ping(conn, pingDone)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		msg, err := decodeFrame(data)
+		if err != nil {
+			continue
+		}
+		c.emit(msg)
+	}
+}
+
+func (c *Client) ping(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (c *Client) emit(msg tea.Msg) {
+	select {
+	case c.events <- msg:
+	case <-c.stop:
+	}
+}
+
+// Send writes a chat message on the current connection, to channel or,
+// when recipient is set, as a direct message.
+func (c *Client) Send(content, channel, recipient string) error {
+	return c.write(wireMessage{Sender: c.Username, Content: content, CreatedAt: time.Now(), Channel: channel, Recipient: recipient})
+}
+
+// Join asks the server to subscribe us to channel.
+func (c *Client) Join(channel string) error {
+	return c.write(control{Type: "join", Channel: channel})
+}
+
+// Leave unsubscribes from channel.
+func (c *Client) Leave(channel string) error {
+	return c.write(control{Type: "leave", Channel: channel})
+}
+
+// ListChannels asks the server for its channels; the reply arrives as a
+// ChannelListMsg.
+func (c *Client) ListChannels() error {
+	return c.write(control{Type: "list_channels"})
+}
+
+// AdminCommand sends an admin command the way marchat clients do: as a message
+// starting with ":" that the server acts on for authenticated admins.
+func (c *Client) AdminCommand(command, target string) error {
+	content := ":" + command
+	if target != "" {
+		content += " " + target
+	}
+	return c.write(wireMessage{Sender: c.Username, Content: content, CreatedAt: time.Now()})
+}
+
+func (c *Client) write(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errNotConnected
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+// Close stops reconnecting and closes the connection.
+func (c *Client) Close() {
+	c.once.Do(func() {
+		close(c.stop)
+		c.mu.Lock()
+		if c.conn != nil {
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			c.conn.Close()
+		}
+		c.mu.Unlock()
+	})
+}
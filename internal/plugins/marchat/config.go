@@ -0,0 +1,148 @@
+package marchat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"forger/internal/types"
+)
+
+// defaultAdminKeyEnv is read for the admin key when no other env var is
+// configured.
+const defaultAdminKeyEnv = "MARCHAT_ADMIN_KEY"
+
+// Config is the "marchat" section of forger.json. Any field left empty
+// falls back to the marchat server config, then to built-in defaults.
+type Config struct {
+	ServerURL    string `json:"server_url"`
+	Username     string `json:"username"`
+	Admin        *bool  `json:"admin"`
+	AdminKeyEnv  string `json:"admin_key_env"`  // env var holding the admin key
+	AdminKeyFile string `json:"admin_key_file"` // file whose contents are the admin key
+	ServerConfig string `json:"server_config"`  // path to marchat's server_config.json
+	Theme        string `json:"theme"`
+	Loopback     bool   `json:"loopback"` // use an in-process fake server instead of marchat-server
+}
+
+// serverConfig is the subset of marchat's server_config.json Forger reads.
+type serverConfig struct {
+	Port     int      `json:"port"`
+	AdminKey string   `json:"admin_key"`
+	Theme    string   `json:"theme"`
+	Admins   []string `json:"admins"`
+}
+
+// settings is the resolved configuration the plugin runs with.
+type settings struct {
+	ServerURL    string
+	Username     string
+	Admin        bool
+	AdminKey     string
+	Theme        string
+	ServerConfig string
+	Port         int
+	Loopback     bool
+}
+
+// loadSettings resolves the plugin's settings. The admin key is taken, in
+// order, from the configured key file, the configured (or default) env var,
+// and finally the server config; it is never read from forger.json so it
+// stays out of the file most likely to be committed.
+func loadSettings(ctx *types.Context) (settings, error) {
+	var cfg Config
+	if err := ctx.LoadPluginConfig("marchat", &cfg); err != nil {
+		return settings{}, fmt.Errorf("invalid marchat config: %v", err)
+	}
+
+	s := settings{
+		Username:     "ForgerUser",
+		Theme:        "patriot",
+		ServerConfig: "server_config.json",
+		Port:         9090,
+	}
+	if cfg.ServerConfig != "" {
+		s.ServerConfig = cfg.ServerConfig
+	}
+
+	var srv serverConfig
+	data, err := os.ReadFile(s.ServerConfig)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &srv); err != nil {
+			return s, fmt.Errorf("invalid %s: %v", s.ServerConfig, err)
+		}
+	case cfg.ServerConfig != "":
+		// Only an explicitly configured server config is required.
+		return s, fmt.Errorf("failed to read %s: %v", s.ServerConfig, err)
+	}
+
+	if srv.Port != 0 {
+		s.Port = srv.Port
+	}
+	if srv.Theme != "" {
+		s.Theme = srv.Theme
+	}
+	if len(srv.Admins) > 0 {
+		s.Username = srv.Admins[0]
+	}
+
+	if cfg.Username != "" {
+		s.Username = cfg.Username
+	}
+	if cfg.Theme != "" {
+		s.Theme = cfg.Theme
+	}
+	s.ServerURL = fmt.Sprintf("ws://localhost:%d/ws", s.Port)
+	if cfg.ServerURL != "" {
+		s.ServerURL = cfg.ServerURL
+	}
+
+	s.Admin = contains(srv.Admins, s.Username)
+	if cfg.Admin != nil {
+		s.Admin = *cfg.Admin
+	}
+
+	key, err := adminKey(cfg, srv)
+	if err != nil {
+		return s, err
+	}
+	s.AdminKey = key
+	s.Loopback = cfg.Loopback
+	// The loopback server accepts any key, so none is needed there.
+	if s.Admin && s.AdminKey == "" && !s.Loopback {
+		return s, fmt.Errorf("admin enabled for %s but no admin key found (set %s or admin_key_file)", s.Username, envName(cfg))
+	}
+	return s, nil
+}
+
+func adminKey(cfg Config, srv serverConfig) (string, error) {
+	if cfg.AdminKeyFile != "" {
+		data, err := os.ReadFile(cfg.AdminKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read admin key file: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if key := os.Getenv(envName(cfg)); key != "" {
+		return key, nil
+	}
+	return srv.AdminKey, nil
+}
+
+func envName(cfg Config) string {
+	if cfg.AdminKeyEnv != "" {
+		return cfg.AdminKeyEnv
+	}
+	return defaultAdminKeyEnv
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,450 @@
+// Package fakeserver is an in-process stand-in for marchat-server. It
+// speaks the subset of the marchat protocol Forger's client uses: the
+// handshake, chat messages with channels and direct messages, join, leave
+// and list_channels requests, the user and channel lists, and ":" admin
+// commands. It lets the marchat plugin run without the real server
+// (loopback mode) and lets tests exercise send, receive and reconnect.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultChannel is the channel every connection is in.
+const DefaultChannel = "general"
+
+// SystemSender is the sender of server replies and announcements.
+const SystemSender = "System"
+
+const (
+	defaultReplay = 50
+	writeTimeout  = 5 * time.Second
+)
+
+// Message is a chat message as it goes over the socket.
+type Message struct {
+	Sender    string    `json:"sender"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type,omitempty"`
+	Channel   string    `json:"channel,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+}
+
+// handshake is the first frame a client sends.
+type handshake struct {
+	Username string `json:"username"`
+	Admin    bool   `json:"admin"`
+	AdminKey string `json:"admin_key"`
+}
+
+type envelope struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Server is a fake marchat server. The zero value is not usable; call New.
+type Server struct {
+	AdminKey string   // key admins must present; empty accepts any key
+	Admins   []string // users allowed to be admins; empty allows anyone with the key
+	Replay   int      // recent messages sent to each new connection
+
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	conns    map[*conn]bool
+	messages []Message
+	channels map[string]bool
+	banned   map[string]bool
+	http     *http.Server
+}
+
+// conn is one connected client.
+type conn struct {
+	ws       *websocket.Conn
+	username string
+	admin    bool
+	channels map[string]bool
+
+	mu sync.Mutex // serializes writes
+}
+
+// New returns a server with no admins configured.
+func New() *Server {
+	return &Server{
+		Replay:   defaultReplay,
+		conns:    make(map[*conn]bool),
+		channels: map[string]bool{DefaultChannel: true},
+		banned:   make(map[string]bool),
+	}
+}
+
+// Start listens on addr (such as "127.0.0.1:0" for any free port) and
+// serves in the background. It returns the WebSocket URL to connect to.
+func (s *Server) Start(addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/ws", s)
+	s.mu.Lock()
+	s.http = &http.Server{Handler: mux}
+	srv := s.http
+	s.mu.Unlock()
+	go srv.Serve(ln)
+	return "ws://" + ln.Addr().String() + "/ws", nil
+}
+
+// Close stops listening and disconnects every client.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	srv := s.http
+	s.http = nil
+	s.mu.Unlock()
+	s.DropAll()
+	if srv == nil {
+		return nil
+	}
+	return srv.Close()
+}
+
+// DropAll closes every connection without stopping the server, as a
+// network failure would; clients are expected to reconnect.
+func (s *Server) DropAll() {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.ws.Close()
+	}
+}
+
+// Users returns the connected usernames, sorted.
+func (s *Server) Users() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users()
+}
+
+// Messages returns every chat message the server holds, oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Post delivers msg as if a client had sent it, for simulating other users.
+func (s *Server) Post(msg Message) {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	s.deliver(msg)
+}
+
+// ServeHTTP upgrades the request and serves one client until it
+// disconnects, so the server can also be mounted on an httptest.Server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	var hs handshake
+	if err := ws.ReadJSON(&hs); err != nil || strings.TrimSpace(hs.Username) == "" {
+		return
+	}
+	c := &conn{ws: ws, username: hs.Username, channels: map[string]bool{DefaultChannel: true}}
+	if hs.Admin {
+		if reason := s.authorize(hs); reason != "" {
+			c.send(envelope{Type: "auth_failed", Data: map[string]string{"reason": reason}})
+			return
+		}
+		c.admin = true
+	}
+
+	s.mu.Lock()
+	if s.banned[strings.ToLower(c.username)] {
+		s.mu.Unlock()
+		c.send(system("You are banned from this server"))
+		return
+	}
+	s.conns[c] = true
+	replay := s.visible(c)
+	s.mu.Unlock()
+
+	for _, m := range replay {
+		c.send(m)
+	}
+	s.broadcastUsers()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		s.broadcastUsers()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		// Requests such as join share the message's type and channel fields.
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		s.handle(c, m)
+	}
+}
+
+func (s *Server) authorize(hs handshake) string {
+	if len(s.Admins) > 0 && !containsFold(s.Admins, hs.Username) {
+		return hs.Username + " is not an admin"
+	}
+	if s.AdminKey != "" && hs.AdminKey != s.AdminKey {
+		return "invalid admin key"
+	}
+	return ""
+}
+
+// handle acts on one frame from c.
+func (s *Server) handle(c *conn, m Message) {
+	switch m.Type {
+	case "join":
+		name := channelName(m.Channel)
+		s.mu.Lock()
+		c.channels[name] = true
+		s.channels[name] = true
+		s.mu.Unlock()
+		return
+	case "leave":
+		name := channelName(m.Channel)
+		if name != DefaultChannel {
+			s.mu.Lock()
+			delete(c.channels, name)
+			s.mu.Unlock()
+		}
+		return
+	case "list_channels":
+		s.mu.Lock()
+		var names []string
+		for name := range s.channels {
+			names = append(names, name)
+		}
+		s.mu.Unlock()
+		sort.Strings(names)
+		c.send(envelope{Type: "channels", Data: map[string][]string{"channels": names}})
+		return
+	}
+
+	if strings.HasPrefix(m.Content, ":") {
+		s.command(c, m.Content)
+		return
+	}
+	m.Sender = c.username // clients can't speak for others
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.Recipient == "" {
+		m.Channel = channelName(m.Channel)
+	}
+	s.deliver(m)
+}
+
+// command runs a ":" admin command from c and replies to c.
+func (s *Server) command(c *conn, content string) {
+	if !c.admin {
+		c.send(system("Admin commands require admin privileges"))
+		return
+	}
+	fields := strings.Fields(strings.TrimPrefix(content, ":"))
+	if len(fields) == 0 {
+		c.send(system("Empty command"))
+		return
+	}
+	target := ""
+	if len(fields) > 1 {
+		target = fields[1]
+	}
+	needsTarget := func() bool {
+		if target == "" {
+			c.send(system(fmt.Sprintf("Usage: :%s <username>", fields[0])))
+			return false
+		}
+		return true
+	}
+
+	switch fields[0] {
+	case "kick":
+		if !needsTarget() {
+			return
+		}
+		if s.disconnect(target) == 0 {
+			c.send(system(target + " is not connected"))
+			return
+		}
+		c.send(system("Kicked " + target))
+	case "ban":
+		if !needsTarget() {
+			return
+		}
+		s.mu.Lock()
+		s.banned[strings.ToLower(target)] = true
+		s.mu.Unlock()
+		s.disconnect(target)
+		c.send(system("Banned " + target))
+	case "unban":
+		if !needsTarget() {
+			return
+		}
+		s.mu.Lock()
+		was := s.banned[strings.ToLower(target)]
+		delete(s.banned, strings.ToLower(target))
+		s.mu.Unlock()
+		if !was {
+			c.send(system(target + " is not banned"))
+			return
+		}
+		c.send(system("Unbanned " + target))
+	case "cleardb":
+		s.mu.Lock()
+		n := len(s.messages)
+		s.messages = nil
+		s.mu.Unlock()
+		c.send(system(fmt.Sprintf("Cleared %d messages", n)))
+	case "stats":
+		s.mu.Lock()
+		stats := fmt.Sprintf("Users online: %d\nMessages: %d\nChannels: %d\nBanned: %d",
+			len(s.users()), len(s.messages), len(s.channels), len(s.banned))
+		s.mu.Unlock()
+		c.send(system(stats))
+	default:
+		c.send(system("Unknown command: " + fields[0]))
+	}
+}
+
+// deliver stores m and sends it to everyone who can see it, including
+// the sender, whose client uses the echo to confirm delivery.
+func (s *Server) deliver(m Message) {
+	s.mu.Lock()
+	s.messages = append(s.messages, m)
+	var to []*conn
+	for c := range s.conns {
+		if canSee(c, m) {
+			to = append(to, c)
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range to {
+		c.send(m)
+	}
+}
+
+// disconnect closes every connection of username and returns how many
+// there were.
+func (s *Server) disconnect(username string) int {
+	s.mu.Lock()
+	var conns []*conn
+	for c := range s.conns {
+		if strings.EqualFold(c.username, username) {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.ws.Close()
+	}
+	return len(conns)
+}
+
+func (s *Server) broadcastUsers() {
+	s.mu.Lock()
+	users := s.users()
+	var conns []*conn
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.send(envelope{Type: "userlist", Data: map[string][]string{"users": users}})
+	}
+}
+
+// users lists connected usernames; s.mu must be held.
+func (s *Server) users() []string {
+	seen := make(map[string]bool)
+	users := []string{}
+	for c := range s.conns {
+		if !seen[c.username] {
+			seen[c.username] = true
+			users = append(users, c.username)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// visible returns the last Replay messages c can see; s.mu must be held.
+func (s *Server) visible(c *conn) []Message {
+	var msgs []Message
+	for _, m := range s.messages {
+		if canSee(c, m) {
+			msgs = append(msgs, m)
+		}
+	}
+	if len(msgs) > s.Replay {
+		msgs = msgs[len(msgs)-s.Replay:]
+	}
+	return msgs
+}
+
+// canSee reports whether m is for c: a direct message to or from it, or a
+// message in one of its channels.
+func canSee(c *conn, m Message) bool {
+	if m.Recipient != "" {
+		return strings.EqualFold(m.Recipient, c.username) || strings.EqualFold(m.Sender, c.username)
+	}
+	return c.channels[channelName(m.Channel)]
+}
+
+func (c *conn) send(v interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.ws.WriteJSON(v)
+}
+
+func system(content string) Message {
+	return Message{Sender: SystemSender, Content: content, CreatedAt: time.Now()}
+}
+
+func channelName(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return DefaultChannel
+	}
+	return name
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,439 @@
+package marchat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyDir is where chat logs are kept, relative to the workspace.
+const historyDir = ".forger/chat"
+
+// defaultChannel is where messages without a channel are shown.
+const defaultChannel = "general"
+
+// History persists chat messages as one JSON Lines file per channel, under
+// a directory per server so logs from different servers never mix.
+//
+// Save and Clear hand their work to a writer goroutine, which runs it in
+// the order it was queued, so Update never waits on the disk.
+type History struct {
+	Dir string
+
+	mu     sync.Mutex
+	writes chan historyWrite // nil until the first write, and after Close
+	idle   sync.WaitGroup
+
+	indexMu sync.Mutex
+	index   map[string]string // channel to log file name; nil until read
+}
+
+// historyWrite is a write waiting for the writer goroutine.
+type historyWrite struct {
+	run  func() error
+	done chan error
+}
+
+// NewHistory returns the history store for the server at serverURL.
+func NewHistory(serverURL string) *History {
+	server := serverURL
+	if u, err := url.Parse(serverURL); err == nil && u.Host != "" {
+		server = u.Host
+	}
+	return &History{Dir: filepath.Join(historyDir, safeName(server))}
+}
+
+// safeName maps s to something usable as a single path element.
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '@':
+			return r
+		}
+		return '_'
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// indexFile records which channel each log holds: file names are
+// sanitized, so they can't be turned back into channel names.
+const indexFile = "channels.json"
+
+// path returns the log for channel. A new channel is given a file not yet
+// used by another channel and, if create is set, recorded in the index.
+func (h *History) path(channel string, create bool) (string, error) {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+	if err := h.loadIndex(); err != nil {
+		return "", err
+	}
+	if name, ok := h.index[channel]; ok {
+		return filepath.Join(h.Dir, name), nil
+	}
+
+	used := make(map[string]bool, len(h.index))
+	for _, name := range h.index {
+		used[name] = true
+	}
+	// safeName never produces '~', so suffixed names can't clash with a
+	// sanitized one. An unindexed log with the plain name is this
+	// channel's, written before the index existed.
+	name := safeName(channel) + ".jsonl"
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s~%d.jsonl", safeName(channel), n)
+	}
+	if create {
+		h.index[channel] = name
+		if err := h.saveIndex(); err != nil {
+			delete(h.index, channel)
+			return "", err
+		}
+	}
+	return filepath.Join(h.Dir, name), nil
+}
+
+// loadIndex reads the index unless it has been read already; the caller
+// holds indexMu.
+func (h *History) loadIndex() error {
+	if h.index != nil {
+		return nil
+	}
+	index := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(h.Dir, indexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("%s: %w", indexFile, err)
+		}
+	}
+	h.index = index
+	return nil
+}
+
+// saveIndex writes the index; the caller holds indexMu.
+func (h *History) saveIndex() error {
+	data, err := json.MarshalIndent(h.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(h.Dir, indexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load returns every stored message for channel, oldest first. A missing
+// log is an empty history; malformed lines are skipped.
+func (h *History) Load(channel string) ([]Message, error) {
+	path, err := h.path(channel, false)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var msgs []Message
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, scanner.Err()
+}
+
+// Append adds msg to the channel's log.
+func (h *History) Append(channel string, msg Message) error {
+	if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+		return err
+	}
+	path, err := h.path(channel, true)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ClearAll deletes the stored history of every channel on the server.
+func (h *History) ClearAll() error {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+	h.index = nil
+	paths, _ := filepath.Glob(filepath.Join(h.Dir, "*.jsonl"))
+	for _, path := range append(paths, filepath.Join(h.Dir, indexFile)) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// queue runs fn on the writer goroutine, after every write queued before
+// it, and returns a channel that receives its error.
+func (h *History) queue(fn func() error) <-chan error {
+	done := make(chan error, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.writes == nil {
+		h.writes = make(chan historyWrite, 256)
+		h.idle.Add(1)
+		go func(writes chan historyWrite) {
+			defer h.idle.Done()
+			for w := range writes {
+				w.done <- w.run()
+			}
+		}(h.writes)
+	}
+	h.writes <- historyWrite{run: fn, done: done}
+	return done
+}
+
+// Save queues msg to be appended to the channel's log. The command
+// reports a failure as a HistorySavedMsg.
+func (h *History) Save(channel string, msg Message) tea.Cmd {
+	done := h.queue(func() error { return h.Append(channel, msg) })
+	return func() tea.Msg {
+		if err := <-done; err != nil {
+			return HistorySavedMsg{Channel: channel, Err: err}
+		}
+		return nil
+	}
+}
+
+// Clear queues the removal of every channel's log, after the messages
+// already queued are written.
+func (h *History) Clear() tea.Cmd {
+	done := h.queue(h.ClearAll)
+	return func() tea.Msg {
+		return HistoryClearedMsg{Err: <-done}
+	}
+}
+
+// Flush waits until every write queued so far is on disk.
+func (h *History) Flush() {
+	<-h.queue(func() error { return nil })
+}
+
+// Close writes what is queued and stops the writer goroutine. A later
+// write starts a new one.
+func (h *History) Close() {
+	h.mu.Lock()
+	if h.writes != nil {
+		close(h.writes)
+		h.writes = nil
+	}
+	h.mu.Unlock()
+	h.idle.Wait()
+}
+
+// listChannels returns a command that lists the channels with stored
+// history, for the sidebar.
+func (h *History) listChannels() tea.Cmd {
+	return func() tea.Msg {
+		return StoredChannelsMsg{Channels: h.Channels()}
+	}
+}
+
+// Channels lists the channels with stored history, by the names recorded
+// in the index. Logs written before the index existed are listed by their
+// file name.
+func (h *History) Channels() []string {
+	h.indexMu.Lock()
+	h.loadIndex() // without an index, logs are listed by file name
+	owner := make(map[string]string, len(h.index))
+	for channel, name := range h.index {
+		owner[name] = channel
+	}
+	h.indexMu.Unlock()
+
+	paths, _ := filepath.Glob(filepath.Join(h.Dir, "*.jsonl"))
+	var channels []string
+	for _, p := range paths {
+		name := filepath.Base(p)
+		channel, ok := owner[name]
+		if !ok {
+			channel = strings.TrimSuffix(name, ".jsonl")
+		}
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// SearchHit is a message matching a history search.
+type SearchHit struct {
+	Channel string
+	Message Message
+}
+
+// Search returns messages in any channel whose sender or content contains
+// every word of query, case-insensitively, oldest first.
+func (h *History) Search(query string) ([]SearchHit, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	var hits []SearchHit
+	for _, channel := range h.Channels() {
+		msgs, err := h.Load(channel)
+		if err != nil {
+			return hits, err
+		}
+		for _, m := range msgs {
+			text := strings.ToLower(m.Username + " " + m.Content)
+			match := true
+			for _, t := range terms {
+				if !strings.Contains(text, t) {
+					match = false
+					break
+				}
+			}
+			if match {
+				hits = append(hits, SearchHit{Channel: channel, Message: m})
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Message.Timestamp.Before(hits[j].Message.Timestamp)
+	})
+	return hits, nil
+}
+
+// messageKey identifies a message for de-duplication; servers replay recent
+// history on connect, which would otherwise be logged twice.
+func messageKey(m Message) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", m.Timestamp.UnixNano(), m.Username, m.Content)
+}
+
+// exportFormats maps file extensions to history writers.
+var exportFormats = map[string]func(io.Writer, string, []Message) error{
+	".md":    writeMarkdown,
+	".jsonl": writeJSONL,
+}
+
+// exportExtensions is the order the export prompt cycles through formats.
+var exportExtensions = []string{".md", ".jsonl"}
+
+// exportHistory writes msgs from channel to path in the format implied by
+// its extension.
+func exportHistory(path, channel string, msgs []Message) ExportResultMsg {
+	write, ok := exportFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return ExportResultMsg{Err: fmt.Errorf("unknown export format %q (use .md or .jsonl)", filepath.Ext(path))}
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return ExportResultMsg{Err: err}
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return ExportResultMsg{Err: err}
+	}
+	defer f.Close()
+	if err := write(f, channel, msgs); err != nil {
+		return ExportResultMsg{Err: err}
+	}
+	return ExportResultMsg{Path: path, Count: len(msgs)}
+}
+
+func writeJSONL(w io.Writer, _ string, msgs []Message) error {
+	enc := json.NewEncoder(w)
+	for _, m := range msgs {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, channel string, msgs []Message) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", channelLabel(channel)))
+	sb.WriteString(fmt.Sprintf("Exported %s, %d messages.\n", time.Now().Format(time.RFC1123), len(msgs)))
+
+	day := ""
+	for _, m := range msgs {
+		if d := m.Timestamp.Format("2006-01-02"); d != day {
+			day = d
+			sb.WriteString("\n## " + day + "\n\n")
+		}
+		content := strings.ReplaceAll(m.Content, "\n", "  \n  ")
+		sb.WriteString(fmt.Sprintf("- **%s** %s: %s\n", m.Timestamp.Format("15:04:05"), m.Username, content))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// HistoryMsg carries the stored history for a channel.
+type HistoryMsg struct {
+	Channel  string
+	Messages []Message
+	Err      error
+}
+
+// StoredChannelsMsg lists the channels with stored history.
+type StoredChannelsMsg struct {
+	Channels []string
+}
+
+// HistorySavedMsg reports a message that could not be saved.
+type HistorySavedMsg struct {
+	Channel string
+	Err     error
+}
+
+// HistoryClearedMsg reports the outcome of clearing the local history.
+type HistoryClearedMsg struct {
+	Err error
+}
+
+// SearchResultMsg carries the result of a history search.
+type SearchResultMsg struct {
+	Query string
+	Hits  []SearchHit
+	Err   error
+}
+
+// ExportResultMsg reports the outcome of a history export.
+type ExportResultMsg struct {
+	Path  string
+	Count int
+	Err   error
+}
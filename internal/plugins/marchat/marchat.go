@@ -2,14 +2,20 @@ package marchat
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
+	"forger/internal/plugins/marchat/fakeserver"
+	"forger/internal/runner"
 	"forger/internal/types"
+	"forger/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type Plugin struct {
@@ -18,137 +24,698 @@ type Plugin struct {
 	serverURL     string
 	username      string
 	theme         string
-	messages      []Message
-	input         string
+	admin         bool
+	adminKey      string
+	serverConfig  string
+	channels      map[string]*channel
+	mention       *regexp.Regexp // matches @username
+	editor        *ui.Editor
+	inserting     bool // composing a message; global shortcuts are suspended
 	connected     bool
 	errorMsg      string
 	result        string // Add result field for command feedback
-	serverProcess *os.Process
+	serverState   ServerState
+	supervisor    *Supervisor
+	loopback      bool               // run against an in-process fake server
+	fake          *fakeserver.Server // the loopback server, once started
+	client        *Client
+	users         []string
+	pending       map[string]int // sent messages awaiting their server echo
+	history       *History
+	channel       string          // name of the channel being shown
+	seen          map[string]bool // messages already shown and logged
+	scroll        int             // messages scrolled back from the newest
+	pager         *ui.Pager       // non-nil while search results are shown
+	searching     bool            // editing the search query
+	query         string
+	exporting     bool // editing the export path
+	exportPath    string
+	selected      int // message picked with ↑/↓ for following references, or -1
+	adminPanel    adminState
 }
 
+const (
+	visibleMessages = 8 // messages the chat box shows at once
+	composerLines   = 4 // lines of a multi-line message shown while typing
+)
+
 type Message struct {
 	Username  string    `json:"username"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
 	Type      string    `json:"type"`
+	Channel   string    `json:"channel,omitempty"`
+	Recipient string    `json:"recipient,omitempty"` // set on direct messages
 }
 
 func New(ctx *types.Context) types.Plugin {
-	return &Plugin{
-		ctx:       ctx,
-		serverURL: "ws://localhost:9090/ws",
-		username:  "ForgerUser",
-		theme:     "patriot",
-		messages:  []Message{},
+	s, err := loadSettings(ctx)
+	p := &Plugin{
+		ctx:          ctx,
+		serverURL:    s.ServerURL,
+		username:     s.Username,
+		theme:        s.Theme,
+		admin:        s.Admin,
+		adminKey:     s.AdminKey,
+		serverConfig: s.ServerConfig,
+		loopback:     s.Loopback,
+		channels:     make(map[string]*channel),
+		mention:      mentionPattern(s.Username),
+		pending:      make(map[string]int),
+		history:      NewHistory(s.ServerURL),
+		channel:      defaultChannel,
+		seen:         make(map[string]bool),
+		editor:       ui.NewEditor(),
+		selected:     -1,
+	}
+	if s.Loopback {
+		// The loopback server's port changes every run; keep one history.
+		p.history = NewHistory("loopback")
 	}
+	if err != nil {
+		p.errorMsg = err.Error()
+	}
+	return p
 }
 
 func (p *Plugin) Init() tea.Cmd {
-	return p.startServer()
+	_, load := p.ensureChannel(defaultChannel)
+	// List every channel with stored history in the sidebar.
+	return tea.Batch(p.startServer(), p.connect(), load, p.history.listChannels())
 }
 
-func (p *Plugin) startServer() tea.Cmd {
+func (p *Plugin) loadHistory(channel string) tea.Cmd {
+	history := p.history
 	return func() tea.Msg {
-		// Check if we have the marchat server executable
-		marchatServerPath := os.Getenv("GOPATH") + "\\bin\\marchat-server.exe"
-
-		fmt.Printf("DEBUG: MarChat checking server at: %s\n", marchatServerPath)
+		msgs, err := history.Load(channel)
+		return HistoryMsg{Channel: channel, Messages: msgs, Err: err}
+	}
+}
 
-		// Check if server executable exists
-		if _, err := os.Stat(marchatServerPath); os.IsNotExist(err) {
-			fmt.Printf("DEBUG: MarChat server not found: %v\n", err)
-			return ServerCheckMsg{Available: false, Error: fmt.Sprintf("marchat-server not found at: %s", marchatServerPath)}
+// record shows msg in its channel and appends it to that channel's
+// history, unless it has been seen before. Messages for other channels
+// count as unread.
+func (p *Plugin) record(msg Message) tea.Cmd {
+	key := messageKey(msg)
+	if p.seen[key] {
+		return nil
+	}
+	p.seen[key] = true
+	name := p.channelOf(msg)
+	ch, cmd := p.ensureChannel(name)
+	ch.Messages = append(ch.Messages, msg)
+	switch {
+	case name != p.channel:
+		ch.Unread++
+		if p.mentions(msg) {
+			ch.Mentions++
 		}
+	case p.scroll > 0:
+		// Keep a scrolled-back view where it is.
+		p.scroll++
+	}
+	cmd = tea.Batch(cmd, p.history.Save(name, msg))
+	if p.mentions(msg) {
+		ref := types.Ref{Plugin: p.Name(), Target: name}
+		text := fmt.Sprintf("%s in %s: %s", msg.Username, channelLabel(name), msg.Content)
+		cmd = tea.Batch(cmd, func() tea.Msg {
+			return types.NotifyMsg{Source: ref.Plugin, Severity: types.SeverityWarning, Text: text, Ref: &ref}
+		})
+	}
+	return cmd
+}
 
-		fmt.Printf("DEBUG: MarChat server found, starting...\n")
-
-		// Start the server using the executable
-		cmd := exec.Command(marchatServerPath, "-config", "server_config.json")
-		// Don't redirect stdout/stderr so we can see any error messages
-
-		if err := cmd.Start(); err != nil {
-			fmt.Printf("DEBUG: MarChat failed to start server: %v\n", err)
-			return ServerCheckMsg{Available: false, Error: fmt.Sprintf("failed to start marchat-server: %v", err)}
+// ownEchoWindow is how far apart the local and server timestamps of one
+// of our own messages may be.
+const ownEchoWindow = time.Minute
+
+// sentEarlier reports whether msg, one of our own messages coming back
+// from the server, is already in its channel: the same content, recorded
+// with our clock when it was sent.
+func (p *Plugin) sentEarlier(msg Message) bool {
+	ch, ok := p.channels[p.channelOf(msg)]
+	if !ok {
+		return false
+	}
+	for i := len(ch.Messages) - 1; i >= 0; i-- {
+		m := ch.Messages[i]
+		if m.Username != msg.Username || m.Content != msg.Content {
+			continue
 		}
+		if d := m.Timestamp.Sub(msg.Timestamp); d > -ownEchoWindow && d < ownEchoWindow {
+			return true
+		}
+	}
+	return false
+}
 
-		fmt.Printf("DEBUG: MarChat server started, PID: %d\n", cmd.Process.Pid)
-
-		// Store the process so we can kill it later
-		p.serverProcess = cmd.Process
-
-		// Wait longer for server to start
-		time.Sleep(5 * time.Second)
-
-		// Check if server is responding by trying to connect
-		marchatClientPath := os.Getenv("GOPATH") + "\\bin\\marchat-client.exe"
-		testCmd := exec.Command(marchatClientPath, "-username", "ForgerUser", "-admin", "-admin-key", "forger-admin-key", "-server", "ws://localhost:9090/ws")
+// connect starts the persistent WebSocket client. It keeps retrying in the
+// background, so it's fine to call before the server is up.
+func (p *Plugin) connect() tea.Cmd {
+	p.client = NewClient(p.serverURL, p.username, p.admin, p.adminKey)
+	go p.client.Run()
+	return p.client.Listen()
+}
 
-		fmt.Printf("DEBUG: MarChat testing server connection...\n")
-		output, err := testCmd.CombinedOutput()
-		if err != nil {
-			fmt.Printf("DEBUG: MarChat server test failed: %v\nOutput: %s\n", err, string(output))
-			return ServerCheckMsg{Available: false, Error: fmt.Sprintf("server not responding: %v", err)}
+// startServer launches the supervisor, which reuses a running server or
+// starts and babysits one in the background. In loopback mode it starts
+// the fake server instead.
+func (p *Plugin) startServer() tea.Cmd {
+	if p.loopback {
+		p.startLoopback()
+		return nil
+	}
+	addr := "localhost:9090"
+	if u, err := url.Parse(p.serverURL); err == nil && u.Host != "" {
+		addr = u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "80")
 		}
+	}
+	serverPath := runner.GopathTool("marchat-server")
+	p.supervisor = NewSupervisor(serverPath, p.serverConfig, filepath.Join(".forger", "logs", "marchat-server.log"), addr)
+	go p.supervisor.Run()
+	return p.supervisor.Listen()
+}
 
-		fmt.Printf("DEBUG: MarChat server test output: %s\n", string(output))
-		fmt.Printf("DEBUG: MarChat server is responding!\n")
-		return ServerCheckMsg{Available: true}
+// startLoopback serves chat from an in-process fake server, so the plugin
+// works without marchat installed. It must run before connect, which
+// dials p.serverURL.
+func (p *Plugin) startLoopback() {
+	srv := fakeserver.New()
+	srv.AdminKey = p.adminKey
+	if p.admin {
+		srv.Admins = []string{p.username}
 	}
+	serverURL, err := srv.Start("127.0.0.1:0")
+	if err != nil {
+		p.serverState = ServerStopped
+		p.errorMsg = "Failed to start loopback server: " + err.Error()
+		return
+	}
+	p.fake = srv
+	p.serverURL = serverURL
+	p.serverState = ServerLoopback
+	p.serverRunning = true
+}
+
+// Close disconnects the client and stops any server Forger started.
+func (p *Plugin) Close() error {
+	if p.client != nil {
+		p.client.Close()
+	}
+	if p.supervisor != nil {
+		p.supervisor.Stop()
+	}
+	if p.fake != nil {
+		p.fake.Close()
+	}
+	p.history.Close()
+	return nil
 }
 
 func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
 	switch msg := msg.(type) {
-	case ServerCheckMsg:
-		p.serverRunning = msg.Available
-		p.errorMsg = msg.Error
-		// Update connection status based on server availability
-		p.connected = msg.Available
+	case ServerStatusMsg:
+		p.serverState = msg.State
+		p.serverRunning = msg.State == ServerReady || msg.State == ServerExternal
+		if msg.State == ServerMissing || msg.State == ServerCrashed {
+			p.errorMsg = msg.Detail
+			return p, tea.Batch(p.supervisor.Listen(), types.Notify(p.Name(), types.SeverityError, "marchat-server "+string(msg.State)+": "+msg.Detail))
+		}
+		return p, p.supervisor.Listen()
+	case ConnectedMsg:
+		p.connected = true
+		p.errorMsg = ""
+		p.result = "✅ Connected to " + p.serverURL
+		return p, p.client.Listen()
+	case DisconnectedMsg:
+		p.connected = false
+		if msg.Err != nil {
+			p.errorMsg = msg.Err.Error()
+		}
+		p.result = fmt.Sprintf("🔌 Disconnected, retrying in %s", msg.RetryIn.Round(time.Millisecond))
+		return p, p.client.Listen()
+	case StoredChannelsMsg:
+		var cmds []tea.Cmd
+		for _, name := range msg.Channels {
+			_, cmd := p.ensureChannel(name)
+			cmds = append(cmds, cmd)
+		}
+		return p, tea.Batch(cmds...)
+	case HistorySavedMsg:
+		p.errorMsg = "Failed to save history: " + msg.Err.Error()
+		return p, nil
+	case HistoryClearedMsg:
+		p.localCleared(msg.Err)
+		return p, nil
+	case HistoryMsg:
+		if msg.Err != nil {
+			p.errorMsg = "Failed to load history: " + msg.Err.Error()
+		}
+		ch, ok := p.channels[msg.Channel]
+		if !ok {
+			return p, nil
+		}
+		// Messages that arrived while loading go after the stored ones.
+		live := ch.Messages
+		ch.Messages = nil
+		for _, m := range msg.Messages {
+			key := messageKey(m)
+			if !p.seen[key] {
+				p.seen[key] = true
+				ch.Messages = append(ch.Messages, m)
+			}
+			if m.Username == p.username && msg.Channel == p.channel {
+				p.editor.Push(m.Content)
+			}
+		}
+		ch.Messages = append(ch.Messages, live...)
+		if msg.Channel == p.channel {
+			p.selected = -1
+		}
+		return p, nil
+	case IncomingMsg:
+		// Our own messages are shown when sent; drop the server's echo,
+		// and the copies it replays after a reconnect, which carry the
+		// server's timestamp rather than ours.
+		if msg.Message.Username == p.username && p.pending[msg.Message.Content] > 0 {
+			p.pending[msg.Message.Content]--
+			p.seen[messageKey(msg.Message)] = true
+			return p, p.client.Listen()
+		}
+		if msg.Message.Username == p.username && p.sentEarlier(msg.Message) {
+			p.seen[messageKey(msg.Message)] = true
+			return p, p.client.Listen()
+		}
+		var clear tea.Cmd
+		if isSystem(msg.Message) {
+			clear = p.adminReply(msg.Message)
+		}
+		return p, tea.Batch(clear, p.record(msg.Message), p.client.Listen())
+	case UserListMsg:
+		p.users = msg.Users
+		return p, p.client.Listen()
+	case ChannelListMsg:
+		cmds := []tea.Cmd{p.client.Listen()}
+		for _, name := range msg.Channels {
+			_, cmd := p.ensureChannel(normalizeChannel(name))
+			cmds = append(cmds, cmd)
+		}
+		p.result = fmt.Sprintf("%d channels on the server", len(msg.Channels))
+		return p, tea.Batch(cmds...)
+	case AuthFailedMsg:
+		p.result = "❌ Authentication failed: " + msg.Reason
+		return p, tea.Batch(p.client.Listen(), types.Notify(p.Name(), types.SeverityError, "Authentication failed: "+msg.Reason))
+	case types.OpenRefMsg:
+		if msg.Ref.Plugin != p.Name() || msg.Ref.Target == "" {
+			return p, nil
+		}
+		return p, p.switchTo(normalizeChannel(msg.Ref.Target))
+	case types.SearchSelectMsg:
+		if msg.Item.Plugin != p.Name() {
+			return p, nil
+		}
+		channel, key, _ := strings.Cut(msg.Item.ID, "\n")
+		return p, p.showMessage(channel, key)
+	case types.ShareMsg:
+		if !p.connected {
+			p.result = "❌ Can't share from " + msg.From + ": not connected"
+			return p, nil
+		}
+		return p, p.send(msg.Text)
+	case AdminSentMsg:
+		p.adminSent(msg)
+		return p, nil
+	case SendResultMsg:
+		if msg.Err != nil {
+			p.result = "❌ Failed to send message: " + msg.Err.Error()
+			if msg.Content != "" {
+				p.pending[msg.Content]--
+			}
+		} else {
+			p.result = "✅ Message sent"
+		}
+		return p, nil
+	case SearchResultMsg:
+		if msg.Err != nil {
+			p.result = "❌ Search failed: " + msg.Err.Error()
+			return p, nil
+		}
+		var lines []string
+		for _, hit := range msg.Hits {
+			m := hit.Message
+			lines = append(lines, fmt.Sprintf("%s [%s] %s: %s", channelLabel(hit.Channel), m.Timestamp.Format("2006-01-02 15:04"), m.Username, strings.ReplaceAll(m.Content, "\n", " ")))
+		}
+		if len(lines) == 0 {
+			lines = []string{"No messages match."}
+		}
+		p.pager = ui.NewPager(fmt.Sprintf("Search %q: %d matches", msg.Query, len(msg.Hits)), strings.Join(lines, "\n"))
+		return p, nil
+	case ExportResultMsg:
+		if msg.Err != nil {
+			p.result = "❌ Export failed: " + msg.Err.Error()
+		} else {
+			p.result = fmt.Sprintf("✅ Exported %d messages to %s", msg.Count, msg.Path)
+		}
 		return p, nil
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "enter":
-			if p.input != "" {
-				// Send message using marchat-client
-				marchatClientPath := os.Getenv("GOPATH") + "\\bin\\marchat-client.exe"
-				cmd := exec.Command(marchatClientPath, "-username", "ForgerUser", "-admin", "-admin-key", "forger-admin-key", "-server", "ws://localhost:9090/ws")
-				cmd.Stdin = strings.NewReader(p.input + "\n")
-
-				err := cmd.Run()
-				if err != nil {
-					p.result = "❌ Failed to send message: " + err.Error()
-				} else {
-					p.result = "✅ Message sent: " + p.input
-					p.messages = append(p.messages, Message{Username: "You", Content: p.input, Timestamp: time.Now(), Type: "message"})
-				}
-				p.input = ""
+		if p.pager != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				p.pager = nil
+			default:
+				p.pager.Update(msg)
 			}
-		case "backspace":
-			if len(p.input) > 0 {
-				p.input = p.input[:len(p.input)-1]
+			return p, nil
+		}
+		if p.searching {
+			return p, p.updateSearch(msg)
+		}
+		if p.exporting {
+			return p, p.updateExport(msg)
+		}
+		if p.adminPanel.open {
+			return p, p.updateAdmin(msg)
+		}
+		if p.inserting {
+			// Core doesn't translate keys while a message is being typed,
+			// so search and export are matched here to work from insert mode.
+			for _, a := range p.ctx.Keys.Bind(p.Name(), p.Actions()) {
+				if (a.ID == "search" || a.ID == "export") && a.Key == msg.String() {
+					return p, p.runAction(a.ID)
+				}
 			}
+		}
+		switch msg.String() {
+		case "pgup":
+			p.scrollBy(visibleMessages)
+		case "pgdown":
+			p.scrollBy(-visibleMessages)
 		case "ctrl+c":
-			// Clean up server process
-			if p.serverProcess != nil {
-				p.serverProcess.Kill()
-			}
 			return p, tea.Quit
 		default:
-			// Handle regular character input
-			if len(msg.String()) == 1 && msg.String() != "tab" && msg.String() != "shift+tab" {
-				p.input += msg.String()
+			if p.inserting {
+				return p, p.updateComposer(msg)
+			}
+			switch msg.String() {
+			case "enter":
+				p.inserting = true
+			case "up":
+				p.selectBy(-1)
+			case "down":
+				p.selectBy(1)
+			case "/":
+				return p, p.runAction("search")
 			}
 		}
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
 	}
 	return p, nil
 }
 
+// Actions lists what can be done from the chat view.
+func (p *Plugin) Actions() []types.Action {
+	return []types.Action{
+		{ID: "compose", Title: "Type a message", Key: "i", Focus: true, Enabled: p.chatting},
+		{ID: "open-ref", Title: "Open ↗ reference in selected message", Key: "o", Enabled: func() bool { return p.chatting() && p.selected >= 0 }},
+		{ID: "next-channel", Title: "Next channel", Key: "]", Enabled: p.chatting},
+		{ID: "prev-channel", Title: "Previous channel", Key: "[", Enabled: p.chatting},
+		{ID: "admin", Title: "Admin panel", Key: "A", Focus: true, Enabled: func() bool { return p.admin && (p.chatting() || p.adminIdle()) }},
+		{ID: "search", Title: "Search history", Key: "ctrl+f", Focus: true, Enabled: p.browsing},
+		{ID: "export", Title: "Export channel", Key: "ctrl+e", Focus: true, Enabled: p.browsing},
+		{ID: "kick", Title: "Kick selected user", Key: "k", Mode: "admin", Focus: true, Enabled: p.userSelected},
+		{ID: "ban", Title: "Ban selected user", Key: "b", Mode: "admin", Focus: true, Enabled: p.userSelected},
+		{ID: "unban", Title: "Unban a user by name", Key: "u", Mode: "admin", Focus: true, Enabled: p.adminIdle},
+		{ID: "stats", Title: "Server stats", Key: "s", Mode: "admin", Enabled: p.adminIdle},
+		{ID: "cleardb", Title: "Clear all history", Key: "x", Mode: "admin", Focus: true, Enabled: p.adminIdle},
+	}
+}
+
+// browsing reports whether the chat view is shown, with no prompt, report
+// or admin panel over it.
+func (p *Plugin) browsing() bool {
+	return p.pager == nil && !p.searching && !p.exporting && !p.adminPanel.open
+}
+
+// chatting reports whether the chat view is shown and no message is being
+// typed.
+func (p *Plugin) chatting() bool {
+	return p.browsing() && !p.inserting
+}
+
+func (p *Plugin) runAction(id string) tea.Cmd {
+	switch id {
+	case "compose":
+		p.inserting = true
+	case "open-ref":
+		return p.openRef()
+	case "next-channel":
+		return p.cycleChannel(1)
+	case "prev-channel":
+		return p.cycleChannel(-1)
+	case "admin":
+		if p.adminPanel.open {
+			p.adminPanel.open = false
+		} else if p.admin {
+			p.openAdmin()
+		}
+	case "kick", "ban", "unban", "stats", "cleardb":
+		return p.runAdminAction(id)
+	case "search":
+		p.searching = true
+		p.query = ""
+	case "export":
+		p.exporting = true
+		if p.exportPath == "" {
+			p.exportPath = "marchat-" + p.channel + ".md"
+		}
+	}
+	return nil
+}
+
+// updateComposer handles keys in insert mode: Enter sends, Esc leaves
+// insert mode and everything else edits the message.
+func (p *Plugin) updateComposer(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		p.inserting = false
+	case "enter":
+		content := p.editor.Value()
+		if strings.TrimSpace(content) == "" {
+			return nil
+		}
+		p.editor.Push(content)
+		p.editor.Reset()
+		if strings.HasPrefix(content, "/") {
+			return p.runCommand(content)
+		}
+		return p.send(content)
+	default:
+		p.editor.Update(msg)
+	}
+	return nil
+}
+
+// send shows content as our own message in the current channel, or as a
+// direct message in a DM thread, and sends it in the background.
+func (p *Plugin) send(content string) tea.Cmd {
+	msg := Message{Username: p.username, Content: content, Timestamp: time.Now(), Type: "message"}
+	if isDM(p.channel) {
+		msg.Recipient = strings.TrimPrefix(p.channel, "@")
+	} else {
+		msg.Channel = p.channel
+	}
+	p.pending[content]++
+	p.scroll = 0
+	p.selected = -1
+	cmd := p.record(msg)
+	client := p.client
+	return tea.Batch(cmd, func() tea.Msg {
+		return SendResultMsg{Content: content, Err: client.Send(msg.Content, msg.Channel, msg.Recipient)}
+	})
+}
+
+// CapturingInput reports whether keys should bypass Forger's global
+// shortcuts because text is being typed.
+func (p *Plugin) CapturingInput() bool {
+	return p.inserting || p.searching || p.exporting || p.adminPanel.unbanning
+}
+
+// selectBy moves the message selection, scrolling to keep it visible.
+// Moving past the newest message clears the selection.
+func (p *Plugin) selectBy(n int) {
+	msgs := p.current().Messages
+	if len(msgs) == 0 {
+		return
+	}
+	if p.selected < 0 {
+		if n > 0 {
+			return
+		}
+		p.selected = len(msgs) - 1 - p.scroll
+	} else {
+		p.selected += n
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+	if p.selected >= len(msgs) {
+		p.selected = -1
+		return
+	}
+	end := len(msgs) - p.scroll
+	if p.selected >= end {
+		p.scroll = len(msgs) - 1 - p.selected
+	} else if p.selected < end-visibleMessages {
+		p.scroll = len(msgs) - p.selected - visibleMessages
+	}
+}
+
+// openRef follows the first reference in the selected message.
+func (p *Plugin) openRef() tea.Cmd {
+	msgs := p.current().Messages
+	if p.selected < 0 || p.selected >= len(msgs) {
+		p.result = "Select a message with ↑/↓ first"
+		return nil
+	}
+	refs := types.ParseRefs(msgs[p.selected].Content)
+	if len(refs) == 0 {
+		p.result = "No Forger reference in that message"
+		return nil
+	}
+	ref := refs[0]
+	return func() tea.Msg { return types.OpenRefMsg{Ref: ref} }
+}
+
+// SearchItems offers the messages of every loaded channel to the fuzzy
+// finder, newest first.
+func (p *Plugin) SearchItems() []types.SearchItem {
+	var items []types.SearchItem
+	for _, name := range p.channelNames() {
+		msgs := p.channels[name].Messages
+		for i := len(msgs) - 1; i >= 0; i-- {
+			m := msgs[i]
+			items = append(items, types.SearchItem{
+				Plugin: p.Name(),
+				Kind:   "message",
+				Title:  m.Username + ": " + strings.ReplaceAll(m.Content, "\n", " "),
+				Detail: channelLabel(name) + " " + m.Timestamp.Format("2006-01-02 15:04"),
+				ID:     name + "\n" + messageKey(m),
+			})
+		}
+	}
+	return items
+}
+
+// showMessage switches to channel and selects the message identified by
+// key, scrolling it into view.
+func (p *Plugin) showMessage(channel, key string) tea.Cmd {
+	cmd := p.switchTo(channel)
+	msgs := p.current().Messages
+	for i, m := range msgs {
+		if messageKey(m) == key {
+			p.selected = i
+			p.scroll = len(msgs) - 1 - i
+			p.scrollBy(0)
+			return cmd
+		}
+	}
+	p.result = "❌ That message is no longer in " + channelLabel(channel)
+	return cmd
+}
+
+func (p *Plugin) scrollBy(n int) {
+	p.scroll += n
+	if last := len(p.current().Messages) - visibleMessages; p.scroll > last {
+		p.scroll = last
+	}
+	if p.scroll < 0 {
+		p.scroll = 0
+	}
+}
+
+// updateSearch edits the search query; Enter searches every channel's
+// stored history.
+func (p *Plugin) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		p.searching = false
+		query, history := p.query, p.history
+		return func() tea.Msg {
+			hits, err := history.Search(query)
+			return SearchResultMsg{Query: query, Hits: hits, Err: err}
+		}
+	case "esc":
+		p.searching = false
+	case "backspace":
+		if r := []rune(p.query); len(r) > 0 {
+			p.query = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		p.query = ""
+	default:
+		if msg.Type == tea.KeyRunes {
+			p.query += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
+// updateExport edits the export path. Ctrl+F cycles the file extension
+// through the supported formats.
+func (p *Plugin) updateExport(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		path, channel := p.exportPath, p.channel
+		msgs := append([]Message(nil), p.current().Messages...)
+		p.exporting = false
+		p.result = "Exporting history to " + path + "..."
+		return func() tea.Msg { return exportHistory(path, channel, msgs) }
+	case "esc":
+		p.exporting = false
+	case "backspace":
+		if r := []rune(p.exportPath); len(r) > 0 {
+			p.exportPath = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		p.exportPath = ""
+	case "ctrl+f":
+		ext := filepath.Ext(p.exportPath)
+		next := exportExtensions[0]
+		for i, e := range exportExtensions {
+			if e == strings.ToLower(ext) {
+				next = exportExtensions[(i+1)%len(exportExtensions)]
+			}
+		}
+		p.exportPath = strings.TrimSuffix(p.exportPath, ext) + next
+	default:
+		if msg.Type == tea.KeyRunes {
+			p.exportPath += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
 func (p *Plugin) View() string {
 	var sb strings.Builder
 
+	if p.pager != nil {
+		sb.WriteString(p.pager.View())
+		sb.WriteString("\n↑/↓ PgUp/PgDn scroll • Esc back")
+		return sb.String()
+	}
+	if p.adminPanel.open {
+		return p.adminView()
+	}
+
 	sb.WriteString("┌─ MarChat ───────────────────────────────────────────────────┐\n")
 	sb.WriteString("│                                                             │\n")
 
-	if !p.serverRunning {
+	if !p.serverRunning && !p.connected {
 		sb.WriteString("│  ❌ MarChat Server Not Available                        │\n")
 		sb.WriteString("│                                                             │\n")
 		sb.WriteString("│  To use MarChat:                                       │\n")
@@ -158,15 +725,22 @@ func (p *Plugin) View() string {
 		sb.WriteString("│  3. Connect client: marchat-client                     │\n")
 		sb.WriteString("│                                                             │\n")
 		sb.WriteString("│  Server Status: " + p.getServerStatus() + "                    │\n")
+		if p.errorMsg != "" {
+			line := p.errorMsg
+			if len(line) > 55 {
+				line = line[:52] + "..."
+			}
+			sb.WriteString(fmt.Sprintf("│  %-57s │\n", line))
+		}
 	} else {
 		sb.WriteString("│  ✅ MarChat Server Available                            │\n")
 		sb.WriteString("│                                                             │\n")
 
 		// Show connection status
-		if p.serverRunning {
-			sb.WriteString("│  🔗 Server started and running                        │\n")
+		if p.connected {
+			sb.WriteString(fmt.Sprintf("│  🔗 Connected as %-20s %3d online       │\n", p.username, len(p.users)))
 		} else {
-			sb.WriteString("│  🔌 Server not running                               │\n")
+			sb.WriteString("│  🔌 Not connected, reconnecting...                   │\n")
 		}
 		sb.WriteString("│                                                             │\n")
 
@@ -176,48 +750,145 @@ func (p *Plugin) View() string {
 			sb.WriteString("│                                                             │\n")
 		}
 
-		sb.WriteString("│  Chat History:                                          │\n")
+		if p.scroll > 0 {
+			sb.WriteString(fmt.Sprintf("│  Chat History: %-13s ↓ %d newer                   │\n", channelLabel(p.channel), p.scroll))
+		} else {
+			sb.WriteString(fmt.Sprintf("│  Chat History: %-13s                             │\n", channelLabel(p.channel)))
+		}
 		sb.WriteString("│  ┌─────────────────────────────────────────────────────┐ │\n")
 
-		// Show last 5 messages
-		start := len(p.messages) - 5
+		// Show a window of messages, scrolled back from the newest
+		msgs := p.current().Messages
+		end := len(msgs) - p.scroll
+		start := end - visibleMessages
 		if start < 0 {
 			start = 0
 		}
-		for i := start; i < len(p.messages); i++ {
-			msg := p.messages[i]
+		for i := start; i < end; i++ {
+			msg := msgs[i]
 			timeStr := msg.Timestamp.Format("15:04")
-			line := fmt.Sprintf("│  [%s] %s: %s", timeStr, msg.Username, msg.Content)
-			if len(line) > 55 {
-				line = line[:52] + "..."
+			content := strings.ReplaceAll(msg.Content, "\n", " ↵ ")
+			prefix := "│  "
+			if i == p.selected {
+				prefix = "│> "
+			}
+			if len(types.ParseRefs(msg.Content)) > 0 {
+				timeStr += " ↗"
 			}
-			sb.WriteString(fmt.Sprintf("│  %-55s │\n", line))
+			line := fmt.Sprintf("%s[%s] %s: %s", prefix, timeStr, msg.Username, content)
+			if r := []rune(line); len(r) > 55 {
+				line = string(r[:52]) + "..."
+			}
+			line = fmt.Sprintf("%-55s", line)
+			if msg.Username != p.username && p.mention.MatchString(msg.Content) {
+				line = mentionStyle.Render(line)
+			}
+			sb.WriteString("│  " + line + " │\n")
 		}
 
 		// Fill remaining space
-		remaining := 5 - (len(p.messages) - start)
+		remaining := visibleMessages - (end - start)
 		for i := 0; i < remaining; i++ {
 			sb.WriteString("│                                                         │\n")
 		}
 
 		sb.WriteString("│  └─────────────────────────────────────────────────────┘ │\n")
 		sb.WriteString("│                                                             │\n")
-		sb.WriteString(fmt.Sprintf("│  Message: [%-45s] │\n", p.input))
+		switch {
+		case p.searching:
+			sb.WriteString(fmt.Sprintf("│  Search:  [%-45s] │\n", p.query))
+		case p.exporting:
+			sb.WriteString(fmt.Sprintf("│  Export:  [%-45s] │\n", p.exportPath))
+		default:
+			label := "Message: "
+			if p.inserting {
+				label = "-INSERT- "
+			}
+			lines := p.editor.Lines(p.inserting)
+			if len(lines) > composerLines {
+				lines = lines[len(lines)-composerLines:]
+			}
+			for _, line := range lines {
+				sb.WriteString("│  " + label + "[" + line + strings.Repeat(" ", max(0, 45-lipgloss.Width(line))) + "] │\n")
+				label = "         "
+			}
+		}
 	}
 
 	sb.WriteString("│                                                             │\n")
 	sb.WriteString("│  Commands:                                                │\n")
-	sb.WriteString("│  • Enter: Send message                                    │\n")
-	sb.WriteString("│  • Ctrl+C: Quit                                           │\n")
-	sb.WriteString("│  • Backspace: Edit message                                │\n")
+	if p.inserting {
+		sb.WriteString("│  • Enter: Send  • Ctrl+J: New line  • Esc: Stop typing    │\n")
+		sb.WriteString("│  • ←/→ Alt+B/F: Move  • ↑/↓: Input history                │\n")
+		sb.WriteString("│  • Ctrl+W: Delete word  • Ctrl+U/K: Delete to start/end   │\n")
+		sb.WriteString("│  • Ctrl+F: Search history  • Ctrl+E: Export               │\n")
+	} else {
+		for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+			sb.WriteString(fmt.Sprintf("│  • %-54s │\n", line))
+		}
+		sb.WriteString("│  • ↑/↓: Select message  • /join /leave /dm /channels      │\n")
+		sb.WriteString("│  • Ctrl+C: Quit                                           │\n")
+	}
+	sb.WriteString("│  • PgUp/PgDn: Scroll history                              │\n")
 	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
 
+	if !p.connected && len(p.channels) <= 1 {
+		return sb.String()
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, p.sidebarView(), " ", sb.String())
+}
+
+var (
+	mentionStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+	activeStyle  = lipgloss.NewStyle().Reverse(true)
+)
+
+// sidebarView lists channels and DMs with unread and mention counts.
+func (p *Plugin) sidebarView() string {
+	var sb strings.Builder
+	sb.WriteString("Channels\n")
+	dms := false
+	for _, name := range p.channelNames() {
+		ch := p.channels[name]
+		if isDM(name) && !dms {
+			dms = true
+			sb.WriteString("\nDirect\n")
+		}
+		label := channelLabel(name)
+		if r := []rune(label); len(r) > 12 {
+			label = string(r[:11]) + "…"
+		}
+		line := fmt.Sprintf("%-12s", label)
+		switch {
+		case ch.Mentions > 0:
+			line += mentionStyle.Render(fmt.Sprintf(" %2d@", ch.Mentions))
+		case ch.Unread > 0:
+			line += fmt.Sprintf(" %3d", ch.Unread)
+		default:
+			line += "    "
+		}
+		if name == p.channel {
+			line = activeStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
 	return sb.String()
 }
 
 func (p *Plugin) getServerStatus() string {
-	if p.serverRunning {
+	switch p.serverState {
+	case ServerReady:
 		return "Running"
+	case ServerExternal:
+		return "Running (external)"
+	case ServerLoopback:
+		return "Running (loopback)"
+	case ServerStarting:
+		return "Starting"
+	case ServerCrashed:
+		return "Crashed, restarting"
+	case ServerStopped:
+		return "Stopped"
 	}
 	return "Not Found"
 }
@@ -226,7 +897,37 @@ func (p *Plugin) Name() string {
 	return "marchat"
 }
 
-type ServerCheckMsg struct {
-	Available bool
-	Error     string
+// ConnectedMsg is sent when the client (re)establishes its connection.
+type ConnectedMsg struct{}
+
+// DisconnectedMsg is sent when the connection drops or a dial fails.
+type DisconnectedMsg struct {
+	Err     error
+	RetryIn time.Duration
+}
+
+// IncomingMsg carries a chat message received from the server.
+type IncomingMsg struct {
+	Message Message
+}
+
+// UserListMsg carries the server's list of connected users.
+type UserListMsg struct {
+	Users []string
+}
+
+// ChannelListMsg carries the channels the server knows about.
+type ChannelListMsg struct {
+	Channels []string
+}
+
+// AuthFailedMsg is sent when the server rejects the handshake.
+type AuthFailedMsg struct {
+	Reason string
+}
+
+// SendResultMsg reports the outcome of sending a message.
+type SendResultMsg struct {
+	Content string
+	Err     error
 }
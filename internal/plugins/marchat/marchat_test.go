@@ -0,0 +1,401 @@
+package marchat
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"forger/internal/plugins/marchat/fakeserver"
+	"forger/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// harness runs a Plugin against a fake server the way Bubble Tea would:
+// commands run in the background and their messages go back to Update.
+type harness struct {
+	t    *testing.T
+	p    *Plugin
+	msgs chan tea.Msg
+	seen []tea.Msg
+}
+
+// newHarness connects a plugin for username to srv. History is kept in a
+// temporary working directory.
+func newHarness(t *testing.T, srv *fakeserver.Server, username string, admin bool) *harness {
+	t.Helper()
+	url, err := srv.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	chdirTemp(t)
+	t.Setenv(defaultAdminKeyEnv, "secret")
+
+	cfg, _ := json.Marshal(Config{ServerURL: url, Username: username, Admin: &admin})
+	p := New(&types.Context{PluginConfig: map[string]json.RawMessage{"marchat": cfg}}).(*Plugin)
+	if p.errorMsg != "" {
+		t.Fatal(p.errorMsg)
+	}
+	h := &harness{t: t, p: p, msgs: make(chan tea.Msg, 256)}
+	_, cmd := p.ensureChannel(defaultChannel)
+	h.run(tea.Batch(cmd, p.connect()))
+	t.Cleanup(func() { p.Close() })
+	h.until("connect", func() bool { return p.connected })
+	return h
+}
+
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func (h *harness) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		switch msg := cmd().(type) {
+		case nil:
+		case tea.BatchMsg:
+			for _, c := range msg {
+				h.run(c)
+			}
+		default:
+			h.msgs <- msg
+		}
+	}()
+}
+
+// until feeds messages to the plugin until cond holds.
+func (h *harness) until(what string, cond func() bool) {
+	h.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case msg := <-h.msgs:
+			h.seen = append(h.seen, msg)
+			_, cmd := h.p.Update(msg)
+			h.run(cmd)
+		case <-timeout:
+			h.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// count returns how many messages in the current channel have content.
+func (h *harness) count(content string) int {
+	n := 0
+	for _, m := range h.p.current().Messages {
+		if m.Content == content {
+			n++
+		}
+	}
+	return n
+}
+
+// logged returns how many messages in the channel's history file have content.
+func (h *harness) logged(content string) int {
+	h.p.history.Flush()
+	msgs, err := h.p.history.Load(defaultChannel)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	n := 0
+	for _, m := range msgs {
+		if m.Content == content {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSendIsDeduplicated(t *testing.T) {
+	srv := fakeserver.New()
+	h := newHarness(t, srv, "alice", false)
+
+	h.run(h.p.send("hello"))
+	h.until("the echo", func() bool { return h.p.pending["hello"] == 0 })
+
+	if got := len(srv.Messages()); got != 1 {
+		t.Fatalf("server has %d messages, want 1", got)
+	}
+	if got := h.count("hello"); got != 1 {
+		t.Errorf("shown %d times, want 1", got)
+	}
+	if got := h.logged("hello"); got != 1 {
+		t.Errorf("logged %d times, want 1", got)
+	}
+}
+
+// TestPasteIsNotSent checks that line breaks in pasted text don't send
+// the message; Enter sends the whole block.
+func TestPasteIsNotSent(t *testing.T) {
+	srv := fakeserver.New()
+	h := newHarness(t, srv, "alice", false)
+	h.p.inserting = true
+
+	block := "func main() {\n\tfmt.Println(\"hi\")\n}"
+	_, cmd := h.p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(block), Paste: true})
+	h.run(cmd)
+	if got := h.p.editor.Value(); got != block {
+		t.Fatalf("editor = %q, want %q", got, block)
+	}
+	if got := len(srv.Messages()); got != 0 {
+		t.Fatalf("server has %d messages after the paste, want 0", got)
+	}
+
+	_, cmd = h.p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	h.run(cmd)
+	h.until("the echo", func() bool { return h.p.pending[block] == 0 })
+	if got := srv.Messages(); len(got) != 1 || got[0].Content != block {
+		t.Errorf("server has %+v, want one message with the block", got)
+	}
+	if got := h.count(block); got != 1 {
+		t.Errorf("shown %d times, want 1", got)
+	}
+}
+
+// TestClearDB checks that local history is cleared only when the server
+// confirms that it cleared its own.
+func TestClearDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		admin   bool
+		cleared bool
+	}{
+		{"as admin", true, true},
+		{"as user", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New()
+			srv.AdminKey = "secret"
+			srv.Admins = []string{"alice"}
+			h := newHarness(t, srv, "alice", tt.admin)
+
+			h.run(h.p.send("before"))
+			h.until("the echo", func() bool { return h.p.pending["before"] == 0 })
+
+			h.run(h.p.runAdmin(adminAction{Command: "cleardb"}))
+			h.until("the reply", func() bool { return len(h.p.adminPanel.log) > 0 && !h.p.adminPanel.clearing })
+			if tt.cleared {
+				h.until("the local clear", func() bool { return strings.HasPrefix(h.p.result, "✅") })
+			}
+			if got := h.count("before") + h.logged("before"); (got == 0) != tt.cleared {
+				t.Errorf("message kept %d times; cleared = %t, want %t", got, got == 0, tt.cleared)
+			}
+			if got := len(srv.Messages()); (got == 0) != tt.cleared {
+				t.Errorf("server kept %d messages", got)
+			}
+		})
+	}
+}
+
+func TestClearConfirmed(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  bool
+	}{
+		{"Cleared 12 messages", true},
+		{"Database cleared", true},
+		{"Admin commands require admin privileges", false},
+		{"Failed to clear database: disk full", false},
+		{"Unknown command: cleardb", false},
+		{"Users online: 2", false},
+	}
+	for _, tt := range tests {
+		if got := clearConfirmed(tt.reply); got != tt.want {
+			t.Errorf("clearConfirmed(%q) = %t, want %t", tt.reply, got, tt.want)
+		}
+	}
+}
+
+// TestHistoryWritesInOrder checks that queued appends and clears reach
+// the disk in the order they were queued, and that Close writes them all.
+func TestHistoryWritesInOrder(t *testing.T) {
+	h := &History{Dir: t.TempDir()}
+	var cmds []tea.Cmd
+	for _, content := range []string{"a", "b"} {
+		cmds = append(cmds, h.Save(defaultChannel, Message{Content: content}))
+	}
+	cmds = append(cmds, h.Clear())
+	for _, content := range []string{"c", "d", "e"} {
+		cmds = append(cmds, h.Save(defaultChannel, Message{Content: content}))
+	}
+	h.Close()
+
+	for _, cmd := range cmds {
+		switch msg := cmd().(type) {
+		case HistorySavedMsg:
+			t.Errorf("save failed: %v", msg.Err)
+		case HistoryClearedMsg:
+			if msg.Err != nil {
+				t.Errorf("clear failed: %v", msg.Err)
+			}
+		}
+	}
+	msgs, err := h.Load(defaultChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range msgs {
+		got = append(got, m.Content)
+	}
+	if strings.Join(got, "") != "cde" {
+		t.Errorf("history = %q, want [c d e]", got)
+	}
+}
+
+func TestHistoryChannelNames(t *testing.T) {
+	h := &History{Dir: t.TempDir()}
+	channels := []string{"@bob", "dev ops", "dev_ops", defaultChannel}
+	for _, channel := range channels {
+		if err := h.Append(channel, Message{Content: "in " + channel}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A fresh History reads the names back from the index.
+	h = &History{Dir: h.Dir}
+	if got, want := strings.Join(h.Channels(), ","), "@bob,dev ops,dev_ops,general"; got != want {
+		t.Errorf("Channels() = %s, want %s", got, want)
+	}
+	for _, channel := range channels {
+		msgs, err := h.Load(channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 1 || msgs[0].Content != "in "+channel {
+			t.Errorf("Load(%q) = %+v", channel, msgs)
+		}
+	}
+
+	if err := h.ClearAll(); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Channels(); len(got) != 0 {
+		t.Errorf("Channels() after ClearAll = %v", got)
+	}
+}
+
+// TestRecallSeedsCurrentChannel checks that Up recalls our own messages
+// from the channel being shown, not from other channels' history.
+func TestRecallSeedsCurrentChannel(t *testing.T) {
+	chdirTemp(t)
+	cfg, _ := json.Marshal(Config{Username: "alice"})
+	p := New(&types.Context{PluginConfig: map[string]json.RawMessage{"marchat": cfg}}).(*Plugin)
+	for _, name := range []string{defaultChannel, "random"} {
+		p.ensureChannel(name)
+		p.Update(HistoryMsg{Channel: name, Messages: []Message{{Username: "alice", Content: "said in " + name}}})
+	}
+
+	p.editor.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got, want := p.editor.Value(), "said in "+defaultChannel; got != want {
+		t.Errorf("recalled %q, want %q", got, want)
+	}
+	p.editor.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got, want := p.editor.Value(), "said in "+defaultChannel; got != want {
+		t.Errorf("recalled %q from another channel, want only %q", got, want)
+	}
+}
+
+func TestReconnectDoesNotLogReplayTwice(t *testing.T) {
+	srv := fakeserver.New()
+	h := newHarness(t, srv, "alice", false)
+
+	h.run(h.p.send("mine"))
+	h.until("the echo", func() bool { return h.p.pending["mine"] == 0 })
+	srv.Post(fakeserver.Message{Sender: "bob", Content: "theirs"})
+	h.until("bob's message", func() bool { return h.count("theirs") == 1 })
+
+	srv.DropAll()
+	h.until("the disconnect", func() bool { return !h.p.connected })
+	h.until("the reconnect", func() bool { return h.p.connected })
+
+	var retry time.Duration
+	for _, msg := range h.seen {
+		if d, ok := msg.(DisconnectedMsg); ok {
+			retry = d.RetryIn
+		}
+	}
+	if retry != minBackoff {
+		t.Errorf("retried in %s, want %s", retry, minBackoff)
+	}
+
+	// The replay arrives after the reconnect; a marker posted now comes
+	// after it on the same connection.
+	srv.Post(fakeserver.Message{Sender: "bob", Content: "marker"})
+	h.until("the marker", func() bool { return h.count("marker") == 1 })
+
+	for _, content := range []string{"mine", "theirs"} {
+		if got := h.count(content); got != 1 {
+			t.Errorf("%q shown %d times, want 1", content, got)
+		}
+		if got := h.logged(content); got != 1 {
+			t.Errorf("%q logged %d times, want 1", content, got)
+		}
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		admin   bool
+		command string
+		reply   string
+	}{
+		{"kick as admin", true, "kick", "Kicked bob"},
+		{"ban as admin", true, "ban", "Banned bob"},
+		{"kick as user", false, "kick", "Admin commands require admin privileges"},
+		{"ban as user", false, "ban", "Admin commands require admin privileges"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeserver.New()
+			srv.AdminKey = "secret"
+			srv.Admins = []string{"alice"}
+			h := newHarness(t, srv, "alice", tt.admin)
+
+			bob := NewClient(h.p.serverURL, "bob", false, "")
+			go bob.Run()
+			defer bob.Close()
+			h.until("bob to join", func() bool { return strings.Contains(strings.Join(h.p.users, ","), "bob") })
+
+			h.run(h.p.runAdmin(adminAction{Command: tt.command, Target: "bob"}))
+			h.until("the reply", func() bool { return len(h.p.adminPanel.log) > 0 })
+			if got := h.p.adminPanel.log[0].Content; got != tt.reply {
+				t.Errorf("reply = %q, want %q", got, tt.reply)
+			}
+
+			online := func() bool {
+				for _, u := range srv.Users() {
+					if u == "bob" {
+						return true
+					}
+				}
+				return false
+			}
+			switch {
+			case tt.command == "ban" && tt.admin:
+				h.until("bob to leave", func() bool { return !online() })
+				time.Sleep(2 * minBackoff) // bob's reconnect is refused
+				if online() {
+					t.Error("banned user reconnected")
+				}
+			case !tt.admin && !online():
+				t.Error("bob was disconnected by a non-admin")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package marchat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// handshake is the first frame a client sends after connecting.
+type handshake struct {
+	Username string `json:"username"`
+	Admin    bool   `json:"admin"`
+	AdminKey string `json:"admin_key,omitempty"`
+}
+
+// wireMessage is a chat message as marchat sends it over the socket.
+type wireMessage struct {
+	Sender    string    `json:"sender"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type,omitempty"`
+	Channel   string    `json:"channel,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+}
+
+// control is a client request that isn't a chat message, such as joining
+// a channel.
+type control struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// envelope wraps non-chat frames such as the user list.
+type envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type userList struct {
+	Users []string `json:"users"`
+}
+
+type channelList struct {
+	Channels []string `json:"channels"`
+}
+
+// decodeFrame turns one incoming frame into a Bubble Tea message. Frames
+// that carry a "data" payload are control frames; anything else is chat.
+func decodeFrame(data []byte) (interface{}, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err == nil && env.Data != nil {
+		switch env.Type {
+		case "userlist":
+			var ul userList
+			if err := json.Unmarshal(env.Data, &ul); err != nil {
+				return nil, err
+			}
+			return UserListMsg{Users: ul.Users}, nil
+		case "channels":
+			var cl channelList
+			if err := json.Unmarshal(env.Data, &cl); err != nil {
+				return nil, err
+			}
+			return ChannelListMsg{Channels: cl.Channels}, nil
+		case "auth_failed":
+			var reason struct {
+				Reason string `json:"reason"`
+			}
+			json.Unmarshal(env.Data, &reason)
+			return AuthFailedMsg{Reason: reason.Reason}, nil
+		}
+	}
+
+	var wm wireMessage
+	if err := json.Unmarshal(data, &wm); err != nil {
+		return nil, err
+	}
+	if wm.CreatedAt.IsZero() {
+		wm.CreatedAt = time.Now()
+	}
+	msgType := wm.Type
+	if msgType == "" || msgType == "text" {
+		msgType = "message"
+	}
+	return IncomingMsg{Message: Message{
+		Username:  wm.Sender,
+		Content:   wm.Content,
+		Timestamp: wm.CreatedAt,
+		Type:      msgType,
+		Channel:   wm.Channel,
+		Recipient: wm.Recipient,
+	}}, nil
+}
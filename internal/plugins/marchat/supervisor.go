@@ -0,0 +1,239 @@
+package marchat
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ServerState is the supervisor's view of the marchat server.
+type ServerState string
+
+const (
+	ServerMissing  ServerState = "missing"  // no server binary installed
+	ServerStarting ServerState = "starting" // launched, waiting for the port
+	ServerReady    ServerState = "ready"    // our process is accepting connections
+	ServerExternal ServerState = "external" // someone else's server owns the port
+	ServerCrashed  ServerState = "crashed"  // exited or never became ready; will retry
+	ServerStopped  ServerState = "stopped"
+	ServerLoopback ServerState = "loopback" // in-process fake server (loopback mode)
+)
+
+const (
+	readyTimeout    = 10 * time.Second
+	readyPoll       = 200 * time.Millisecond
+	externalPoll    = 5 * time.Second
+	maxRestartDelay = time.Minute
+)
+
+// Supervisor owns the marchat-server process: it reuses a server that is
+// already listening, otherwise starts one with output captured to a log,
+// waits for it to accept connections, and restarts it with backoff if it
+// exits. Status changes are delivered through Listen.
+type Supervisor struct {
+	Path       string // server executable
+	ConfigPath string
+	LogPath    string
+	Addr       string // host:port the server listens on
+
+	events chan tea.Msg
+	stop   chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	proc *os.Process
+	done chan struct{} // closed when proc exits
+}
+
+// NewSupervisor returns a Supervisor; call Run to start supervising.
+func NewSupervisor(path, configPath, logPath, addr string) *Supervisor {
+	return &Supervisor{
+		Path:       path,
+		ConfigPath: configPath,
+		LogPath:    logPath,
+		Addr:       addr,
+		events:     make(chan tea.Msg, 16),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Listen returns a command that waits for the next status change.
+func (s *Supervisor) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-s.events:
+			return msg
+		case <-s.stop:
+			return nil
+		}
+	}
+}
+
+func (s *Supervisor) emit(state ServerState, detail string) {
+	select {
+	case s.events <- ServerStatusMsg{State: state, Detail: detail}:
+	case <-s.stop:
+	}
+}
+
+// portOpen reports whether something accepts TCP connections on addr.
+func portOpen(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Run supervises until Stop is called.
+func (s *Supervisor) Run() {
+	delay := time.Second
+	for {
+		if portOpen(s.Addr) {
+			s.emit(ServerExternal, "using server already listening on "+s.Addr)
+			if !s.sleep(externalPoll) {
+				return
+			}
+			for portOpen(s.Addr) {
+				if !s.sleep(externalPoll) {
+					return
+				}
+			}
+			continue
+		}
+
+		if _, err := os.Stat(s.Path); err != nil {
+			s.emit(ServerMissing, fmt.Sprintf("marchat-server not found at: %s", s.Path))
+			return
+		}
+
+		started := time.Now()
+		err := s.runOnce()
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		// A server that stayed up a while earns a fresh backoff.
+		if time.Since(started) > maxRestartDelay {
+			delay = time.Second
+		}
+		s.emit(ServerCrashed, fmt.Sprintf("%v; restarting in %s (see %s)", err, delay, s.LogPath))
+		if !s.sleep(delay) {
+			return
+		}
+		if delay *= 2; delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// runOnce starts the server, waits for readiness and then for it to exit.
+func (s *Supervisor) runOnce() error {
+	if err := os.MkdirAll(filepath.Dir(s.LogPath), 0o755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(s.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "--- starting %s at %s ---\n", s.Path, time.Now().Format(time.RFC3339))
+
+	cmd := exec.Command(s.Path, "-config", s.ConfigPath)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start marchat-server: %v", err)
+	}
+
+	done := make(chan struct{})
+	var waitErr error
+	go func() {
+		waitErr = cmd.Wait()
+		close(done)
+	}()
+	s.mu.Lock()
+	s.proc, s.done = cmd.Process, done
+	s.mu.Unlock()
+
+	s.emit(ServerStarting, fmt.Sprintf("PID %d, waiting for %s", cmd.Process.Pid, s.Addr))
+	if err := s.waitReady(done); err != nil {
+		cmd.Process.Kill()
+		<-done
+		return err
+	}
+	s.emit(ServerReady, fmt.Sprintf("PID %d listening on %s", cmd.Process.Pid, s.Addr))
+
+	<-done
+	if waitErr == nil {
+		return fmt.Errorf("marchat-server exited")
+	}
+	return fmt.Errorf("marchat-server exited: %v", waitErr)
+}
+
+func (s *Supervisor) waitReady(done chan struct{}) error {
+	deadline := time.Now().Add(readyTimeout)
+	for time.Now().Before(deadline) {
+		if portOpen(s.Addr) {
+			return nil
+		}
+		select {
+		case <-done:
+			return fmt.Errorf("marchat-server exited during startup")
+		case <-s.stop:
+			return fmt.Errorf("stopped")
+		case <-time.After(readyPoll):
+		}
+	}
+	return fmt.Errorf("marchat-server not ready after %s", readyTimeout)
+}
+
+// sleep waits for d, returning false if Stop was called meanwhile.
+func (s *Supervisor) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// Stop ends supervision and terminates a server this supervisor started.
+// Servers found already running are left alone.
+func (s *Supervisor) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.mu.Lock()
+		proc, done := s.proc, s.done
+		s.mu.Unlock()
+		if proc == nil {
+			return
+		}
+		// Windows has no SIGINT for child processes; kill outright there.
+		if runtime.GOOS == "windows" || proc.Signal(os.Interrupt) != nil {
+			proc.Kill()
+		}
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			proc.Kill()
+			<-done
+		}
+	})
+}
+
+// ServerStatusMsg reports a change in the supervised server's state.
+type ServerStatusMsg struct {
+	State  ServerState
+	Detail string
+}
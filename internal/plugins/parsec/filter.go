@@ -0,0 +1,111 @@
+package parsec
+
+import "strings"
+
+// filter selects entries. Its terms are separated by spaces and all must
+// match: level>=warn (also >, <=, <, or level=warn for exactly), key=value
+// for a field containing value, -word to exclude lines containing word,
+// and any other word for lines containing it. Matching ignores case.
+type filter struct {
+	minLevel Level
+	maxLevel Level
+	fields   map[string]string
+	words    []string
+	exclude  []string
+}
+
+func parseFilter(s string) filter {
+	f := filter{maxLevel: LevelFatal, fields: make(map[string]string)}
+	for _, term := range strings.Fields(strings.ToLower(s)) {
+		if rest, ok := strings.CutPrefix(term, "level"); ok && rest != "" {
+			if f.levelTerm(rest) {
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(term, "-") && len(term) > 1:
+			f.exclude = append(f.exclude, term[1:])
+		case strings.Contains(term, "=") && !strings.HasPrefix(term, "="):
+			k, v, _ := strings.Cut(term, "=")
+			f.fields[k] = v
+		default:
+			f.words = append(f.words, term)
+		}
+	}
+	return f
+}
+
+// levelTerm applies the comparison after "level" in a term, reporting
+// whether it was one.
+func (f *filter) levelTerm(rest string) bool {
+	for _, op := range []string{">=", "<=", ">", "<", "=", ":"} {
+		name, ok := strings.CutPrefix(rest, op)
+		if !ok {
+			continue
+		}
+		l := ParseLevel(name)
+		if l == LevelNone {
+			return false
+		}
+		switch op {
+		case ">=":
+			f.minLevel = l
+		case ">":
+			f.minLevel = min(l+1, LevelFatal)
+		case "<=":
+			f.maxLevel = l
+		case "<":
+			f.maxLevel = max(l-1, LevelTrace)
+		default:
+			f.minLevel, f.maxLevel = l, l
+		}
+		return true
+	}
+	return false
+}
+
+func (f filter) match(e Entry) bool {
+	if f.minLevel != LevelNone && (e.Level < f.minLevel || e.Level > f.maxLevel) {
+		return false
+	}
+	if f.maxLevel != LevelFatal && e.Level > f.maxLevel {
+		return false
+	}
+	for k, v := range f.fields {
+		if !strings.Contains(strings.ToLower(e.field(k)), v) {
+			return false
+		}
+	}
+	if len(f.words) == 0 && len(f.exclude) == 0 {
+		return true
+	}
+	raw := strings.ToLower(e.Raw)
+	for _, w := range f.exclude {
+		if strings.Contains(raw, w) {
+			return false
+		}
+	}
+	for _, w := range f.words {
+		if !strings.Contains(raw, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// field returns a field of e by name, including "msg" and "level". Keys
+// are matched without regard to case.
+func (e Entry) field(key string) string {
+	switch key {
+	case "msg", "message":
+		return e.Message
+	case "level":
+		return e.Level.String()
+	}
+	for k, v := range e.Fields {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package parsec
+
+import "testing"
+
+func TestParseFilter(t *testing.T) {
+	entries := map[string]Entry{
+		"debug": {Level: LevelDebug, Message: "cache warm", Raw: "DEBUG cache warm", Fields: map[string]string{"svc": "Cache"}},
+		"info":  {Level: LevelInfo, Message: "request done", Raw: "INFO request done status=200", Fields: map[string]string{"status": "200"}},
+		"warn":  {Level: LevelWarn, Message: "slow request", Raw: "WARN slow request status=200", Fields: map[string]string{"status": "200"}},
+		"error": {Level: LevelError, Message: "request failed", Raw: "ERROR request failed status=500", Fields: map[string]string{"status": "500"}},
+		"plain": {Message: "starting up", Raw: "starting up", Fields: map[string]string{}},
+	}
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"debug", "error", "info", "plain", "warn"}},
+		{"level>=warn", []string{"error", "warn"}},
+		{"LEVEL>warn", []string{"error"}},
+		{"level=info", []string{"info"}},
+		{"level:info", []string{"info"}},
+		{"level>=info level<error", []string{"info", "warn"}},
+		{"level<=debug", []string{"debug", "plain"}},
+		{"status=500", []string{"error"}},
+		{"svc=cache", []string{"debug"}},
+		{"msg=request", []string{"error", "info", "warn"}},
+		{"request -slow", []string{"error", "info"}},
+		{"Request Failed", []string{"error"}},
+		{"levelx", nil},
+		{"level>=bogus", nil},
+		{"-", nil},
+	}
+	for _, tt := range tests {
+		f := parseFilter(tt.filter)
+		var got []string
+		for _, name := range []string{"debug", "error", "info", "plain", "warn"} {
+			if f.match(entries[name]) {
+				got = append(got, name)
+			}
+		}
+		if !equal(got, tt.want) {
+			t.Errorf("filter %q matched %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,338 @@
+package parsec
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Level is the severity of an entry.
+type Level int
+
+const (
+	LevelNone Level = iota // no level given
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{"", "TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel reads the level names and numbers used by common loggers:
+// "warning", "WRN", "err", "crit", zerolog's and pino's numbers.
+func ParseLevel(s string) Level {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil {
+		// pino and bunyan: 10 trace ... 60 fatal.
+		switch {
+		case n >= 60:
+			return LevelFatal
+		case n >= 50:
+			return LevelError
+		case n >= 40:
+			return LevelWarn
+		case n >= 30:
+			return LevelInfo
+		case n >= 20:
+			return LevelDebug
+		case n >= 10:
+			return LevelTrace
+		}
+		return LevelNone
+	}
+	switch s {
+	case "trace", "trc", "verbose":
+		return LevelTrace
+	case "debug", "dbg", "d":
+		return LevelDebug
+	case "info", "inf", "information", "notice", "i":
+		return LevelInfo
+	case "warn", "warning", "wrn", "w":
+		return LevelWarn
+	case "error", "err", "erro", "e":
+		return LevelError
+	case "fatal", "panic", "crit", "critical", "alert", "emerg", "dpanic", "ftl":
+		return LevelFatal
+	}
+	return LevelNone
+}
+
+// Entry is one parsed log line or test event.
+type Entry struct {
+	Time    time.Time // zero when the line has none
+	Level   Level
+	Message string
+	Fields  map[string]string // everything else, with dotted keys for nested JSON
+	Raw     string
+}
+
+// Formats that lines can be parsed as.
+const (
+	FormatAuto   = "auto"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatGoTest = "gotest"
+)
+
+// Keys commonly used for the time, level and message of a structured log
+// line, in order of preference.
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t", "date"}
+	levelKeys   = []string{"level", "lvl", "severity", "levelname", "log.level"}
+	messageKeys = []string{"msg", "message", "@message", "event", "text"}
+)
+
+// Parse parses line in format, or detects the format when it is auto.
+// Lines that aren't structured become entries with just a message.
+func Parse(line, format string) Entry {
+	trimmed := strings.TrimSpace(line)
+	switch format {
+	case FormatJSON, FormatGoTest:
+		if e, ok := parseJSON(line); ok {
+			return e
+		}
+	case FormatLogfmt:
+		if e, ok := parseLogfmt(line); ok {
+			return e
+		}
+	default:
+		if strings.HasPrefix(trimmed, "{") {
+			if e, ok := parseJSON(line); ok {
+				return e
+			}
+		}
+		if e, ok := parseLogfmt(line); ok {
+			return e
+		}
+	}
+	return Entry{Message: line, Level: guessLevel(line), Fields: map[string]string{}, Raw: line}
+}
+
+func parseJSON(line string) (Entry, bool) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return Entry{}, false
+	}
+	fields := make(map[string]string)
+	flatten("", obj, fields)
+	if _, ok := fields["Action"]; ok {
+		return goTestEntry(line, fields), true
+	}
+	return structured(line, fields), true
+}
+
+func flatten(prefix string, v interface{}, fields map[string]string) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		fields[prefix] = scalar(v)
+		return
+	}
+	for k, child := range obj {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		flatten(k, child, fields)
+	}
+}
+
+func scalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// goTestEntry turns a test2json event into an entry: failures are errors,
+// skips warnings, passes info, output debug and the rest trace.
+func goTestEntry(line string, fields map[string]string) Entry {
+	e := Entry{Raw: line, Fields: map[string]string{}}
+	e.Time, _ = time.Parse(time.RFC3339Nano, fields["Time"])
+	action, test, output := fields["Action"], fields["Test"], strings.TrimRight(fields["Output"], "\n")
+	if pkg := fields["Package"]; pkg != "" {
+		e.Fields["package"] = pkg
+	}
+	if test != "" {
+		e.Fields["test"] = test
+	}
+	if elapsed := fields["Elapsed"]; elapsed != "" {
+		e.Fields["elapsed"] = elapsed + "s"
+	}
+
+	switch action {
+	case "fail":
+		e.Level = LevelError
+	case "skip":
+		e.Level = LevelWarn
+	case "pass":
+		e.Level = LevelInfo
+	case "output":
+		e.Level = LevelDebug
+		trimmed := strings.TrimSpace(output)
+		switch {
+		case strings.HasPrefix(trimmed, "--- FAIL"), strings.HasPrefix(trimmed, "panic:"), trimmed == "FAIL" || strings.HasPrefix(trimmed, "FAIL\t"):
+			e.Level = LevelError
+		case strings.HasPrefix(trimmed, "--- SKIP"):
+			e.Level = LevelWarn
+		}
+	default:
+		e.Level = LevelTrace
+	}
+
+	switch {
+	case action == "output":
+		e.Message = output
+	case test != "":
+		e.Message = fmt.Sprintf("%s %s", strings.ToUpper(action), test)
+	default:
+		e.Message = fmt.Sprintf("%s %s", strings.ToUpper(action), fields["Package"])
+	}
+	return e
+}
+
+// structured picks the time, level and message out of a log line's fields.
+func structured(line string, fields map[string]string) Entry {
+	e := Entry{Raw: line, Fields: fields}
+	if k, v := take(fields, timeKeys); k != "" {
+		if t, ok := parseTime(v); ok {
+			e.Time = t
+		} else {
+			fields[k] = v
+		}
+	}
+	if _, v := take(fields, levelKeys); v != "" {
+		e.Level = ParseLevel(v)
+	}
+	_, e.Message = take(fields, messageKeys)
+	return e
+}
+
+// take removes and returns the first of keys present in fields.
+func take(fields map[string]string, keys []string) (string, string) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			return k, v
+		}
+	}
+	return "", ""
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"}
+
+// parseTime reads RFC 3339 style times and Unix times in seconds or
+// milliseconds.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+		if f > 1e12 {
+			return time.UnixMilli(int64(f)), true
+		}
+		return time.Unix(0, int64(f*1e9)), true
+	}
+	return time.Time{}, false
+}
+
+// parseLogfmt parses key=value pairs, with double-quoted values when they
+// contain spaces. A line is logfmt only if every token is a pair, so
+// plain text with the odd "=" in it is left alone.
+func parseLogfmt(line string) (Entry, bool) {
+	fields := make(map[string]string)
+	s := strings.TrimSpace(line)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.IndexFunc(s[:eq], unicode.IsSpace) >= 0 {
+			return Entry{}, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := 1
+			for end < len(s) && (s[end] != '"' || s[end-1] == '\\') {
+				end++
+			}
+			if end >= len(s) {
+				return Entry{}, false
+			}
+			v, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				v = s[1:end]
+			}
+			value, s = v, s[end+1:]
+		} else if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+			value, s = s[:i], s[i:]
+		} else {
+			value, s = s, ""
+		}
+		fields[key] = value
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	}
+	if len(fields) == 0 {
+		return Entry{}, false
+	}
+	return structured(line, fields), true
+}
+
+// guessLevel finds a level word near the start of an unstructured line,
+// such as "ERROR" or "[warn]".
+func guessLevel(line string) Level {
+	words := strings.FieldsFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for i, w := range words {
+		if i >= 4 {
+			break
+		}
+		if len(w) >= 4 || strings.ToUpper(w) == w {
+			if l := ParseLevel(w); l != LevelNone && len(w) > 1 {
+				return l
+			}
+		}
+	}
+	return LevelNone
+}
+
+// commonFields returns the field keys that appear in entries, most
+// frequent first.
+func commonFields(entries []Entry) []string {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		for k := range e.Fields {
+			counts[k]++
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
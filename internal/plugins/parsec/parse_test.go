@@ -0,0 +1,118 @@
+package parsec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		line   string
+		format string
+		want   Entry
+	}{
+		{
+			name:   "json",
+			line:   `{"time":"2024-05-01T12:00:00Z","level":"warning","msg":"disk low","disk":{"free":"3%"}}`,
+			format: FormatAuto,
+			want:   Entry{Time: ts, Level: LevelWarn, Message: "disk low", Fields: map[string]string{"disk.free": "3%"}},
+		},
+		{
+			name:   "json numeric level and unix millis",
+			line:   `{"ts":1714564800000,"level":50,"message":"boom"}`,
+			format: FormatJSON,
+			want:   Entry{Time: time.UnixMilli(1714564800000), Level: LevelError, Message: "boom", Fields: map[string]string{}},
+		},
+		{
+			name:   "json with unparsable time keeps it",
+			line:   `{"time":"yesterday","msg":"x"}`,
+			format: FormatAuto,
+			want:   Entry{Message: "x", Fields: map[string]string{"time": "yesterday"}},
+		},
+		{
+			name:   "logfmt",
+			line:   `ts=2024-05-01T12:00:00Z lvl=err msg="request failed" path=/api status=500`,
+			format: FormatAuto,
+			want:   Entry{Time: ts, Level: LevelError, Message: "request failed", Fields: map[string]string{"path": "/api", "status": "500"}},
+		},
+		{
+			name:   "go test failure",
+			line:   `{"Time":"2024-05-01T12:00:00Z","Action":"fail","Package":"forger/x","Test":"TestA","Elapsed":0.5}`,
+			format: FormatGoTest,
+			want:   Entry{Time: ts, Level: LevelError, Message: "FAIL TestA", Fields: map[string]string{"package": "forger/x", "test": "TestA", "elapsed": "0.5s"}},
+		},
+		{
+			name:   "go test output",
+			line:   `{"Action":"output","Package":"forger/x","Output":"--- SKIP: TestB\n"}`,
+			format: FormatAuto,
+			want:   Entry{Level: LevelWarn, Message: "--- SKIP: TestB", Fields: map[string]string{"package": "forger/x"}},
+		},
+		{
+			name:   "plain text guesses the level",
+			line:   "2024/05/01 [ERROR] connection refused",
+			format: FormatAuto,
+			want:   Entry{Level: LevelError, Message: "2024/05/01 [ERROR] connection refused", Fields: map[string]string{}},
+		},
+		{
+			name:   "plain text with an equals sign",
+			line:   "set x=1 then retry",
+			format: FormatAuto,
+			want:   Entry{Message: "set x=1 then retry", Fields: map[string]string{}},
+		},
+		{
+			name:   "json line forced to logfmt",
+			line:   `{"msg":"x"}`,
+			format: FormatLogfmt,
+			want:   Entry{Message: `{"msg":"x"}`, Fields: map[string]string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Raw = tt.line
+			got := Parse(tt.line, tt.format)
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("Time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time, tt.want.Time = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line   string
+		fields map[string]string
+		ok     bool
+	}{
+		{`a=1 b=two`, map[string]string{"a": "1", "b": "two"}, true},
+		{`msg="hello world" n=2`, map[string]string{"n": "2"}, true},
+		{`msg="say \"hi\""`, map[string]string{}, true},
+		{`empty= next=x`, map[string]string{"empty": "", "next": "x"}, true},
+		{`  padded=yes  `, map[string]string{"padded": "yes"}, true},
+		{`a=1 stray`, nil, false},
+		{`=1`, nil, false},
+		{`msg="unterminated`, nil, false},
+		{``, nil, false},
+	}
+	for _, tt := range tests {
+		e, ok := parseLogfmt(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseLogfmt(%q) ok = %t, want %t", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(e.Fields, tt.fields) {
+			t.Errorf("parseLogfmt(%q) fields = %v, want %v", tt.line, e.Fields, tt.fields)
+		}
+	}
+
+	e, _ := parseLogfmt(`msg="say \"hi\""`)
+	if e.Message != `say "hi"` {
+		t.Errorf("escaped message = %q, want %q", e.Message, `say "hi"`)
+	}
+}
@@ -0,0 +1,658 @@
+// Package parsec implements the parsec plugin, which turns structured
+// logs, test output and other machine-readable CLI output into a readable
+// table. It reads JSON lines, logfmt and go test -json from a file, a
+// command or stdin, and follows the file or command as it writes more.
+package parsec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"forger/internal/types"
+	"forger/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// maxEntries is how many entries are kept; older ones are dropped.
+	maxEntries = 10000
+	// maxColumns is how many field columns are shown by default.
+	maxColumns = 3
+	// columnWidth caps the width of a field column.
+	columnWidth = 16
+)
+
+// Config is the "parsec" section of forger.json. Input piped into Forger
+// is read in preference to the file or command.
+type Config struct {
+	File    string   `json:"file"`    // a log file to read and follow
+	Command []string `json:"command"` // a command to read, such as ["go", "test", "-json", "./..."]
+	Dir     string   `json:"dir"`     // the command's working directory
+	Format  string   `json:"format"`  // auto (default), json, logfmt or gotest
+	Columns []string `json:"columns"` // field columns; the most common fields by default
+	Follow  *bool    `json:"follow"`  // keep the newest entry in view; on by default
+}
+
+// minLevels is the order L cycles the minimum level through.
+var minLevels = []Level{LevelNone, LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+var (
+	levelStyles = map[Level]lipgloss.Style{
+		LevelTrace: lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+		LevelDebug: lipgloss.NewStyle().Foreground(lipgloss.Color("12")),
+		LevelInfo:  lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+		LevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		LevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		LevelFatal: lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true).Reverse(true),
+	}
+	headerStyle = lipgloss.NewStyle().Bold(true).Underline(true)
+	dimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+type Plugin struct {
+	ctx      *types.Context
+	cfg      Config
+	src      *source
+	done     bool // the source has no more lines
+	entries  []Entry
+	visible  []int // indexes into entries that pass the filter
+	selected int   // index into visible
+	offset   int
+	follow   bool
+	filter   string
+	parsed   filter
+	minLevel Level
+	columns  []string
+	width    int
+	height   int
+	prompt   string // "filter" or "open" while editing
+	input    string
+	result   string
+	pager    *ui.Pager // details of the selected entry
+}
+
+func New(ctx *types.Context) types.Plugin {
+	p := &Plugin{ctx: ctx, follow: true, width: 80, height: 20, parsed: parseFilter("")}
+	if err := ctx.LoadPluginConfig("parsec", &p.cfg); err != nil {
+		p.result = "❌ Invalid config: " + err.Error()
+	}
+	switch p.cfg.Format {
+	case "":
+		p.cfg.Format = FormatAuto
+	case FormatAuto, FormatJSON, FormatLogfmt, FormatGoTest:
+	default:
+		p.result = fmt.Sprintf("❌ Unknown format %q (use auto, json, logfmt or gotest)", p.cfg.Format)
+		p.cfg.Format = FormatAuto
+	}
+	if p.cfg.Follow != nil {
+		p.follow = *p.cfg.Follow
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return "parsec"
+}
+
+func (p *Plugin) Init() tea.Cmd {
+	switch {
+	case stdinPiped():
+		return p.open(openStdin(), nil)
+	case len(p.cfg.Command) > 0:
+		return p.open(openCommand(p.cfg.Dir, p.cfg.Command))
+	case p.cfg.File != "":
+		return p.open(openFile(p.cfg.File))
+	}
+	return nil
+}
+
+// open switches to reading src, dropping what was read before.
+func (p *Plugin) open(src *source, err error) tea.Cmd {
+	if err != nil {
+		p.result = "❌ " + err.Error()
+		return types.Notify(p.Name(), types.SeverityError, err.Error())
+	}
+	if p.src != nil {
+		p.src.close()
+	}
+	p.src, p.done = src, false
+	p.entries, p.visible, p.selected, p.offset = nil, nil, 0, 0
+	p.result = ""
+	return src.listen()
+}
+
+// Close stops reading the current source.
+func (p *Plugin) Close() error {
+	if p.src != nil {
+		p.src.close()
+	}
+	return nil
+}
+
+func (p *Plugin) Update(msg tea.Msg) (types.Plugin, tea.Cmd) {
+	switch msg := msg.(type) {
+	case linesMsg:
+		// Lines from a source already replaced are dropped.
+		if msg.src != p.src {
+			return p, nil
+		}
+		p.add(msg.lines)
+		return p, p.src.listen()
+	case doneMsg:
+		if msg.src != p.src {
+			return p, nil
+		}
+		p.done = true
+		if msg.err != nil {
+			p.result = "❌ " + msg.err.Error()
+			return p, types.Notify(p.Name(), types.SeverityError, msg.err.Error())
+		}
+		p.result = fmt.Sprintf("✅ End of %s", p.src.name)
+		return p, nil
+	case tea.WindowSizeMsg:
+		if w := msg.Width - 26; w > 40 {
+			p.width = w
+		}
+		if h := msg.Height - 8; h > 5 {
+			p.height = h
+			if p.pager != nil {
+				p.pager.Height = h
+			}
+		}
+		p.scrollTo(p.selected)
+		return p, nil
+	case tea.KeyMsg:
+		if p.pager != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				p.pager = nil
+			default:
+				p.pager.Update(msg)
+			}
+			return p, nil
+		}
+		if p.prompt != "" {
+			return p, p.updatePrompt(msg)
+		}
+		return p, p.updateKeys(msg)
+	case types.ActionMsg:
+		if msg.Plugin == p.Name() {
+			return p, p.runAction(msg.ID)
+		}
+	}
+	return p, nil
+}
+
+func (p *Plugin) updateKeys(msg tea.KeyMsg) tea.Cmd {
+	page := max(1, p.rows()-1)
+	switch msg.String() {
+	case "up", "k":
+		p.moveTo(p.selected - 1)
+	case "down", "j":
+		p.moveTo(p.selected + 1)
+	case "pgup":
+		p.moveTo(p.selected - page)
+	case "pgdown", " ":
+		p.moveTo(p.selected + page)
+	case "home", "g":
+		p.moveTo(0)
+	case "end", "G":
+		p.follow = true
+		p.moveTo(len(p.visible) - 1)
+	case "esc":
+		if p.filter != "" || p.minLevel != LevelNone {
+			p.filter, p.parsed, p.minLevel = "", parseFilter(""), LevelNone
+			p.refilter()
+		}
+	case "enter":
+		if e, ok := p.current(); ok {
+			p.pager = ui.NewPager(e.Message, details(e))
+			p.pager.Height = p.height
+		}
+	}
+	return nil
+}
+
+// Actions lists what can be done with the log. Everything but opening and
+// reloading needs entries to work on.
+func (p *Plugin) Actions() []types.Action {
+	idle := func() bool { return p.pager == nil && p.prompt == "" }
+	reading := func() bool { return idle() && len(p.entries) > 0 }
+	return []types.Action{
+		{ID: "open", Title: "Open a file, or !command to run one", Key: "o", Focus: true, Enabled: idle},
+		{ID: "reload", Title: "Reload from the start", Key: "r", Enabled: idle},
+		{ID: "filter", Title: "Filter", Key: "/", Focus: true, Enabled: reading},
+		{ID: "level", Title: "Cycle minimum level", Key: "l", Enabled: reading},
+		{ID: "follow", Title: "Toggle follow", Key: "f", Enabled: reading},
+		{ID: "share", Title: "Share entry to chat", Key: "p", Enabled: reading},
+		{ID: "clear", Title: "Clear entries", Key: "x", Enabled: reading},
+	}
+}
+
+func (p *Plugin) runAction(id string) tea.Cmd {
+	switch id {
+	case "follow":
+		p.follow = !p.follow
+		if p.follow {
+			p.moveTo(len(p.visible) - 1)
+		}
+	case "level":
+		for i, l := range minLevels {
+			if l == p.minLevel {
+				p.minLevel = minLevels[(i+1)%len(minLevels)]
+				break
+			}
+		}
+		p.refilter()
+	case "filter":
+		p.prompt, p.input = "filter", p.filter
+	case "open":
+		p.prompt, p.input = "open", ""
+	case "reload":
+		return p.reload()
+	case "clear":
+		p.entries, p.visible, p.selected, p.offset = nil, nil, 0, 0
+		p.columns = nil
+	case "share":
+		if e, ok := p.current(); ok {
+			text := fmt.Sprintf("📜 parsec: %s\n%s", p.src.name, types.Excerpt(e.Raw, 10))
+			p.result = "✅ Shared to chat"
+			return func() tea.Msg { return types.ShareMsg{From: p.Name(), Text: text} }
+		}
+	}
+	return nil
+}
+
+// reload reopens the current source from the start.
+func (p *Plugin) reload() tea.Cmd {
+	switch {
+	case p.src == nil:
+		return p.Init()
+	case p.src.reopen == nil:
+		p.result = "❌ Standard input can't be read again"
+		return nil
+	}
+	return p.open(p.src.reopen())
+}
+
+// CapturingInput reports whether the filter or a path is being typed, so
+// typing q or c there doesn't trigger Forger's global shortcuts.
+func (p *Plugin) CapturingInput() bool {
+	return p.prompt != ""
+}
+
+// updatePrompt edits the filter, applying it as it is typed, or the file
+// or command to open.
+func (p *Plugin) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		prompt, input := p.prompt, strings.TrimSpace(p.input)
+		p.prompt = ""
+		if prompt == "open" && input != "" {
+			// A leading "!" runs a command instead of opening a file.
+			if cmd, ok := strings.CutPrefix(input, "!"); ok {
+				args := strings.Fields(cmd)
+				if len(args) == 0 {
+					return nil
+				}
+				return p.open(openCommand("", args))
+			}
+			return p.open(openFile(input))
+		}
+		return nil
+	case "esc":
+		if p.prompt == "filter" {
+			p.setFilter("")
+		}
+		p.prompt = ""
+		return nil
+	case "backspace":
+		if r := []rune(p.input); len(r) > 0 {
+			p.input = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		p.input = ""
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			p.input += string(msg.Runes)
+		}
+	}
+	if p.prompt == "filter" {
+		p.setFilter(p.input)
+	}
+	return nil
+}
+
+func (p *Plugin) setFilter(s string) {
+	p.filter, p.parsed = s, parseFilter(s)
+	p.refilter()
+}
+
+// add parses lines into entries, keeping at most maxEntries.
+func (p *Plugin) add(lines []string) {
+	dropped := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p.entries = append(p.entries, Parse(line, p.cfg.Format))
+	}
+	if over := len(p.entries) - maxEntries; over > 0 {
+		p.entries = append(p.entries[:0:0], p.entries[over:]...)
+		dropped = over
+	}
+	if p.columns == nil || len(p.cfg.Columns) == 0 && len(p.entries) < 1000 {
+		p.chooseColumns()
+	}
+
+	if dropped > 0 {
+		// Indexes shifted; start over.
+		p.refilter()
+		return
+	}
+	start := 0
+	if n := len(p.visible); n > 0 {
+		start = p.visible[n-1] + 1
+	}
+	for i := start; i < len(p.entries); i++ {
+		if p.matches(p.entries[i]) {
+			p.visible = append(p.visible, i)
+		}
+	}
+	if p.follow {
+		p.moveTo(len(p.visible) - 1)
+	}
+}
+
+// chooseColumns picks the configured field columns, or the most common
+// fields.
+func (p *Plugin) chooseColumns() {
+	if len(p.cfg.Columns) > 0 {
+		p.columns = p.cfg.Columns
+		return
+	}
+	p.columns = commonFields(p.entries)
+	if len(p.columns) > maxColumns {
+		p.columns = p.columns[:maxColumns]
+	}
+	sort.Strings(p.columns)
+}
+
+func (p *Plugin) matches(e Entry) bool {
+	return e.Level >= p.minLevel && p.parsed.match(e)
+}
+
+// refilter recomputes the visible entries, keeping the selection on the
+// same entry where it is still visible.
+func (p *Plugin) refilter() {
+	keep := -1
+	if e, ok := p.currentIndex(); ok {
+		keep = e
+	}
+	p.visible = p.visible[:0]
+	sel := 0
+	for i, e := range p.entries {
+		if p.matches(e) {
+			if i <= keep {
+				sel = len(p.visible)
+			}
+			p.visible = append(p.visible, i)
+		}
+	}
+	if p.follow {
+		sel = len(p.visible) - 1
+	}
+	p.moveTo(sel)
+}
+
+func (p *Plugin) currentIndex() (int, bool) {
+	if p.selected < 0 || p.selected >= len(p.visible) {
+		return 0, false
+	}
+	return p.visible[p.selected], true
+}
+
+func (p *Plugin) current() (Entry, bool) {
+	i, ok := p.currentIndex()
+	if !ok {
+		return Entry{}, false
+	}
+	return p.entries[i], true
+}
+
+// moveTo selects the visible entry at i. Moving off the last entry stops
+// following.
+func (p *Plugin) moveTo(i int) {
+	i = max(0, min(i, len(p.visible)-1))
+	if i < len(p.visible)-1 {
+		p.follow = false
+	}
+	p.selected = i
+	p.scrollTo(i)
+}
+
+func (p *Plugin) scrollTo(i int) {
+	rows := p.rows()
+	if i < p.offset {
+		p.offset = i
+	}
+	if i >= p.offset+rows {
+		p.offset = i - rows + 1
+	}
+	p.offset = max(0, min(p.offset, len(p.visible)-rows))
+}
+
+// rows is how many entries fit in the table.
+func (p *Plugin) rows() int {
+	return max(1, p.height-5)
+}
+
+// details lists every part of an entry for the pager.
+func details(e Entry) string {
+	var sb strings.Builder
+	if !e.Time.IsZero() {
+		sb.WriteString("time     " + e.Time.Format("2006-01-02 15:04:05.000 MST") + "\n")
+	}
+	if e.Level != LevelNone {
+		sb.WriteString("level    " + e.Level.String() + "\n")
+	}
+	sb.WriteString("message  " + strings.ReplaceAll(e.Message, "\n", "\n         ") + "\n")
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%-8s %s\n", k, e.Fields[k]))
+	}
+	sb.WriteString("\nraw\n" + e.Raw)
+	return sb.String()
+}
+
+func (p *Plugin) View() string {
+	if p.pager != nil {
+		return p.pager.View() + "\n↑/↓ PgUp/PgDn scroll • Esc back"
+	}
+	if p.src == nil {
+		return p.emptyView()
+	}
+
+	var sb strings.Builder
+	state := "reading"
+	switch {
+	case p.done:
+		state = "finished"
+	case p.follow:
+		state = "following"
+	}
+	header := fmt.Sprintf("Parsec ─ %s • %d/%d entries • %s", p.src.name, len(p.visible), len(p.entries), state)
+	sb.WriteString(fit(header, p.width) + "\n")
+
+	switch {
+	case p.prompt == "filter":
+		sb.WriteString(fit("Filter: "+p.input+"█", p.width) + "\n")
+	case p.prompt == "open":
+		sb.WriteString(fit("Open file (or !command): "+p.input+"█", p.width) + "\n")
+	case p.filter != "" || p.minLevel != LevelNone:
+		desc := "Filter: " + p.filter
+		if p.minLevel != LevelNone {
+			desc += fmt.Sprintf(" [level ≥ %s]", p.minLevel)
+		}
+		sb.WriteString(fit(desc, p.width) + "\n")
+	default:
+		sb.WriteString("\n")
+	}
+
+	widths, columns := p.layout()
+	head := []string{"TIME", "LEVEL"}
+	for _, c := range columns {
+		head = append(head, strings.ToUpper(c))
+	}
+	head = append(head, "MESSAGE")
+	sb.WriteString("  " + headerStyle.Render(row(head, widths)) + "\n")
+
+	end := min(p.offset+p.rows(), len(p.visible))
+	for i := p.offset; i < end; i++ {
+		e := p.entries[p.visible[i]]
+		prefix := "  "
+		if i == p.selected {
+			prefix = "> "
+		}
+		sb.WriteString(prefix + p.formatEntry(e, columns, widths) + "\n")
+	}
+	for i := end - p.offset; i < p.rows(); i++ {
+		sb.WriteString("\n")
+	}
+
+	if p.result != "" {
+		sb.WriteString(fit(p.result, p.width) + "\n")
+	} else {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fit("Enter details • "+types.ActionHints(p.ctx.Keys.Bind(p.Name(), p.Actions())), p.width))
+	return sb.String()
+}
+
+// layout sizes the columns to the panel, dropping field columns that
+// would leave the message too little room.
+func (p *Plugin) layout() ([]int, []string) {
+	columns := p.columns
+	for {
+		widths := []int{12, 5}
+		used := 2 + 12 + 5 + 2
+		for _, c := range columns {
+			w := min(columnWidth, max(len(c), p.columnWidth(c)))
+			widths = append(widths, w)
+			used += w + 1
+		}
+		msg := p.width - used
+		if msg >= 24 || len(columns) == 0 {
+			return append(widths, max(10, msg)), columns
+		}
+		columns = columns[:len(columns)-1]
+	}
+}
+
+// columnWidth is the widest value of field c among the entries on screen.
+func (p *Plugin) columnWidth(c string) int {
+	w := 0
+	end := min(p.offset+p.rows(), len(p.visible))
+	for i := p.offset; i < end; i++ {
+		w = max(w, len([]rune(p.entries[p.visible[i]].Fields[c])))
+	}
+	return w
+}
+
+func (p *Plugin) formatEntry(e Entry, columns []string, widths []int) string {
+	ts := ""
+	if !e.Time.IsZero() {
+		ts = e.Time.Local().Format("15:04:05.000")
+	}
+	cells := []string{ts, e.Level.String()}
+	for _, c := range columns {
+		cells = append(cells, e.Fields[c])
+	}
+	cells = append(cells, strings.ReplaceAll(e.Message, "\n", " ⏎ "))
+
+	var sb strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		text := pad(cell, widths[i])
+		switch {
+		case i == 0:
+			text = dimStyle.Render(text)
+		case i == 1:
+			if style, ok := levelStyles[e.Level]; ok {
+				text = style.Render(text)
+			}
+		case i == len(cells)-1 && e.Level >= LevelError:
+			text = levelStyles[LevelError].Render(strings.TrimRight(text, " "))
+		}
+		sb.WriteString(text)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+func row(cells []string, widths []int) string {
+	for i := range cells {
+		cells[i] = pad(cells[i], widths[i])
+	}
+	return strings.TrimRight(strings.Join(cells, " "), " ")
+}
+
+// pad fits s to exactly n columns.
+func pad(s string, n int) string {
+	s = strings.ReplaceAll(s, "\t", " ")
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:max(0, n-1)]) + "…"
+	}
+	return s + strings.Repeat(" ", n-len(r))
+}
+
+// fit truncates s to n columns.
+func fit(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:max(0, n-1)]) + "…"
+	}
+	return s
+}
+
+func (p *Plugin) emptyView() string {
+	var sb strings.Builder
+	sb.WriteString("┌─ Parsec ───────────────────────────────────────────────────┐\n")
+	sb.WriteString("│                                                             │\n")
+	for _, line := range []string{
+		"Nothing to read. Parsec shows JSON lines, logfmt and",
+		"go test -json from a file, a command, or input piped",
+		"into Forger:",
+		"  go test -json ./... | forger",
+		"",
+		"Set \"file\" or \"command\" under plugins.parsec in",
+		"forger.json to open one at startup.",
+	} {
+		sb.WriteString(fmt.Sprintf("│  %-58s │\n", line))
+	}
+	sb.WriteString("│                                                             │\n")
+	if p.result != "" {
+		sb.WriteString(fmt.Sprintf("│  %-58s │\n", fit(p.result, 58)))
+		sb.WriteString("│                                                             │\n")
+	}
+	if p.prompt == "open" {
+		sb.WriteString(fmt.Sprintf("│  Open: [%-49s] │\n", fit(p.input, 49)))
+		sb.WriteString("│  Enter: open (!cmd runs a command) • Esc: cancel            │\n")
+		sb.WriteString("│                                                             │\n")
+	}
+	sb.WriteString("│  Commands:                                                  │\n")
+	for _, line := range types.ActionHelp(p.ctx.Keys.Bind(p.Name(), p.Actions())) {
+		sb.WriteString(fmt.Sprintf("│  • %-56s │\n", line))
+	}
+	sb.WriteString("│                                                             │\n")
+	sb.WriteString("└─────────────────────────────────────────────────────────────┘")
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package parsec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"forger/internal/runner"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// pollInterval is how often a followed file is checked for new lines.
+	pollInterval = 500 * time.Millisecond
+	// maxBatch is the most lines delivered in one message.
+	maxBatch = 500
+)
+
+// source is where lines are read from: a file, a command or stdin. Lines
+// are read in the background and delivered in batches by listen.
+type source struct {
+	name  string // for the header, such as "app.log" or "$ go test -json ./..."
+	lines chan string
+	quit  chan struct{}
+	stop  func()
+	err   error // why reading ended; set before lines is closed
+
+	reopen func() (*source, error) // reads it again from the start; nil for stdin
+}
+
+func newSource(name string) *source {
+	return &source{name: name, lines: make(chan string, 1024), quit: make(chan struct{}), stop: func() {}}
+}
+
+// send delivers line unless the source has been closed.
+func (s *source) send(line string) bool {
+	select {
+	case s.lines <- line:
+		return true
+	case <-s.quit:
+		return false
+	}
+}
+
+// close stops reading.
+func (s *source) close() {
+	select {
+	case <-s.quit:
+	default:
+		close(s.quit)
+		s.stop()
+	}
+}
+
+// listen waits for the next lines, taking whatever else is already
+// waiting along with them.
+func (s *source) listen() tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-s.lines
+		if !ok {
+			return doneMsg{src: s, err: s.err}
+		}
+		batch := []string{line}
+		for len(batch) < maxBatch {
+			select {
+			case line, ok := <-s.lines:
+				if !ok {
+					// Delivered by the next listen.
+					return linesMsg{src: s, lines: batch}
+				}
+				batch = append(batch, line)
+			default:
+				return linesMsg{src: s, lines: batch}
+			}
+		}
+		return linesMsg{src: s, lines: batch}
+	}
+}
+
+// openFile reads the file at path and then follows it for appended lines,
+// starting over if it is truncated, as when a log is rotated.
+func openFile(path string) (*source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := newSource(path)
+	s.reopen = func() (*source, error) { return openFile(path) }
+	go func() {
+		defer close(s.lines)
+		defer f.Close()
+		r := bufio.NewReader(f)
+		var offset int64
+		var partial string
+		for {
+			chunk, err := r.ReadString('\n')
+			offset += int64(len(chunk))
+			if err == nil {
+				if !s.send(strings.TrimRight(partial+chunk, "\r\n")) {
+					return
+				}
+				partial = ""
+				continue
+			}
+			if err != io.EOF {
+				s.err = err
+				return
+			}
+			// Hold on to a line still being written until it ends.
+			partial += chunk
+			select {
+			case <-s.quit:
+				return
+			case <-time.After(pollInterval):
+			}
+			if info, err := f.Stat(); err == nil && info.Size() < offset {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					s.err = err
+					return
+				}
+				r.Reset(f)
+				offset, partial = 0, ""
+			}
+		}
+	}()
+	return s, nil
+}
+
+// openCommand runs args and reads their output until they exit.
+func openCommand(dir string, args []string) (*source, error) {
+	stream, err := runner.Start(dir, args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	s := newSource("$ " + strings.Join(args, " "))
+	s.stop = stream.Stop
+	s.reopen = func() (*source, error) { return openCommand(dir, args) }
+	go func() {
+		defer close(s.lines)
+		for line := range stream.Lines {
+			if !s.send(line) {
+				stream.Stop()
+			}
+		}
+		if res := stream.Result(); res.Err != nil {
+			s.err = fmt.Errorf("%s: %v", strings.Join(res.Args, " "), res.Err)
+		}
+	}()
+	return s, nil
+}
+
+// stdinPiped reports whether Forger's standard input is a pipe or file,
+// as in "go test -json ./... | forger". Bubble Tea then reads keys from
+// the terminal instead.
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// openStdin reads standard input until it ends.
+func openStdin() *source {
+	s := newSource("stdin")
+	go func() {
+		defer close(s.lines)
+		r := bufio.NewReader(os.Stdin)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" && !s.send(strings.TrimRight(line, "\r\n")) {
+				return
+			}
+			if err != nil {
+				if err != io.EOF {
+					s.err = err
+				}
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// linesMsg carries lines read from a source.
+type linesMsg struct {
+	src   *source
+	lines []string
+}
+
+// doneMsg reports that a source has no more lines.
+type doneMsg struct {
+	src *source
+	err error
+}
@@ -0,0 +1,147 @@
+// Package runner runs the external commands plugins are built on. Plugins
+// call it from inside a tea.Cmd, so commands never block the UI.
+package runner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GopathTool is where plugins run the Go-installed tool name from:
+// GOPATH\bin\name.exe, rather than whatever PATH finds.
+func GopathTool(name string) string {
+	return os.Getenv("GOPATH") + "\\bin\\" + name + ".exe"
+}
+
+// Result is the outcome of a finished command.
+type Result struct {
+	Args     []string // the command and its arguments
+	Output   string   // stdout and stderr, interleaved
+	Err      error
+	Duration time.Duration
+}
+
+// Run runs name with args in dir, or the current directory when dir is
+// empty, and waits for it to finish.
+func Run(dir, name string, args ...string) Result {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	return Result{
+		Args:     append([]string{name}, args...),
+		Output:   string(output),
+		Err:      err,
+		Duration: time.Since(start),
+	}
+}
+
+// Stream is a running command whose output is read a line at a time, for
+// commands that report progress as they go.
+type Stream struct {
+	Args  []string
+	Lines <-chan string // stdout and stderr lines; closed when the command has exited
+
+	cmd    *exec.Cmd
+	start  time.Time
+	result Result
+	quit   chan struct{}
+	once   sync.Once
+}
+
+// Start starts name with args in dir and streams its output.
+func Start(dir, name string, args ...string) (*Stream, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	pr, pw := io.Pipe()
+	// The same writer for both means exec writes to it from one goroutine
+	// at a time, so lines from stdout and stderr don't interleave mid-line.
+	cmd.Stdout, cmd.Stderr = pw, pw
+	// Don't wait forever for children that outlive the command, such as
+	// test binaries left behind by a killed go test.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	lines := make(chan string, 256)
+	s := &Stream{
+		Args:  append([]string{name}, args...),
+		Lines: lines,
+		cmd:   cmd,
+		start: time.Now(),
+		quit:  make(chan struct{}),
+	}
+	waited := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		s.result = Result{Args: s.Args, Err: err, Duration: time.Since(s.start)}
+		close(waited)
+		pw.Close()
+	}()
+	go func() {
+		r := bufio.NewReader(pr)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				select {
+				case lines <- strings.TrimRight(line, "\r\n"):
+				case <-s.quit:
+					// Keep draining so the command isn't blocked writing.
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		<-waited
+		close(lines)
+	}()
+	return s, nil
+}
+
+// Next waits for the next line of output and returns it along with any
+// others already waiting, up to n lines in all, so a busy command doesn't
+// cause one update per line. It returns false once the command has exited
+// and all of its output has been read.
+func (s *Stream) Next(n int) ([]string, bool) {
+	line, ok := <-s.Lines
+	if !ok {
+		return nil, false
+	}
+	lines := []string{line}
+	for len(lines) < n {
+		select {
+		case line, ok := <-s.Lines:
+			if !ok {
+				return lines, true
+			}
+			lines = append(lines, line)
+		default:
+			return lines, true
+		}
+	}
+	return lines, true
+}
+
+// Result reports how the command ended once Lines is closed. Its Output is
+// empty, as the output went to Lines.
+func (s *Stream) Result() Result {
+	return s.result
+}
+
+// Stop kills the command. Lines is closed once it has exited; lines not
+// yet received are dropped.
+func (s *Stream) Stop() {
+	s.once.Do(func() {
+		close(s.quit)
+		if s.cmd.Process != nil {
+			s.cmd.Process.Kill()
+		}
+	})
+}
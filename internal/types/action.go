@@ -0,0 +1,103 @@
+package types
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Action is a command a plugin offers: listed in the command palette, and
+// run when its key is pressed while the plugin has focus.
+type Action struct {
+	ID    string // unique within the plugin
+	Title string
+	Key   string // default key, as tea.KeyMsg.String() writes it; "" for none
+	// Mode names the view the action belongs to when a plugin has several,
+	// such as a list and a report. Actions in different modes are never
+	// available together, so they may share a key.
+	Mode string
+	// Focus marks actions that open a prompt, pager or panel in the
+	// plugin. The command palette switches to the plugin before running
+	// them; other actions run where they are.
+	Focus bool
+	// Enabled reports whether the action can run now, such as only while a
+	// list has a selection. Nil means always.
+	Enabled func() bool
+}
+
+// Available reports whether a can run now.
+func (a Action) Available() bool {
+	return a.Enabled == nil || a.Enabled()
+}
+
+// Actor is implemented by plugins that declare their actions. Core turns
+// an action's key into an ActionMsg before the plugin sees the key, so the
+// plugin handles the action in one place however it was invoked.
+type Actor interface {
+	Actions() []Action
+}
+
+// ActionMsg asks Plugin to run the action with ID.
+type ActionMsg struct {
+	Plugin string
+	ID     string
+}
+
+// keyNames are how keys with names are written in help text.
+var keyNames = map[string]string{
+	"up": "↑", "down": "↓", "left": "←", "right": "→",
+	"pgup": "PgUp", "pgdown": "PgDn", " ": "Space",
+}
+
+// KeyLabel formats key for help text in the style of the plugin views:
+// letters in capitals, as in "S: Create snapshot", with shift spelled out
+// for capitals so "A" and "a" stay distinct.
+func KeyLabel(key string) string {
+	if key == "" {
+		return "—"
+	}
+	if name, ok := keyNames[key]; ok {
+		return name
+	}
+	if r := []rune(key); len(r) == 1 && unicode.IsUpper(r[0]) {
+		return "Shift+" + key
+	}
+	parts := strings.Split(key, "+")
+	for i, part := range parts {
+		switch {
+		case keyNames[part] != "":
+			parts[i] = keyNames[part]
+		case len([]rune(part)) == 1:
+			parts[i] = strings.ToUpper(part)
+		case part != "":
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+// ActionHelp lists the actions available now as "Key: Title" lines.
+func ActionHelp(actions []Action) []string {
+	var lines []string
+	for _, a := range actions {
+		if a.Key != "" && a.Available() {
+			lines = append(lines, KeyLabel(a.Key)+": "+a.Title)
+		}
+	}
+	return lines
+}
+
+// ActionHints lists the actions available now on one line, as in
+// "R refresh • P share summary to chat", for compact footers.
+func ActionHints(actions []Action) string {
+	var hints []string
+	for _, a := range actions {
+		if a.Key != "" && a.Available() {
+			r := []rune(a.Title)
+			if len(r) > 0 {
+				r[0] = unicode.ToLower(r[0])
+			}
+			hints = append(hints, KeyLabel(a.Key)+" "+string(r))
+		}
+	}
+	return strings.Join(hints, " • ")
+}
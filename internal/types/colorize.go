@@ -0,0 +1,14 @@
+package types
+
+// ColorizerPlugin is the name of the plugin that displays artifacts.
+const ColorizerPlugin = "ascii-colorizer"
+
+// ColorizeMsg asks the ascii-colorizer plugin to display an artifact:
+// an image file, or diagram text to color. Core brings the plugin to the
+// front; set Path or Text.
+type ColorizeMsg struct {
+	From  string // plugin sending the artifact
+	Title string
+	Path  string // PNG or JPEG image
+	Text  string // diagram or other text output
+}
@@ -0,0 +1,34 @@
+package types
+
+// Keymap holds the effective key bindings: Forger's global keys and the
+// keys the user rebound plugin actions to. Core builds it from the keymap
+// section of forger.json.
+type Keymap struct {
+	// Global maps each global action, such as "quit", to its keys.
+	Global map[string][]string
+	// Plugins maps a plugin's name to the keys its actions were rebound
+	// to, by action ID. An empty key unbinds the action.
+	Plugins map[string]map[string]string
+}
+
+// Key returns the key that runs plugin's action a. A nil Keymap keeps the
+// plugin's defaults.
+func (k *Keymap) Key(plugin string, a Action) string {
+	if k != nil {
+		if key, ok := k.Plugins[plugin][a.ID]; ok {
+			return key
+		}
+	}
+	return a.Key
+}
+
+// Bind returns plugin's actions with the keys the user chose, for listing
+// them and matching key presses against them.
+func (k *Keymap) Bind(plugin string, actions []Action) []Action {
+	bound := make([]Action, len(actions))
+	for i, a := range actions {
+		a.Key = k.Key(plugin, a)
+		bound[i] = a
+	}
+	return bound
+}
@@ -0,0 +1,42 @@
+package types
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// Severity ranks a notification.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeveritySuccess
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeveritySuccess:
+		return "success"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "info"
+}
+
+// NotifyMsg asks core to show a notification: briefly as a toast, then in
+// the notification list, with a badge on Source in the sidebar until that
+// plugin is viewed.
+type NotifyMsg struct {
+	Source   string // plugin name
+	Severity Severity
+	Text     string
+	Ref      *Ref // optional; Enter in the notification list follows it
+}
+
+// Notify returns a command that emits a notification from source.
+func Notify(source string, severity Severity, text string) tea.Cmd {
+	return func() tea.Msg {
+		return NotifyMsg{Source: source, Severity: severity, Text: text}
+	}
+}
@@ -1,6 +1,10 @@
 package types
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"encoding/json"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 // Plugin is the interface every plugin must implement.
 type Plugin interface {
@@ -10,7 +14,41 @@ type Plugin interface {
 	Name() string
 }
 
+// Closer is implemented by plugins holding resources, such as processes or
+// connections, that must be released when Forger exits.
+type Closer interface {
+	Close() error
+}
+
+// InputCapturer is implemented by plugins that sometimes need every key,
+// such as while text is being typed. Core suspends its global shortcuts,
+// other than ctrl+c, while CapturingInput reports true.
+type InputCapturer interface {
+	CapturingInput() bool
+}
+
 // Context holds shared mutable state for plugins.
 type Context struct {
 	GlobalState map[string]interface{}
+	// PluginConfig holds each plugin's raw section from forger.json.
+	PluginConfig map[string]json.RawMessage
+	// Keys holds the effective key bindings; plugins list their actions
+	// with the keys it gives them.
+	Keys *Keymap
+}
+
+// LoadPluginConfig decodes the named plugin's config section into v. A
+// missing section leaves v untouched.
+func (c *Context) LoadPluginConfig(name string, v interface{}) error {
+	raw, ok := c.PluginConfig[name]
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
 }
+
+// GlobalState keys shared between plugins.
+const (
+	// StateSnapshot holds the commit of the snapshot selected in ignoregrets.
+	StateSnapshot = "snapshot"
+)
@@ -0,0 +1,27 @@
+package types
+
+// SearchItem is something the fuzzy finder can jump to.
+type SearchItem struct {
+	Plugin string // the plugin that owns the item and opens it
+	Kind   string // shown beside the title, such as "file" or "snapshot"
+	Title  string // the text the query is matched against
+	Detail string // shown after the title but not matched
+	// Ref, when set, is opened as if it had been followed from chat.
+	// Otherwise the item is sent back to Plugin in a SearchSelectMsg,
+	// identified by ID.
+	Ref *Ref
+	ID  string
+}
+
+// Searchable is implemented by plugins that offer items to the fuzzy
+// finder. SearchItems is called when the finder opens, so it should return
+// what the plugin already has loaded rather than doing slow work.
+type Searchable interface {
+	SearchItems() []SearchItem
+}
+
+// SearchSelectMsg delivers an item picked in the fuzzy finder to the
+// plugin that owns it, after core has brought that plugin to the front.
+type SearchSelectMsg struct {
+	Item SearchItem
+}
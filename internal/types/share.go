@@ -0,0 +1,71 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RefScheme prefixes references to Forger views embedded in chat messages.
+const RefScheme = "forger://"
+
+// Ref points at something a plugin can show: a snapshot, or a line in a
+// file. It is written as forger://<plugin>/<target>[#L<line>].
+type Ref struct {
+	Plugin string
+	Target string
+	Line   int // 1-based; 0 when the reference has no line
+}
+
+func (r Ref) String() string {
+	s := RefScheme + r.Plugin + "/" + r.Target
+	if r.Line > 0 {
+		s += fmt.Sprintf("#L%d", r.Line)
+	}
+	return s
+}
+
+var refPattern = regexp.MustCompile(`forger://([A-Za-z0-9_-]+)/([^\s#]+)(?:#L([0-9]+))?`)
+
+// ParseRefs returns every reference in text, in order.
+func ParseRefs(text string) []Ref {
+	var refs []Ref
+	for _, m := range refPattern.FindAllStringSubmatch(text, -1) {
+		r := Ref{Plugin: m[1], Target: strings.TrimRight(m[2], ".,;:)]")}
+		if m[3] != "" {
+			r.Line, _ = strconv.Atoi(m[3])
+		}
+		refs = append(refs, r)
+	}
+	return refs
+}
+
+// ShareMsg asks the chat plugin to post Text to the current channel.
+type ShareMsg struct {
+	From string // plugin sharing the artifact
+	Text string
+}
+
+// OpenRefMsg asks core to switch to Ref.Plugin and that plugin to show
+// the referenced item.
+type OpenRefMsg struct {
+	Ref Ref
+}
+
+// Excerpt returns at most n lines of text, noting how many were left out.
+func Excerpt(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-n)
+}
+
+// FirstLine returns text up to its first line break.
+func FirstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
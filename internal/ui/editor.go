@@ -0,0 +1,298 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Editor is a multi-line text input. It edits runes rather than bytes, so
+// multi-byte characters are moved over and deleted as a unit, and keeps a
+// history of submitted entries that Up/Down recall from the first and last
+// lines.
+//
+// Pasted text arrives as one bracketed-paste key and is inserted whole, so
+// its line breaks become new lines rather than Enter presses. Ctrl+J and
+// Alt+Enter insert a line break when typing.
+type Editor struct {
+	Width      int // visible columns per line
+	MaxHistory int
+
+	text   []rune
+	cursor int // index into text
+
+	history []string
+	recall  int    // index into history while browsing, len(history) otherwise
+	draft   string // unsent text saved when browsing starts
+}
+
+// NewEditor returns an empty Editor.
+func NewEditor() *Editor {
+	return &Editor{Width: 45, MaxHistory: 100}
+}
+
+// Value returns the current text.
+func (e *Editor) Value() string {
+	return string(e.text)
+}
+
+// SetValue replaces the text and moves the cursor to its end.
+func (e *Editor) SetValue(s string) {
+	e.text = []rune(s)
+	e.cursor = len(e.text)
+}
+
+// Reset clears the text and stops browsing history.
+func (e *Editor) Reset() {
+	e.SetValue("")
+	e.recall = len(e.history)
+	e.draft = ""
+}
+
+// Push records s as the newest history entry.
+func (e *Editor) Push(s string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	if n := len(e.history); n == 0 || e.history[n-1] != s {
+		e.history = append(e.history, s)
+	}
+	if e.MaxHistory > 0 && len(e.history) > e.MaxHistory {
+		e.history = e.history[len(e.history)-e.MaxHistory:]
+	}
+	e.recall = len(e.history)
+}
+
+// Update applies an editing key. It reports whether the key was consumed;
+// Enter and Esc never are, so the owner decides what they mean.
+func (e *Editor) Update(msg tea.Msg) bool {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return false
+	}
+	if key.Paste {
+		e.insert(key.Runes)
+		return true
+	}
+	switch key.String() {
+	case "left", "ctrl+b":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case "right":
+		if e.cursor < len(e.text) {
+			e.cursor++
+		}
+	case "alt+b", "ctrl+left", "alt+left":
+		e.cursor = e.wordStart()
+	case "alt+f", "ctrl+right", "alt+right":
+		e.cursor = e.wordEnd()
+	case "home", "ctrl+a":
+		e.cursor = e.lineStart()
+	case "end":
+		e.cursor = e.lineEnd()
+	case "ctrl+home":
+		e.cursor = 0
+	case "ctrl+end":
+		e.cursor = len(e.text)
+	case "up":
+		if !e.moveLine(-1) {
+			e.browse(-1)
+		}
+	case "down":
+		if !e.moveLine(1) {
+			e.browse(1)
+		}
+	case "backspace", "ctrl+h":
+		if e.cursor > 0 {
+			e.delete(e.cursor-1, e.cursor)
+		}
+	case "delete", "ctrl+d":
+		if e.cursor < len(e.text) {
+			e.delete(e.cursor, e.cursor+1)
+		}
+	case "ctrl+w", "alt+backspace":
+		e.delete(e.wordStart(), e.cursor)
+	case "alt+d":
+		e.delete(e.cursor, e.wordEnd())
+	case "ctrl+u":
+		e.delete(e.lineStart(), e.cursor)
+	case "ctrl+k":
+		if end := e.lineEnd(); end > e.cursor {
+			e.delete(e.cursor, end)
+		} else if e.cursor < len(e.text) {
+			// At the end of a line, join it with the next.
+			e.delete(e.cursor, e.cursor+1)
+		}
+	case "ctrl+j", "alt+enter":
+		e.insert([]rune{'\n'})
+	case " ":
+		e.insert([]rune{' '})
+	default:
+		if key.Type != tea.KeyRunes || key.Alt {
+			return false
+		}
+		e.insert(key.Runes)
+	}
+	return true
+}
+
+func (e *Editor) insert(r []rune) {
+	// Normalize pasted CRLF and lone CR line breaks.
+	s := strings.ReplaceAll(strings.ReplaceAll(string(r), "\r\n", "\n"), "\r", "\n")
+	r = []rune(s)
+	text := make([]rune, 0, len(e.text)+len(r))
+	text = append(text, e.text[:e.cursor]...)
+	text = append(text, r...)
+	e.text = append(text, e.text[e.cursor:]...)
+	e.cursor += len(r)
+}
+
+func (e *Editor) delete(from, to int) {
+	if from >= to {
+		return
+	}
+	e.text = append(e.text[:from], e.text[to:]...)
+	e.cursor = from
+}
+
+// wordStart is the start of the word before the cursor.
+func (e *Editor) wordStart() int {
+	i := e.cursor
+	for i > 0 && !isWordRune(e.text[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(e.text[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd is the end of the word after the cursor.
+func (e *Editor) wordEnd() int {
+	i := e.cursor
+	for i < len(e.text) && !isWordRune(e.text[i]) {
+		i++
+	}
+	for i < len(e.text) && isWordRune(e.text[i]) {
+		i++
+	}
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func (e *Editor) lineStart() int {
+	i := e.cursor
+	for i > 0 && e.text[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+func (e *Editor) lineEnd() int {
+	i := e.cursor
+	for i < len(e.text) && e.text[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// moveLine moves the cursor to the same column on an adjacent line,
+// reporting false if there is no such line.
+func (e *Editor) moveLine(dir int) bool {
+	start := e.lineStart()
+	col := e.cursor - start
+	var target int
+	if dir < 0 {
+		if start == 0 {
+			return false
+		}
+		target = start - 1 // end of previous line
+		for target > 0 && e.text[target-1] != '\n' {
+			target--
+		}
+	} else {
+		end := e.lineEnd()
+		if end == len(e.text) {
+			return false
+		}
+		target = end + 1
+	}
+	e.cursor = target
+	if end := e.lineEnd(); target+col < end {
+		e.cursor = target + col
+	} else {
+		e.cursor = end
+	}
+	return true
+}
+
+// browse steps through history, keeping the unsent draft at the end.
+func (e *Editor) browse(dir int) {
+	next := e.recall + dir
+	if next < 0 || next > len(e.history) {
+		return
+	}
+	if e.recall == len(e.history) {
+		e.draft = string(e.text)
+	}
+	e.recall = next
+	if next == len(e.history) {
+		e.SetValue(e.draft)
+	} else {
+		e.SetValue(e.history[next])
+	}
+}
+
+// Lines returns the text split into lines, with the cursor drawn in
+// reverse video when focused and each line scrolled horizontally to keep the
+// cursor visible within Width.
+func (e *Editor) Lines(focused bool) []string {
+	var lines []string
+	lineStart := 0
+	for i := 0; i <= len(e.text); i++ {
+		if i < len(e.text) && e.text[i] != '\n' {
+			continue
+		}
+		line := e.text[lineStart:i]
+		col := -1
+		if focused && e.cursor >= lineStart && e.cursor <= i {
+			col = e.cursor - lineStart
+		}
+		lines = append(lines, e.renderLine(line, col))
+		lineStart = i + 1
+	}
+	return lines
+}
+
+func (e *Editor) renderLine(line []rune, col int) string {
+	width := e.Width
+	if width <= 1 {
+		width = 45
+	}
+	// Reserve a column for the cursor at end of line.
+	offset := 0
+	if col >= width {
+		offset = col - width + 1
+	}
+	end := offset + width
+	if end > len(line) {
+		end = len(line)
+	}
+	var sb strings.Builder
+	for i := offset; i < end; i++ {
+		if i == col {
+			sb.WriteString(cursorStyle.Render(string(line[i])))
+		} else {
+			sb.WriteRune(line[i])
+		}
+	}
+	if col == len(line) && col-offset < width {
+		sb.WriteString(cursorStyle.Render(" "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestEditorUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  string
+		keys   []tea.KeyMsg
+		want   string
+		cursor int
+	}{
+		{"type", "", []tea.KeyMsg{runes("hé"), {Type: tea.KeySpace, Runes: []rune{' '}}, runes("世")}, "hé 世", 4},
+		{"backspace multibyte", "añ", []tea.KeyMsg{{Type: tea.KeyBackspace}}, "a", 1},
+		{"insert mid-line", "ac", []tea.KeyMsg{{Type: tea.KeyLeft}, runes("b")}, "abc", 2},
+		{"delete word", "foo bar", []tea.KeyMsg{{Type: tea.KeyCtrlW}}, "foo ", 4},
+		{"delete word skips spaces", "foo bar  ", []tea.KeyMsg{{Type: tea.KeyCtrlW}}, "foo ", 4},
+		{"delete to line start", "ab\ncd", []tea.KeyMsg{{Type: tea.KeyCtrlU}}, "ab\n", 3},
+		{"ctrl+k joins lines", "ab\ncd", []tea.KeyMsg{{Type: tea.KeyUp}, {Type: tea.KeyCtrlK}}, "abcd", 2},
+		{"ctrl+k kills to end", "abcd", []tea.KeyMsg{{Type: tea.KeyHome}, {Type: tea.KeyRight}, {Type: tea.KeyCtrlK}}, "a", 1},
+		{"newline", "ab", []tea.KeyMsg{{Type: tea.KeyCtrlJ}, runes("c")}, "ab\nc", 4},
+		{"pasted CRLF", "", []tea.KeyMsg{runes("a\r\nb\rc")}, "a\nb\nc", 5},
+		{"pasted block", "> ", []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("one\ntwo\r\n  three"), Paste: true}}, "> one\ntwo\n  three", 17},
+		{"pasted key names", "", []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("q"), Paste: true}}, "q", 1},
+		{"up keeps column", "abc\nde", []tea.KeyMsg{{Type: tea.KeyUp}}, "abc\nde", 2},
+		{"down clamps to line end", "abc\nd", []tea.KeyMsg{{Type: tea.KeyUp}, {Type: tea.KeyEnd}, {Type: tea.KeyDown}}, "abc\nd", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEditor()
+			e.SetValue(tt.start)
+			for _, k := range tt.keys {
+				if !e.Update(k) {
+					t.Fatalf("key %q not consumed", k.String())
+				}
+			}
+			if got := e.Value(); got != tt.want {
+				t.Errorf("Value() = %q, want %q", got, tt.want)
+			}
+			if e.cursor != tt.cursor {
+				t.Errorf("cursor = %d, want %d", e.cursor, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestEditorLeavesEnterAndEsc(t *testing.T) {
+	e := NewEditor()
+	for _, k := range []tea.KeyMsg{{Type: tea.KeyEnter}, {Type: tea.KeyEsc}, {Type: tea.KeyRunes, Runes: []rune("x"), Alt: true}} {
+		if e.Update(k) {
+			t.Errorf("key %q consumed", k.String())
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		push  []string
+		draft string
+		keys  []tea.KeyType
+		want  string
+	}{
+		{"newest first", []string{"one", "two"}, "", []tea.KeyType{tea.KeyUp}, "two"},
+		{"older", []string{"one", "two"}, "", []tea.KeyType{tea.KeyUp, tea.KeyUp}, "one"},
+		{"stops at oldest", []string{"one", "two"}, "", []tea.KeyType{tea.KeyUp, tea.KeyUp, tea.KeyUp}, "one"},
+		{"draft restored", []string{"one"}, "draft", []tea.KeyType{tea.KeyUp, tea.KeyDown}, "draft"},
+		{"blank and repeats skipped", []string{"one", " ", "one"}, "", []tea.KeyType{tea.KeyUp, tea.KeyUp}, "one"},
+		{"trimmed to MaxHistory", []string{"one", "two", "three"}, "", []tea.KeyType{tea.KeyUp, tea.KeyUp, tea.KeyUp}, "two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEditor()
+			e.MaxHistory = 2
+			for _, s := range tt.push {
+				e.Push(s)
+			}
+			e.SetValue(tt.draft)
+			for _, k := range tt.keys {
+				e.Update(tea.KeyMsg{Type: k})
+			}
+			if got := e.Value(); got != tt.want {
+				t.Errorf("Value() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package ui
+
+import "unicode"
+
+// Scores used by FuzzyMatch, in the manner of fzf: every matched rune is
+// worth scoreMatch, runes at the start of a word earn a bonus, runs of
+// consecutive matches keep the bonus of the run's first rune, and gaps
+// between matches cost a penalty for opening and for each extra rune.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusBoundary     = scoreMatch / 2    // after a space, slash, dot, dash or underscore
+	bonusCamel        = bonusBoundary - 1 // a lowercase-to-uppercase or letter-to-digit change
+	bonusConsecutive  = -(scoreGapStart + scoreGapExtension)
+	bonusFirstRune    = 2 // multiplies the bonus of the pattern's first rune
+)
+
+// FuzzyMatch reports whether the runes of pattern appear in text in order,
+// and if so how well they match and where, as rune indexes into text.
+// Matching ignores case unless pattern contains an uppercase letter.
+//
+// Of all the ways the pattern can be placed in text, the best scoring one
+// is found by dynamic programming over pattern and text runes, so "pu"
+// matches the word starts in "Plugin.Update" rather than the first p and u.
+func FuzzyMatch(pattern, text string) (score int, positions []int, ok bool) {
+	pat := []rune(pattern)
+	if len(pat) == 0 {
+		return 0, nil, true
+	}
+	runes := []rune(text)
+	fold := true
+	for _, r := range pat {
+		if unicode.IsUpper(r) {
+			fold = false
+			break
+		}
+	}
+	if fold {
+		for i, r := range pat {
+			pat[i] = unicode.ToLower(r)
+		}
+		for i, r := range runes {
+			runes[i] = unicode.ToLower(r)
+		}
+	}
+	// Most candidates don't match at all; find out cheaply first.
+	pi := 0
+	for _, r := range runes {
+		if r == pat[pi] {
+			if pi++; pi == len(pat) {
+				break
+			}
+		}
+	}
+	if pi < len(pat) {
+		return 0, nil, false
+	}
+
+	// Word boundaries are judged on the text as written.
+	orig := []rune(text)
+	bonus := make([]int, len(orig))
+	for j := range orig {
+		bonus[j] = boundaryBonus(orig, j)
+	}
+	const none = -1 << 30
+	// best[i][j] is the best score placing pat[:i+1] with pat[i] at
+	// runes[j]; from[i][j] is where pat[i-1] went on that placement, and
+	// run[i][j] the bonus of the run of consecutive matches ending at j.
+	best := make([][]int, len(pat))
+	from := make([][]int, len(pat))
+	run := make([][]int, len(pat))
+	for i := range pat {
+		best[i] = make([]int, len(runes))
+		from[i] = make([]int, len(runes))
+		run[i] = make([]int, len(runes))
+		gap, gapFrom := none, -1 // best placement of pat[i-1] before a gap, gap cost included
+		for j := range runes {
+			if i > 0 {
+				if gap != none {
+					gap += scoreGapExtension
+				}
+				if k := j - 2; k >= 0 && best[i-1][k] != none && best[i-1][k]+scoreGapStart >= gap {
+					gap, gapFrom = best[i-1][k]+scoreGapStart, k
+				}
+			}
+			best[i][j] = none
+			if runes[j] != pat[i] {
+				continue
+			}
+			if i == 0 {
+				best[i][j], from[i][j], run[i][j] = scoreMatch+bonus[j]*bonusFirstRune, -1, bonus[j]
+				continue
+			}
+			if gap != none {
+				best[i][j], from[i][j], run[i][j] = gap+scoreMatch+bonus[j], gapFrom, bonus[j]
+			}
+			if j > 0 && best[i-1][j-1] != none {
+				// A run keeps the bonus it started with.
+				b := max(bonus[j], run[i-1][j-1], bonusConsecutive)
+				if s := best[i-1][j-1] + scoreMatch + b; s > best[i][j] {
+					best[i][j], from[i][j], run[i][j] = s, j-1, run[i-1][j-1]
+				}
+			}
+		}
+	}
+
+	last := len(pat) - 1
+	end := -1
+	for j := range runes {
+		if best[last][j] != none && (end < 0 || best[last][j] > best[last][end]) {
+			end = j
+		}
+	}
+	positions = make([]int, len(pat))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return best[last][end], positions, true
+}
+
+// boundaryBonus scores how much runes[i] looks like the start of a word.
+func boundaryBonus(runes []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case isDelimiter(prev) && !isDelimiter(cur):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur),
+		unicode.IsLetter(prev) && unicode.IsDigit(cur):
+		return bonusCamel
+	}
+	return 0
+}
+
+func isDelimiter(r rune) bool {
+	switch r {
+	case ' ', '/', '\\', '.', '-', '_', ':', '#', '@':
+		return true
+	}
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+		positions     []int
+	}{
+		{"", "anything", true, nil},
+		{"abc", "abc", true, []int{0, 1, 2}},
+		{"abc", "ab", false, nil},
+		{"ba", "abc", false, nil},
+		{"pu", "Plugin.Update", true, []int{0, 7}},
+		{"mu", "marchat/update.go", true, []int{0, 8}},
+		{"Fz", "fuzzy", false, nil},
+		{"Fz", "Fuzzy.go", true, []int{0, 2}},
+		{"fz", "FUZZY", true, []int{0, 2}},
+		{"ñé", "Año café", true, []int{1, 7}},
+		{"gomod", "go.mod", true, []int{0, 1, 3, 4, 5}},
+		{"go.mod", "internal/go.mod", true, []int{9, 10, 11, 12, 13, 14}},
+	}
+	for _, tt := range tests {
+		_, positions, ok := FuzzyMatch(tt.pattern, tt.text)
+		if ok != tt.ok {
+			t.Errorf("FuzzyMatch(%q, %q) ok = %t, want %t", tt.pattern, tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("FuzzyMatch(%q, %q) positions = %v, want %v", tt.pattern, tt.text, positions, tt.positions)
+		}
+	}
+}
+
+// TestFuzzyMatchRanking checks that better matches score higher.
+func TestFuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"main", "main.go", "my_animation.go"},         // consecutive beats scattered
+		{"pu", "Plugin.Update", "popup"},               // word starts beat letters mid-word
+		{"view", "internal/ui/view.go", "overview.go"}, // a word start beats a run mid-word
+		{"kc", "keymap_config", "backpack"},            // after an underscore is a word start
+		{"ab", "ab", "a_____b"},                        // short gaps beat long ones
+	}
+	for _, tt := range tests {
+		better, _, ok1 := FuzzyMatch(tt.pattern, tt.better)
+		worse, _, ok2 := FuzzyMatch(tt.pattern, tt.worse)
+		if !ok1 || !ok2 {
+			t.Errorf("%q should match both %q and %q", tt.pattern, tt.better, tt.worse)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("%q: %q scored %d, not above %q at %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
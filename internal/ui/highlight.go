@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Language describes how to tokenize and color source lines for one language.
+type Language struct {
+	Name          string
+	Keywords      map[string]bool
+	CaseFold      bool     // match keywords case-insensitively (COBOL)
+	LineComments  []string // prefixes that start a comment running to end of line
+	Quotes        string   // characters that open and close string literals
+	Escapes       bool     // backslash escapes the next character inside strings
+	WordChars     string   // extra characters allowed inside identifiers
+	IsCommentLine func(line string) bool
+}
+
+var (
+	keywordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	stringStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	commentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Italic(true)
+	numberStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
+)
+
+// languages maps lowercase file extensions to their Language.
+var languages = map[string]*Language{}
+
+// RegisterLanguage associates a Language with one or more file extensions.
+func RegisterLanguage(lang *Language, exts ...string) {
+	for _, ext := range exts {
+		languages[strings.ToLower(ext)] = lang
+	}
+}
+
+// LanguageFor returns the Language registered for path's extension, or nil.
+func LanguageFor(path string) *Language {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+func init() {
+	RegisterLanguage(cobolLanguage, ".cbl", ".cob", ".cpy")
+	RegisterLanguage(goLanguage, ".go")
+}
+
+var cobolLanguage = &Language{
+	Name:         "COBOL",
+	Keywords:     wordSet("ACCEPT ADD CALL CLOSE COMPUTE CONTINUE COPY DATA DISPLAY DIVIDE DIVISION ELSE END-IF END-PERFORM END-READ END-EVALUATE ENVIRONMENT EVALUATE EXIT FD FILE FILLER GIVING GO GOBACK IDENTIFICATION IF INTO IS MOVE MULTIPLY NOT OCCURS OPEN OUTPUT INPUT PERFORM PIC PICTURE PROCEDURE PROGRAM-ID READ REDEFINES RUN SECTION SELECT SET STOP STORAGE SUBTRACT THEN THRU THROUGH TIMES TO UNTIL USING VALUE VARYING WHEN WORKING-STORAGE LINKAGE WRITE"),
+	CaseFold:     true,
+	LineComments: []string{"*>"},
+	Quotes:       `"'`,
+	WordChars:    "-",
+	IsCommentLine: func(line string) bool {
+		// Fixed-format indicator area is column 7.
+		return len(line) >= 7 && (line[6] == '*' || line[6] == '/')
+	},
+}
+
+var goLanguage = &Language{
+	Name:         "Go",
+	Keywords:     wordSet("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false"),
+	LineComments: []string{"//"},
+	Quotes:       "\"'`",
+	Escapes:      true,
+	WordChars:    "_",
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// Highlight returns line with ANSI styling applied. A nil Language returns
+// the line unchanged.
+func (l *Language) Highlight(line string) string {
+	if l == nil {
+		return line
+	}
+	if l.IsCommentLine != nil && l.IsCommentLine(line) {
+		return commentStyle.Render(line)
+	}
+
+	var sb strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		rest := string(runes[i:])
+
+		if l.startsComment(rest) {
+			sb.WriteString(commentStyle.Render(rest))
+			break
+		}
+
+		r := runes[i]
+		switch {
+		case strings.ContainsRune(l.Quotes, r):
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' && l.Escapes {
+					j++
+				}
+				j++
+			}
+			if j < len(runes) {
+				j++
+			} else {
+				j = len(runes)
+			}
+			sb.WriteString(stringStyle.Render(string(runes[i:j])))
+			i = j
+		case l.isWordRune(r):
+			j := i
+			for j < len(runes) && l.isWordRune(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			sb.WriteString(l.styleWord(word))
+			i = j
+		default:
+			sb.WriteRune(r)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func (l *Language) startsComment(s string) bool {
+	for _, p := range l.LineComments {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Language) isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(l.WordChars, r)
+}
+
+func (l *Language) styleWord(word string) string {
+	key := word
+	if l.CaseFold {
+		key = strings.ToUpper(word)
+	}
+	if l.Keywords[key] {
+		return keywordStyle.Render(word)
+	}
+	if isNumber(word) {
+		return numberStyle.Render(word)
+	}
+	return word
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) && r != '.' {
+			return false
+		}
+	}
+	return word != ""
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Pager is a scrollable read-only text view.
+type Pager struct {
+	Title  string
+	Lines  []string
+	Height int
+
+	offset int
+}
+
+// NewPager builds a Pager over text.
+func NewPager(title, text string) *Pager {
+	return &Pager{
+		Title:  title,
+		Lines:  splitLines(text),
+		Height: 20,
+	}
+}
+
+// SetText replaces the text, keeping the scroll position where it can.
+func (p *Pager) SetText(text string) {
+	p.Lines = splitLines(text)
+	p.scrollTo(p.offset)
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+}
+
+// Update handles scrolling keys. It reports whether the key was consumed.
+func (p *Pager) Update(msg tea.Msg) bool {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return false
+	}
+	switch key.String() {
+	case "up", "k":
+		p.scrollTo(p.offset - 1)
+	case "down", "j":
+		p.scrollTo(p.offset + 1)
+	case "pgup":
+		p.scrollTo(p.offset - p.Height)
+	case "pgdown", " ":
+		p.scrollTo(p.offset + p.Height)
+	case "home":
+		p.scrollTo(0)
+	case "end":
+		p.scrollTo(len(p.Lines))
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *Pager) scrollTo(offset int) {
+	if last := len(p.Lines) - p.Height; offset > last {
+		offset = last
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	p.offset = offset
+}
+
+// View renders the title, the visible lines and a position indicator.
+func (p *Pager) View() string {
+	var sb strings.Builder
+	sb.WriteString(p.Title + "\n\n")
+	end := p.offset + p.Height
+	if end > len(p.Lines) {
+		end = len(p.Lines)
+	}
+	for _, line := range p.Lines[p.offset:end] {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("\n[%d-%d of %d]", p.offset+1, end, len(p.Lines)))
+	return sb.String()
+}
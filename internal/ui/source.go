@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Marker annotates a single source line in the gutter.
+type Marker struct {
+	Line   int // 1-based line number
+	Symbol string
+	Color  lipgloss.Color
+	Note   string
+}
+
+// SourceView is a scrollable, syntax-highlighted view of one file with
+// gutter markers and next/previous marker navigation.
+type SourceView struct {
+	Path    string
+	Lines   []string
+	Lang    *Language
+	Markers []Marker
+	Height  int
+	Width   int
+
+	cursor int // 0-based line index
+	offset int
+}
+
+var (
+	gutterStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	cursorStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+// NewSourceView builds a view of content, choosing a Language from path.
+func NewSourceView(path, content string, markers []Marker) *SourceView {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\t", "    ")
+	sorted := append([]Marker(nil), markers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Line < sorted[j].Line })
+	return &SourceView{
+		Path:    path,
+		Lines:   strings.Split(content, "\n"),
+		Lang:    LanguageFor(path),
+		Markers: sorted,
+		Height:  20,
+		Width:   100,
+	}
+}
+
+// Update handles navigation keys. It reports whether the key was consumed.
+func (v *SourceView) Update(msg tea.Msg) bool {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return false
+	}
+	switch key.String() {
+	case "up", "k":
+		v.moveTo(v.cursor - 1)
+	case "down", "j":
+		v.moveTo(v.cursor + 1)
+	case "pgup":
+		v.moveTo(v.cursor - v.Height)
+	case "pgdown":
+		v.moveTo(v.cursor + v.Height)
+	case "home", "g":
+		v.moveTo(0)
+	case "end", "G":
+		v.moveTo(len(v.Lines) - 1)
+	case "n":
+		v.NextMarker()
+	case "N":
+		v.PrevMarker()
+	default:
+		return false
+	}
+	return true
+}
+
+// NextMarker moves the cursor to the first marker after it, wrapping around.
+func (v *SourceView) NextMarker() {
+	if len(v.Markers) == 0 {
+		return
+	}
+	for _, m := range v.Markers {
+		if m.Line-1 > v.cursor {
+			v.moveTo(m.Line - 1)
+			return
+		}
+	}
+	v.moveTo(v.Markers[0].Line - 1)
+}
+
+// PrevMarker moves the cursor to the last marker before it, wrapping around.
+func (v *SourceView) PrevMarker() {
+	if len(v.Markers) == 0 {
+		return
+	}
+	for i := len(v.Markers) - 1; i >= 0; i-- {
+		if v.Markers[i].Line-1 < v.cursor {
+			v.moveTo(v.Markers[i].Line - 1)
+			return
+		}
+	}
+	v.moveTo(v.Markers[len(v.Markers)-1].Line - 1)
+}
+
+// GotoLine moves the cursor to the given 1-based line.
+func (v *SourceView) GotoLine(line int) {
+	v.moveTo(line - 1)
+}
+
+// CursorLine returns the 1-based line under the cursor.
+func (v *SourceView) CursorLine() int {
+	return v.cursor + 1
+}
+
+// CursorMarkers returns the markers on the cursor line.
+func (v *SourceView) CursorMarkers() []Marker {
+	return v.markersAt(v.cursor + 1)
+}
+
+func (v *SourceView) moveTo(line int) {
+	if line >= len(v.Lines) {
+		line = len(v.Lines) - 1
+	}
+	if line < 0 {
+		line = 0
+	}
+	v.cursor = line
+	if v.cursor < v.offset {
+		v.offset = v.cursor
+	}
+	if v.cursor >= v.offset+v.Height {
+		v.offset = v.cursor - v.Height + 1
+	}
+}
+
+func (v *SourceView) markersAt(line int) []Marker {
+	var out []Marker
+	for _, m := range v.Markers {
+		if m.Line == line {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// View renders the visible window of the file followed by a status line
+// describing any markers on the cursor line.
+func (v *SourceView) View() string {
+	var sb strings.Builder
+
+	lang := "plain text"
+	if v.Lang != nil {
+		lang = v.Lang.Name
+	}
+	sb.WriteString(fmt.Sprintf("%s (%s, %d findings)\n", v.Path, lang, len(v.Markers)))
+
+	numWidth := len(fmt.Sprint(len(v.Lines)))
+	end := v.offset + v.Height
+	if end > len(v.Lines) {
+		end = len(v.Lines)
+	}
+	for i := v.offset; i < end; i++ {
+		mark := " "
+		if ms := v.markersAt(i + 1); len(ms) > 0 {
+			mark = lipgloss.NewStyle().Foreground(ms[0].Color).Render(ms[0].Symbol)
+		}
+		num := fmt.Sprintf("%*d", numWidth, i+1)
+		if i == v.cursor {
+			num = cursorStyle.Render(num)
+		} else {
+			num = gutterStyle.Render(num)
+		}
+		line := v.Lines[i]
+		if r := []rune(line); v.Width > 0 && len(r) > v.Width {
+			line = string(r[:v.Width])
+		}
+		sb.WriteString(fmt.Sprintf("%s %s │ %s\n", mark, num, v.Lang.Highlight(line)))
+	}
+
+	sb.WriteString("\n")
+	if ms := v.markersAt(v.cursor + 1); len(ms) > 0 {
+		for _, m := range ms {
+			sb.WriteString(fmt.Sprintf("Line %d: %s\n", m.Line, m.Note))
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("Line %d/%d\n", v.cursor+1, len(v.Lines)))
+	}
+	return sb.String()
+}